@@ -0,0 +1,35 @@
+package v1alpha1
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ValidateExpiration checks that exactly one of expirationDate or ttl is set
+func (s *EphemeralApplicationSpec) ValidateExpiration() error {
+	if s.ExpirationDate != nil && s.TTL != nil {
+		return fmt.Errorf("spec.expirationDate and spec.ttl are mutually exclusive")
+	}
+	if s.ExpirationDate == nil && s.TTL == nil {
+		return fmt.Errorf("either spec.expirationDate or spec.ttl must be set")
+	}
+	if s.TTL != nil && s.TTL.Duration <= 0 {
+		return fmt.Errorf("spec.ttl must be positive")
+	}
+	return nil
+}
+
+// ExpiresAt returns the effective expiry of the environment
+// The TTL counts from the creation time, nil is returned when no expiry can be computed
+func (e *EphemeralApplication) ExpiresAt() *metav1.Time {
+	if e.Spec.ExpirationDate != nil {
+		return e.Spec.ExpirationDate.DeepCopy()
+	}
+	if e.Spec.TTL != nil && !e.CreationTimestamp.IsZero() {
+		// Truncated to the precision stored in the status
+		expiresAt := metav1.NewTime(e.CreationTimestamp.Add(e.Spec.TTL.Duration)).Rfc3339Copy()
+		return &expiresAt
+	}
+	return nil
+}
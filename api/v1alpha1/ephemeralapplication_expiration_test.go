@@ -0,0 +1,77 @@
+package v1alpha1
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidateExpiration(t *testing.T) {
+	expirationDate := metav1.NewTime(time.Now().Add(time.Hour))
+
+	tests := []struct {
+		name    string
+		spec    EphemeralApplicationSpec
+		wantErr bool
+	}{
+		{
+			name: "expiration date",
+			spec: EphemeralApplicationSpec{ExpirationDate: &expirationDate},
+		},
+		{
+			name: "ttl",
+			spec: EphemeralApplicationSpec{TTL: &metav1.Duration{Duration: 72 * time.Hour}},
+		},
+		{
+			name: "both",
+			spec: EphemeralApplicationSpec{
+				ExpirationDate: &expirationDate,
+				TTL:            &metav1.Duration{Duration: 72 * time.Hour},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "neither",
+			spec:    EphemeralApplicationSpec{},
+			wantErr: true,
+		},
+		{
+			name:    "negative ttl",
+			spec:    EphemeralApplicationSpec{TTL: &metav1.Duration{Duration: -time.Hour}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.spec.ValidateExpiration()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateExpiration() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExpiresAt(t *testing.T) {
+	created := metav1.NewTime(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	expirationDate := metav1.NewTime(time.Date(2025, 1, 2, 12, 0, 0, 0, time.UTC))
+
+	ephApp := &EphemeralApplication{
+		ObjectMeta: metav1.ObjectMeta{CreationTimestamp: created},
+		Spec:       EphemeralApplicationSpec{TTL: &metav1.Duration{Duration: 72 * time.Hour}},
+	}
+	if got := ephApp.ExpiresAt(); got == nil || !got.Time.Equal(created.Add(72*time.Hour)) {
+		t.Errorf("expected expiry 72h after creation, got %v", got)
+	}
+
+	ephApp.Spec = EphemeralApplicationSpec{ExpirationDate: &expirationDate}
+	if got := ephApp.ExpiresAt(); got == nil || !got.Time.Equal(expirationDate.Time) {
+		t.Errorf("expected expiry %v, got %v", expirationDate, got)
+	}
+
+	ephApp.Spec = EphemeralApplicationSpec{}
+	if got := ephApp.ExpiresAt(); got != nil {
+		t.Errorf("expected no expiry, got %v", got)
+	}
+}
@@ -0,0 +1,76 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// ValidateHibernation checks the cron expressions and the time zone of the hibernation schedule
+func (s *EphemeralApplicationSpec) ValidateHibernation() error {
+	if s.Hibernation == nil {
+		return nil
+	}
+	_, _, _, err := s.Hibernation.parse()
+	return err
+}
+
+// Hibernating reports whether the environment should be hibernating at now and when the
+// next sleep or wake is scheduled. wokenAt is the last manual wake, a manual wake lasts
+// until the next scheduled sleep
+func (h *HibernationSpec) Hibernating(now, wokenAt time.Time) (bool, time.Time, error) {
+	sleep, wake, loc, err := h.parse()
+	if err != nil {
+		return false, time.Time{}, err
+	}
+
+	local := now.In(loc)
+	nextSleep := sleep.Next(local)
+	nextWake := wake.Next(local)
+	if nextSleep.IsZero() || nextWake.IsZero() {
+		return false, time.Time{}, fmt.Errorf("spec.hibernation schedules never fire")
+	}
+
+	// Between a sleep and the following wake the next event is the wake
+	if !nextWake.Before(nextSleep) {
+		return false, nextSleep, nil
+	}
+	if !wokenAt.IsZero() && sleep.Next(wokenAt.In(loc)).After(local) {
+		return false, nextSleep, nil
+	}
+	return true, nextWake, nil
+}
+
+// parse returns the sleep and wake schedules and the time zone they are evaluated in
+func (h *HibernationSpec) parse() (cron.Schedule, cron.Schedule, *time.Location, error) {
+	sleep, err := cron.ParseStandard(h.SleepSchedule)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("spec.hibernation.sleepSchedule is invalid: %w", err)
+	}
+	wake, err := cron.ParseStandard(h.WakeSchedule)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("spec.hibernation.wakeSchedule is invalid: %w", err)
+	}
+
+	loc := time.UTC
+	if h.Timezone != "" {
+		if loc, err = time.LoadLocation(h.Timezone); err != nil {
+			return nil, nil, nil, fmt.Errorf("spec.hibernation.timezone is invalid: %w", err)
+		}
+	}
+	return sleep, wake, loc, nil
+}
+
+// WakeRequestedAt returns when the environment was last woken up manually, or the zero time
+func (e *EphemeralApplication) WakeRequestedAt() time.Time {
+	value, ok := e.Annotations[WakeRequestedAnnotation]
+	if !ok {
+		return time.Time{}
+	}
+	wokenAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}
+	}
+	return wokenAt
+}
@@ -0,0 +1,107 @@
+package v1alpha1
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestValidateHibernation(t *testing.T) {
+	tests := []struct {
+		name        string
+		hibernation *HibernationSpec
+		wantErr     bool
+	}{
+		{name: "no hibernation"},
+		{
+			name:        "weekday nights",
+			hibernation: &HibernationSpec{SleepSchedule: "0 20 * * 1-5", WakeSchedule: "0 8 * * 1-5", Timezone: "Europe/Madrid"},
+		},
+		{
+			name:        "invalid cron",
+			hibernation: &HibernationSpec{SleepSchedule: "every night", WakeSchedule: "0 8 * * *"},
+			wantErr:     true,
+		},
+		{
+			name:        "unknown timezone",
+			hibernation: &HibernationSpec{SleepSchedule: "0 20 * * *", WakeSchedule: "0 8 * * *", Timezone: "Mars/Olympus"},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := EphemeralApplicationSpec{Hibernation: tt.hibernation}
+			err := spec.ValidateHibernation()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateHibernation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHibernating(t *testing.T) {
+	madrid, err := time.LoadLocation("Europe/Madrid")
+	if err != nil {
+		t.Fatalf("failed to load time zone: %v", err)
+	}
+	hibernation := &HibernationSpec{
+		SleepSchedule: "0 20 * * *",
+		WakeSchedule:  "0 8 * * *",
+		Timezone:      "Europe/Madrid",
+	}
+
+	tests := []struct {
+		name          string
+		now           time.Time
+		wokenAt       time.Time
+		wantHibernate bool
+		wantNext      time.Time
+	}{
+		{
+			name:     "working hours",
+			now:      time.Date(2025, 3, 10, 12, 0, 0, 0, madrid),
+			wantNext: time.Date(2025, 3, 10, 20, 0, 0, 0, madrid),
+		},
+		{
+			name:          "night",
+			now:           time.Date(2025, 3, 10, 23, 0, 0, 0, madrid),
+			wantHibernate: true,
+			wantNext:      time.Date(2025, 3, 11, 8, 0, 0, 0, madrid),
+		},
+		{
+			name:          "schedules use the time zone",
+			now:           time.Date(2025, 3, 10, 19, 30, 0, 0, time.UTC),
+			wantHibernate: true,
+			wantNext:      time.Date(2025, 3, 11, 8, 0, 0, 0, madrid),
+		},
+		{
+			name:     "woken up manually",
+			now:      time.Date(2025, 3, 10, 23, 0, 0, 0, madrid),
+			wokenAt:  time.Date(2025, 3, 10, 22, 0, 0, 0, madrid),
+			wantNext: time.Date(2025, 3, 11, 20, 0, 0, 0, madrid),
+		},
+		{
+			name:          "manual wake ends at the next sleep",
+			now:           time.Date(2025, 3, 11, 21, 0, 0, 0, madrid),
+			wokenAt:       time.Date(2025, 3, 10, 22, 0, 0, 0, madrid),
+			wantHibernate: true,
+			wantNext:      time.Date(2025, 3, 12, 8, 0, 0, 0, madrid),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hibernate, next, err := hibernation.Hibernating(tt.now, tt.wokenAt)
+			if err != nil {
+				t.Fatalf("Hibernating() failed: %v", err)
+			}
+			if hibernate != tt.wantHibernate {
+				t.Errorf("expected hibernate %v, got %v", tt.wantHibernate, hibernate)
+			}
+			if !next.Equal(tt.wantNext) {
+				t.Errorf("expected next transition %v, got %v", tt.wantNext, next)
+			}
+		})
+	}
+}
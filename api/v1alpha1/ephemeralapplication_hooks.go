@@ -0,0 +1,76 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// hookStages are the stages in the order they run
+var hookStages = []HookStage{HookPreSync, HookPostReady, HookPreDelete}
+
+// ValidateHooks checks that every hook has a unique name within its stage that can be used
+// as the name of its Job, and that the Job runs at least one container
+func (s *EphemeralApplicationSpec) ValidateHooks() error {
+	if s.Hooks == nil {
+		return nil
+	}
+
+	for _, stage := range hookStages {
+		names := make(map[string]bool)
+		for i, hook := range s.Hooks.Stage(stage) {
+			field := fmt.Sprintf("spec.hooks.%s[%d]", stage.field(), i)
+			if hook.Name == "" {
+				return fmt.Errorf("%s.name is required", field)
+			}
+			if names[hook.Name] {
+				return fmt.Errorf("%s.name %q is duplicated", field, hook.Name)
+			}
+			names[hook.Name] = true
+
+			if errs := validation.IsDNS1123Label(stage.JobName(hook.Name)); len(errs) > 0 {
+				return fmt.Errorf("%s.name %q is invalid: %s", field, hook.Name, strings.Join(errs, ", "))
+			}
+
+			pod := hook.Spec.Template.Spec
+			if len(pod.Containers) == 0 {
+				return fmt.Errorf("%s.spec.template.spec.containers is required", field)
+			}
+			switch pod.RestartPolicy {
+			case "", corev1.RestartPolicyNever, corev1.RestartPolicyOnFailure:
+			default:
+				return fmt.Errorf("%s.spec.template.spec.restartPolicy must be Never or OnFailure", field)
+			}
+		}
+	}
+	return nil
+}
+
+// Stage returns the hooks that run at a stage
+func (h *Hooks) Stage(stage HookStage) []Hook {
+	if h == nil {
+		return nil
+	}
+	switch stage {
+	case HookPreSync:
+		return h.PreSync
+	case HookPostReady:
+		return h.PostReady
+	case HookPreDelete:
+		return h.PreDelete
+	default:
+		return nil
+	}
+}
+
+// JobName returns the name of the Job running a hook of the stage (e.g., presync-migrate)
+func (s HookStage) JobName(hook string) string {
+	return strings.ToLower(string(s)) + "-" + hook
+}
+
+// field returns the name of the stage in the spec (e.g., preSync)
+func (s HookStage) field() string {
+	return strings.ToLower(string(s[:1])) + string(s[1:])
+}
@@ -0,0 +1,93 @@
+package v1alpha1
+
+import (
+	"strings"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestValidateHooks(t *testing.T) {
+	job := func(restartPolicy corev1.RestartPolicy) batchv1.JobSpec {
+		return batchv1.JobSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy: restartPolicy,
+					Containers:    []corev1.Container{{Name: "migrate", Image: "migrate:latest"}},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		hooks   *Hooks
+		wantErr string
+	}{
+		{name: "no hooks"},
+		{
+			name: "every stage",
+			hooks: &Hooks{
+				PreSync:   []Hook{{Name: "migrate", Spec: job("")}, {Name: "seed", Spec: job(corev1.RestartPolicyOnFailure)}},
+				PostReady: []Hook{{Name: "smoke", Spec: job(corev1.RestartPolicyNever)}},
+				PreDelete: []Hook{{Name: "migrate", Spec: job("")}},
+			},
+		},
+		{
+			name:    "missing name",
+			hooks:   &Hooks{PreSync: []Hook{{Spec: job("")}}},
+			wantErr: "spec.hooks.preSync[0].name is required",
+		},
+		{
+			name:    "duplicated name",
+			hooks:   &Hooks{PostReady: []Hook{{Name: "smoke", Spec: job("")}, {Name: "smoke", Spec: job("")}}},
+			wantErr: "spec.hooks.postReady[1].name \"smoke\" is duplicated",
+		},
+		{
+			name:    "invalid name",
+			hooks:   &Hooks{PreDelete: []Hook{{Name: "Dump_DB", Spec: job("")}}},
+			wantErr: "spec.hooks.preDelete[0].name \"Dump_DB\" is invalid",
+		},
+		{
+			name:    "job name too long",
+			hooks:   &Hooks{PostReady: []Hook{{Name: strings.Repeat("a", 54), Spec: job("")}}},
+			wantErr: "is invalid",
+		},
+		{
+			name:    "no containers",
+			hooks:   &Hooks{PreSync: []Hook{{Name: "migrate"}}},
+			wantErr: "spec.hooks.preSync[0].spec.template.spec.containers is required",
+		},
+		{
+			name:    "restart always",
+			hooks:   &Hooks{PreSync: []Hook{{Name: "migrate", Spec: job(corev1.RestartPolicyAlways)}}},
+			wantErr: "restartPolicy must be Never or OnFailure",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := EphemeralApplicationSpec{Hooks: tt.hooks}
+			err := spec.ValidateHooks()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateHooks() unexpected error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateHooks() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHookStageJobName(t *testing.T) {
+	if got := HookPreSync.JobName("migrate"); got != "presync-migrate" {
+		t.Errorf("expected presync-migrate, got %s", got)
+	}
+	if got := HookPostReady.JobName("smoke"); got != "postready-smoke" {
+		t.Errorf("expected postready-smoke, got %s", got)
+	}
+}
@@ -1,33 +1,72 @@
 package v1alpha1
 
 import (
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 // EphemeralApplicationSpec defines the desired state of EphemeralApplication
+// +kubebuilder:validation:XValidation:rule="has(self.expirationDate) != has(self.ttl)",message="exactly one of expirationDate or ttl must be set"
+// +kubebuilder:validation:XValidation:rule="has(self.destination) == has(oldSelf.destination)",message="destination is immutable"
 type EphemeralApplicationSpec struct {
+	// TemplateRef references an EphemeralApplicationTemplate in the same namespace
+	// Fields set on the EphemeralApplication override the template defaults
+	// +optional
+	TemplateRef *TemplateReference `json:"templateRef,omitempty"`
+
 	// RepoURL is the Git repository URL containing the application manifests
-	// +kubebuilder:validation:Required
-	RepoURL string `json:"repoURL"`
+	// Required unless Components is set or it is inherited from the template
+	// +optional
+	RepoURL string `json:"repoURL,omitempty"`
 
 	// Path is the path within the Git repository
-	// +kubebuilder:validation:Required
-	Path string `json:"path"`
+	// Required unless Helm.Chart is set
+	// +optional
+	Path string `json:"path,omitempty"`
 
 	// TargetRevision is the Git revision to deploy (branch, tag, or commit)
-	// +kubebuilder:default:="HEAD"
+	// When Helm.Chart is set, it is the chart version. Defaults to HEAD
+	// +optional
 	TargetRevision string `json:"targetRevision,omitempty"`
 
+	// Helm defines Helm specific options for the application source
+	// +optional
+	Helm *HelmSource `json:"helm,omitempty"`
+
+	// Kustomize defines Kustomize overrides for the application source
+	// Mutually exclusive with Helm
+	// +optional
+	Kustomize *KustomizeSource `json:"kustomize,omitempty"`
+
+	// Components are the applications that make up the environment, each one deployed
+	// as its own ArgoCD Application into the shared ephemeral namespace
+	// Mutually exclusive with RepoURL, Path, Helm and Kustomize
+	// +optional
+	Components []Component `json:"components,omitempty"`
+
 	// ExpirationDate is the date when this ephemeral environment should be deleted
 	// Format: RFC3339 (e.g., "2024-12-31T23:59:59Z")
-	// +kubebuilder:validation:Required
-	ExpirationDate metav1.Time `json:"expirationDate"`
+	// Mutually exclusive with TTL, one of them is required
+	// +optional
+	ExpirationDate *metav1.Time `json:"expirationDate,omitempty"`
+
+	// TTL is the lifetime of the environment counted from its creation (e.g., "72h")
+	// Mutually exclusive with ExpirationDate
+	// +optional
+	TTL *metav1.Duration `json:"ttl,omitempty"`
 
 	// NamespaceName is the name for the ephemeral namespace
 	// If not provided, a random name will be generated: ephemeral-{random}
 	// +optional
 	NamespaceName string `json:"namespaceName,omitempty"`
 
+	// Namespace configures the metadata and the guardrails of the ephemeral namespace
+	// The EphemeralPolicies provide the defaults and the upper limits
+	// +optional
+	Namespace *NamespaceOptions `json:"namespace,omitempty"`
+
 	// Secrets to copy from other namespaces into the ephemeral namespace
 	// Allows applications to access shared credentials (databases, APIs, etc.)
 	// +optional
@@ -41,6 +80,322 @@ type EphemeralApplicationSpec struct {
 	// SyncPolicy defines how the application should be synced
 	// +optional
 	SyncPolicy *SyncPolicy `json:"syncPolicy,omitempty"`
+
+	// Hibernation scales the workloads of the environment to zero outside working hours
+	// +optional
+	Hibernation *HibernationSpec `json:"hibernation,omitempty"`
+
+	// Hooks are Jobs run in the ephemeral namespace before the applications are synced,
+	// once they are ready and before the environment is deleted
+	// +optional
+	Hooks *Hooks `json:"hooks,omitempty"`
+
+	// Destination selects the cluster the environment is deployed to
+	// Defaults to the cluster the operator runs in, it can not be added, changed or removed
+	// +optional
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="destination is immutable"
+	Destination *Destination `json:"destination,omitempty"`
+}
+
+// Destination selects a remote cluster, exactly one of Name or KubeconfigSecretRef must be set
+// +kubebuilder:validation:XValidation:rule="has(self.name) != has(self.kubeconfigSecretRef)",message="exactly one of name or kubeconfigSecretRef must be set"
+type Destination struct {
+	// Name of a cluster registered in ArgoCD, its credentials are read from the ArgoCD cluster secret
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// KubeconfigSecretRef references a Secret in the namespace of the EphemeralApplication
+	// holding a kubeconfig for the cluster, whose server must also be registered in ArgoCD
+	// +optional
+	KubeconfigSecretRef *KubeconfigSecretReference `json:"kubeconfigSecretRef,omitempty"`
+}
+
+// KubeconfigSecretReference references a kubeconfig stored in a Secret
+type KubeconfigSecretReference struct {
+	// Name of the Secret
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Key of the kubeconfig in the Secret, defaults to "kubeconfig"
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// HibernationSpec defines when the environment sleeps and wakes up
+// Deployments and StatefulSets are scaled to zero between a sleep and the following wake
+type HibernationSpec struct {
+	// SleepSchedule is the cron expression when the environment is scaled to zero (e.g., "0 20 * * 1-5")
+	// +kubebuilder:validation:Required
+	SleepSchedule string `json:"sleepSchedule"`
+
+	// WakeSchedule is the cron expression when the environment is restored (e.g., "0 8 * * 1-5")
+	// +kubebuilder:validation:Required
+	WakeSchedule string `json:"wakeSchedule"`
+
+	// Timezone is the IANA time zone the schedules are evaluated in (e.g., "Europe/Madrid")
+	// Defaults to UTC
+	// +optional
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// Hooks defines the Jobs run at each stage of the environment lifecycle
+// The hooks of a stage run one after the other, in the order they are listed
+type Hooks struct {
+	// PreSync hooks run once the namespace, secrets and configmaps exist and before the
+	// ArgoCD Applications are created (e.g., database migrations or seed data)
+	// +optional
+	PreSync []Hook `json:"preSync,omitempty"`
+
+	// PostReady hooks run once every application is synced and healthy
+	// The environment only becomes Active after they succeed (e.g., smoke tests)
+	// +optional
+	PostReady []Hook `json:"postReady,omitempty"`
+
+	// PreDelete hooks run before the applications and the namespace are deleted
+	// The deletion waits until they finish, a failed hook does not block it
+	// +optional
+	PreDelete []Hook `json:"preDelete,omitempty"`
+}
+
+// Hook is a Job run in the ephemeral namespace
+type Hook struct {
+	// Name of the hook, the Job is named after the stage and the hook (e.g., presync-migrate)
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Spec of the Job, the restart policy of its pods defaults to Never and activeDeadlineSeconds to 30 minutes
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type=object
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Spec batchv1.JobSpec `json:"spec"`
+}
+
+// HookStage is the point of the environment lifecycle a hook runs at
+// +kubebuilder:validation:Enum=PreSync;PostReady;PreDelete
+type HookStage string
+
+const (
+	// HookPreSync runs before the ArgoCD Applications are created
+	HookPreSync HookStage = "PreSync"
+	// HookPostReady runs before the environment becomes Active
+	HookPostReady HookStage = "PostReady"
+	// HookPreDelete runs before the environment is deleted
+	HookPreDelete HookStage = "PreDelete"
+)
+
+// HookPhase is the state of the Job of a hook
+// +kubebuilder:validation:Enum=Running;Succeeded;Failed
+type HookPhase string
+
+const (
+	// HookRunning indicates the Job was created and has not finished yet
+	HookRunning HookPhase = "Running"
+	// HookSucceeded indicates the Job completed
+	HookSucceeded HookPhase = "Succeeded"
+	// HookFailed indicates the Job failed, it is not run again
+	HookFailed HookPhase = "Failed"
+)
+
+// NamespaceOptions configures the ephemeral namespace
+type NamespaceOptions struct {
+	// Labels added to the namespace (e.g., pod-security.kubernetes.io/enforce: restricted)
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations added to the namespace
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// ResourceQuota is the hard limit of the resources the environment can consume
+	// (e.g., requests.cpu, limits.memory, pods), a ResourceQuota is created when set
+	// +optional
+	ResourceQuota corev1.ResourceList `json:"resourceQuota,omitempty"`
+
+	// LimitRange sets the default and maximum resources of the containers, a LimitRange
+	// is created when set
+	// +optional
+	LimitRange *ContainerLimits `json:"limitRange,omitempty"`
+
+	// NetworkPolicy denies the ingress traffic from other namespaces, a NetworkPolicy is
+	// created when set
+	// +optional
+	NetworkPolicy *NetworkIsolation `json:"networkPolicy,omitempty"`
+}
+
+// ContainerLimits are the resources applied to the containers of the ephemeral namespace
+type ContainerLimits struct {
+	// Default are the limits of containers that do not set them
+	// +optional
+	Default corev1.ResourceList `json:"default,omitempty"`
+
+	// DefaultRequest are the requests of containers that do not set them
+	// +optional
+	DefaultRequest corev1.ResourceList `json:"defaultRequest,omitempty"`
+
+	// Max are the maximum limits of a container
+	// +optional
+	Max corev1.ResourceList `json:"max,omitempty"`
+}
+
+// NetworkIsolation denies the ingress traffic to the ephemeral namespace except from its own
+// pods and from the allowed namespaces
+type NetworkIsolation struct {
+	// AllowedNamespaces are the namespaces allowed to reach the pods of the environment
+	// Defaults to the namespace of the ingress controller, "ingress-nginx"
+	// +optional
+	AllowedNamespaces []string `json:"allowedNamespaces,omitempty"`
+}
+
+// TemplateReference references an EphemeralApplicationTemplate
+type TemplateReference struct {
+	// Name of the EphemeralApplicationTemplate
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+}
+
+// Component is a single application of a multi-component environment
+type Component struct {
+	// Name identifies the component, the ArgoCD Application is named {ephemeral-app}-{name}
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name"`
+
+	// RepoURL is the Git or Helm repository URL containing the component manifests
+	// Required unless it is inherited from the template component with the same name
+	// +optional
+	RepoURL string `json:"repoURL,omitempty"`
+
+	// Path is the path within the Git repository
+	// Required unless Helm.Chart is set
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// TargetRevision is the Git revision or chart version to deploy. Defaults to HEAD
+	// +optional
+	TargetRevision string `json:"targetRevision,omitempty"`
+
+	// Helm defines Helm specific options for the component source
+	// +optional
+	Helm *HelmSource `json:"helm,omitempty"`
+
+	// Kustomize defines Kustomize overrides for the component source
+	// Mutually exclusive with Helm
+	// +optional
+	Kustomize *KustomizeSource `json:"kustomize,omitempty"`
+}
+
+// HelmSource defines Helm specific options for the application source
+type HelmSource struct {
+	// Chart is the name of the chart when RepoURL points to a Helm repository
+	// Mutually exclusive with Path
+	// +optional
+	Chart string `json:"chart,omitempty"`
+
+	// ReleaseName is the Helm release name, defaults to the application name
+	// +optional
+	ReleaseName string `json:"releaseName,omitempty"`
+
+	// ValueFiles is a list of values files to use, relative to the chart
+	// +optional
+	ValueFiles []string `json:"valueFiles,omitempty"`
+
+	// Values are inline Helm values, merged on top of the value files
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type=object
+	// +optional
+	Values *runtime.RawExtension `json:"values,omitempty"`
+
+	// Parameters override individual Helm values
+	// +optional
+	Parameters []HelmParameter `json:"parameters,omitempty"`
+}
+
+// HelmParameter is a single Helm value override
+type HelmParameter struct {
+	// Name is the path of the value (e.g. "image.tag")
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Value is the value to set
+	// +optional
+	Value string `json:"value,omitempty"`
+
+	// ForceString makes Helm treat the value as a string
+	// +optional
+	ForceString bool `json:"forceString,omitempty"`
+}
+
+// KustomizeSource defines Kustomize overrides applied on top of the overlay in Git
+type KustomizeSource struct {
+	// Images overrides container images, using the kustomize format
+	// (e.g. "my-app=registry.example.com/my-app:pr-42")
+	// +optional
+	Images []string `json:"images,omitempty"`
+
+	// NamePrefix is prepended to the name of every resource
+	// +optional
+	NamePrefix string `json:"namePrefix,omitempty"`
+
+	// NameSuffix is appended to the name of every resource
+	// +optional
+	NameSuffix string `json:"nameSuffix,omitempty"`
+
+	// CommonLabels are added to every resource
+	// +optional
+	CommonLabels map[string]string `json:"commonLabels,omitempty"`
+
+	// CommonAnnotations are added to every resource
+	// +optional
+	CommonAnnotations map[string]string `json:"commonAnnotations,omitempty"`
+
+	// Patches are inline patches applied to the rendered resources
+	// +optional
+	Patches []KustomizePatch `json:"patches,omitempty"`
+}
+
+// KustomizePatch is an inline strategic merge or JSON6902 patch
+type KustomizePatch struct {
+	// Patch is the inline patch content
+	// +kubebuilder:validation:Required
+	Patch string `json:"patch"`
+
+	// Target selects the resources to patch
+	// If not specified, the target is inferred from the patch
+	// +optional
+	Target *KustomizePatchTarget `json:"target,omitempty"`
+}
+
+// KustomizePatchTarget selects the resources a patch applies to
+type KustomizePatchTarget struct {
+	// Group is the API group of the target resources
+	// +optional
+	Group string `json:"group,omitempty"`
+
+	// Version is the API version of the target resources
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// Kind is the kind of the target resources
+	// +optional
+	Kind string `json:"kind,omitempty"`
+
+	// Name is the name of the target resource
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Namespace is the namespace of the target resource
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// LabelSelector selects resources by label
+	// +optional
+	LabelSelector string `json:"labelSelector,omitempty"`
+
+	// AnnotationSelector selects resources by annotation
+	// +optional
+	AnnotationSelector string `json:"annotationSelector,omitempty"`
 }
 
 // ConfigMapReference defines a configmap to copy or create
@@ -50,14 +405,30 @@ type ConfigMapReference struct {
 	Name string `json:"name"`
 
 	// SourceNamespace where the configmap exists (for copying)
-	// Mutually exclusive with Data
+	// Both data and binaryData of the source are copied
 	// +optional
 	SourceNamespace string `json:"sourceNamespace,omitempty"`
 
+	// TargetName is the optional name for the configmap in the target namespace
+	// If not specified, uses the same name as the source
+	// +optional
+	TargetName string `json:"targetName,omitempty"`
+
 	// Data to create a new configmap inline
-	// Mutually exclusive with SourceNamespace
+	// When SourceNamespace is also set, Data is merged over the data of the source
 	// +optional
 	Data map[string]string `json:"data,omitempty"`
+
+	// Templates are Go templates rendered into the values of their keys, merged over the
+	// copied or inline data. Templates can reference .Name, .Namespace, .PreviewURL and
+	// the copied or inline data under .Data
+	// +optional
+	Templates map[string]string `json:"templates,omitempty"`
+
+	// SyncMode defines whether the configmap is copied again when the source changes
+	// Only applies to configmaps copied from a SourceNamespace, defaults to Once
+	// +optional
+	SyncMode SyncMode `json:"syncMode,omitempty"`
 }
 
 // SecretReference defines a secret to copy from another namespace
@@ -66,9 +437,10 @@ type SecretReference struct {
 	// +kubebuilder:validation:Required
 	Name string `json:"name"`
 
-	// SourceNamespace where the secret exists
-	// +kubebuilder:validation:Required
-	SourceNamespace string `json:"sourceNamespace"`
+	// SourceNamespace where the secret exists (for copying)
+	// Not set for secrets created from values, templates or generate
+	// +optional
+	SourceNamespace string `json:"sourceNamespace,omitempty"`
 
 	// TargetName is the optional name for the secret in the target namespace
 	// If not specified, uses the same name as the source
@@ -79,21 +451,143 @@ type SecretReference struct {
 	// If not specified, the secret will be copied as is using the secret from the SourceNamespace
 	// +optional
 	Values map[string]string `json:"values,omitempty"`
+
+	// Keys limits the keys copied from the source secret to the listed ones
+	// Mutually exclusive with ExcludeKeys
+	// +optional
+	Keys []string `json:"keys,omitempty"`
+
+	// ExcludeKeys lists the keys of the source secret that are not copied
+	// +optional
+	ExcludeKeys []string `json:"excludeKeys,omitempty"`
+
+	// RenameKeys maps keys of the source secret to the keys they are copied to
+	// +optional
+	RenameKeys map[string]string `json:"renameKeys,omitempty"`
+
+	// Templates are Go templates rendered into the values of their keys, merged over the
+	// copied or inline values. Templates can reference .Name, .Namespace, .PreviewURL and
+	// the copied or inline values under .Data, e.g. "app_{{ .Namespace }}"
+	// +optional
+	Templates map[string]string `json:"templates,omitempty"`
+
+	// Generate creates the secret with random passwords, keys or a self-signed certificate
+	// Generated values are stored once in the ephemeral namespace and never written to the spec
+	// Mutually exclusive with Values and SourceNamespace
+	// +optional
+	Generate *SecretGenerator `json:"generate,omitempty"`
+
+	// SyncMode defines whether the secret is copied again when the source changes
+	// Only applies to secrets copied from the SourceNamespace, defaults to Once
+	// +optional
+	SyncMode SyncMode `json:"syncMode,omitempty"`
 }
 
+// SecretGenerator defines the values generated for a secret
+// Keys already present in the ephemeral secret are kept, only missing ones are generated
+type SecretGenerator struct {
+	// Passwords generates a random password for each key
+	// +optional
+	Passwords []PasswordGenerator `json:"passwords,omitempty"`
+
+	// KeyPair generates a private key in PKCS#8 PEM under privateKey and its public key
+	// in PKIX PEM under publicKey
+	// +optional
+	KeyPair *KeyPairGenerator `json:"keyPair,omitempty"`
+
+	// TLS generates a self-signed certificate under tls.crt, tls.key and ca.crt,
+	// the secret type is kubernetes.io/tls
+	// +optional
+	TLS *TLSGenerator `json:"tls,omitempty"`
+}
+
+// PasswordGenerator defines a random password
+type PasswordGenerator struct {
+	// Key of the password in the secret
+	// +kubebuilder:validation:Required
+	Key string `json:"key"`
+
+	// Length of the password, defaults to 32
+	// +kubebuilder:validation:Minimum=8
+	// +kubebuilder:validation:Maximum=256
+	// +optional
+	Length int32 `json:"length,omitempty"`
+
+	// Charset is the set of characters the password is made of, defaults to letters and digits
+	// +optional
+	Charset string `json:"charset,omitempty"`
+}
+
+// KeyAlgorithm is the algorithm of a generated key pair
+// +kubebuilder:validation:Enum=RSA;Ed25519
+type KeyAlgorithm string
+
+const (
+	// KeyAlgorithmRSA generates an RSA key pair
+	KeyAlgorithmRSA KeyAlgorithm = "RSA"
+	// KeyAlgorithmEd25519 generates an Ed25519 key pair
+	KeyAlgorithmEd25519 KeyAlgorithm = "Ed25519"
+)
+
+// KeyPairGenerator defines a generated key pair
+type KeyPairGenerator struct {
+	// Algorithm of the key pair, defaults to RSA
+	// +optional
+	Algorithm KeyAlgorithm `json:"algorithm,omitempty"`
+
+	// Bits is the size of RSA keys, defaults to 2048
+	// +kubebuilder:validation:Minimum=2048
+	// +kubebuilder:validation:Maximum=8192
+	// +optional
+	Bits int32 `json:"bits,omitempty"`
+}
+
+// TLSGenerator defines a generated self-signed certificate
+type TLSGenerator struct {
+	// DNSNames of the certificate, defaults to the preview hostname of the environment
+	// +optional
+	DNSNames []string `json:"dnsNames,omitempty"`
+
+	// Validity of the certificate, defaults to one year
+	// +optional
+	Validity *metav1.Duration `json:"validity,omitempty"`
+}
+
+// SyncMode defines when a secret or configmap is copied from its source
+// +kubebuilder:validation:Enum=Once;Continuous
+type SyncMode string
+
+const (
+	// SyncModeOnce copies the source when the environment is created or its spec changes
+	SyncModeOnce SyncMode = "Once"
+	// SyncModeContinuous also copies the source again whenever it changes (e.g., a rotated password)
+	SyncModeContinuous SyncMode = "Continuous"
+)
+
 // SyncPolicy defines the sync behavior
+// When Automated is omitted the ArgoCD Application is created in manual sync mode
 type SyncPolicy struct {
 	// Automated defines if the application should auto-sync
 	// +optional
 	Automated *AutomatedSyncPolicy `json:"automated,omitempty"`
 
 	// Prune specifies whether to delete resources that are no longer defined
+	// Only applied when Automated is set
 	// +optional
 	Prune bool `json:"prune,omitempty"`
 
 	// SelfHeal specifies whether to revert resources back to their desired state
+	// Only applied when Automated is set
 	// +optional
 	SelfHeal bool `json:"selfHeal,omitempty"`
+
+	// SyncOptions are passed through to the ArgoCD Application (e.g. "CreateNamespace=true")
+	// +optional
+	SyncOptions []string `json:"syncOptions,omitempty"`
+
+	// Retry controls the retry behavior of failed syncs
+	// +optional
+	Retry *RetryPolicy `json:"retry,omitempty"`
 }
 
 // AutomatedSyncPolicy defines automated sync options
@@ -105,6 +599,36 @@ type AutomatedSyncPolicy struct {
 	// SelfHeal specifies whether to revert resources during auto-sync
 	// +optional
 	SelfHeal bool `json:"selfHeal,omitempty"`
+
+	// AllowEmpty allows auto-sync to delete all application resources
+	// +optional
+	AllowEmpty bool `json:"allowEmpty,omitempty"`
+}
+
+// RetryPolicy defines how failed syncs are retried
+type RetryPolicy struct {
+	// Limit is the maximum number of attempts, a negative value means unlimited
+	// +optional
+	Limit int64 `json:"limit,omitempty"`
+
+	// Backoff controls the delay between retries
+	// +optional
+	Backoff *BackoffPolicy `json:"backoff,omitempty"`
+}
+
+// BackoffPolicy defines the backoff strategy between sync retries
+type BackoffPolicy struct {
+	// Duration is the base delay, as a duration string (e.g. "5s", "2m")
+	// +optional
+	Duration string `json:"duration,omitempty"`
+
+	// Factor multiplies the delay after each failed retry
+	// +optional
+	Factor *int64 `json:"factor,omitempty"`
+
+	// MaxDuration is the maximum delay between retries (e.g. "3m")
+	// +optional
+	MaxDuration string `json:"maxDuration,omitempty"`
 }
 
 // EphemeralApplicationStatus defines the observed state of EphemeralApplication
@@ -113,6 +637,14 @@ type EphemeralApplicationStatus struct {
 	// +optional
 	Phase EphemeralApplicationPhase `json:"phase,omitempty"`
 
+	// ObservedGeneration is the most recent generation propagated to the ArgoCD Application
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// TemplateGeneration is the generation of the EphemeralApplicationTemplate propagated to the ArgoCD Applications
+	// +optional
+	TemplateGeneration int64 `json:"templateGeneration,omitempty"`
+
 	// Namespace is the actual namespace created for this ephemeral application
 	// +optional
 	Namespace string `json:"namespace,omitempty"`
@@ -121,6 +653,22 @@ type EphemeralApplicationStatus struct {
 	// +optional
 	ArgoApplicationName string `json:"argoApplicationName,omitempty"`
 
+	// AppProject is the name of the ArgoCD AppProject created for this environment
+	// +optional
+	AppProject string `json:"appProject,omitempty"`
+
+	// DestinationServer is the API server URL of the cluster the environment is deployed to
+	// +optional
+	DestinationServer string `json:"destinationServer,omitempty"`
+
+	// PreviewURL is the URL the environment is exposed at when the operator has a preview domain
+	// +optional
+	PreviewURL string `json:"previewURL,omitempty"`
+
+	// Components contains the status of each component when spec.components is used
+	// +optional
+	Components []ComponentStatus `json:"components,omitempty"`
+
 	// Conditions represent the latest available observations of the application's state
 	// +optional
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
@@ -129,22 +677,122 @@ type EphemeralApplicationStatus struct {
 	// +optional
 	Message string `json:"message,omitempty"`
 
+	// ExpiresAt is the effective expiry computed from ExpirationDate or TTL
+	// and capped by the EphemeralPolicies
+	// +optional
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+
+	// Extensions is the number of times the expiry has been postponed
+	// +optional
+	Extensions int32 `json:"extensions,omitempty"`
+
+	// HibernatedAt is the time the workloads were scaled to zero, unset while awake
+	// +optional
+	HibernatedAt *metav1.Time `json:"hibernatedAt,omitempty"`
+
+	// NextHibernationTransition is the next scheduled time the environment sleeps or wakes up
+	// +optional
+	NextHibernationTransition *metav1.Time `json:"nextHibernationTransition,omitempty"`
+
 	// LastSyncTime is the last time the application was synced
 	// +optional
 	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
 
-	// CopiedSecrets contains the list of secrets that were copied
-	// Format: "source-ns/source-name -> target-name"
+	// InjectedResources contains the status of every secret and configmap injected in the namespace
 	// +optional
-	CopiedSecrets []string `json:"copiedSecrets,omitempty"`
+	InjectedResources []InjectedResourceStatus `json:"injectedResources,omitempty"`
 
-	// CopiedConfigMaps contains the list of configmaps that were copied
+	// Hooks contains the status of the hooks that were started
 	// +optional
-	CopiedConfigMaps []string `json:"copiedConfigMaps,omitempty"`
+	Hooks []HookStatus `json:"hooks,omitempty"`
 }
 
+// HookStatus defines the observed state of the Job of a hook
+type HookStatus struct {
+	// Stage the hook runs at
+	Stage HookStage `json:"stage"`
+
+	// Name of the hook
+	Name string `json:"name"`
+
+	// JobName is the name of the Job in the ephemeral namespace
+	JobName string `json:"jobName"`
+
+	// Phase of the Job
+	Phase HookPhase `json:"phase"`
+
+	// StartedAt is the time the Job was created
+	// +optional
+	StartedAt *metav1.Time `json:"startedAt,omitempty"`
+
+	// CompletedAt is the time the Job was seen succeeded or failed
+	// +optional
+	CompletedAt *metav1.Time `json:"completedAt,omitempty"`
+
+	// Message is the reason the Job failed
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// InjectedResourceStatus defines the observed state of a secret or configmap injected in the namespace
+type InjectedResourceStatus struct {
+	// Kind is Secret or ConfigMap
+	Kind string `json:"kind"`
+
+	// Name of the object in the ephemeral namespace
+	Name string `json:"name"`
+
+	// SourceNamespace and SourceName identify the copied object, empty for inline and generated ones
+	// +optional
+	SourceNamespace string `json:"sourceNamespace,omitempty"`
+	// +optional
+	SourceName string `json:"sourceName,omitempty"`
+
+	// Hash is the SHA-256 of the injected data, it changes whenever the data does
+	// +optional
+	Hash string `json:"hash,omitempty"`
+
+	// LastSyncedTime is the last time the object was written with new data
+	// +optional
+	LastSyncedTime *metav1.Time `json:"lastSyncedTime,omitempty"`
+
+	// LastSyncedResourceVersion is the resourceVersion of the source when it was last copied
+	// +optional
+	LastSyncedResourceVersion string `json:"lastSyncedResourceVersion,omitempty"`
+
+	// Error is the reason the last attempt to inject the object failed, empty when it succeeded
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+// ComponentStatus defines the observed state of a single component
+type ComponentStatus struct {
+	// Name of the component
+	Name string `json:"name"`
+
+	// ArgoApplicationName is the name of the ArgoCD Application created for the component
+	ArgoApplicationName string `json:"argoApplicationName"`
+
+	// SyncStatus is the ArgoCD sync status of the component (e.g. Synced, OutOfSync)
+	// +optional
+	SyncStatus string `json:"syncStatus,omitempty"`
+
+	// HealthStatus is the ArgoCD health status of the component (e.g. Healthy, Progressing)
+	// +optional
+	HealthStatus string `json:"healthStatus,omitempty"`
+}
+
+const (
+	// CreatedByAnnotation records the user that created the EphemeralApplication
+	CreatedByAnnotation = "ephemeral.argo.io/created-by"
+
+	// WakeRequestedAnnotation records when a hibernating environment was woken up manually (RFC3339)
+	// The environment stays awake until the next scheduled sleep
+	WakeRequestedAnnotation = "ephemeral.argo.io/wake-requested-at"
+)
+
 // EphemeralApplicationPhase represents the phase of an ephemeral application
-// +kubebuilder:validation:Enum=Pending;Creating;Active;Expiring;Failed
+// +kubebuilder:validation:Enum=Pending;Creating;Updating;Active;Hibernating;Expiring;Failed
 type EphemeralApplicationPhase string
 
 const (
@@ -152,8 +800,12 @@ const (
 	PhasePending EphemeralApplicationPhase = "Pending"
 	// PhaseCreating indicates the application is being created
 	PhaseCreating EphemeralApplicationPhase = "Creating"
+	// PhaseUpdating indicates a spec change is being rolled out to the ArgoCD Application
+	PhaseUpdating EphemeralApplicationPhase = "Updating"
 	// PhaseActive indicates the application is active and running
 	PhaseActive EphemeralApplicationPhase = "Active"
+	// PhaseHibernating indicates the workloads are scaled to zero until the next wake
+	PhaseHibernating EphemeralApplicationPhase = "Hibernating"
 	// PhaseExpiring indicates the application is being deleted due to expiration
 	PhaseExpiring EphemeralApplicationPhase = "Expiring"
 	// PhaseFailed indicates the application has failed
@@ -165,7 +817,7 @@ const (
 // +kubebuilder:resource:shortName=ephapp
 // +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
 // +kubebuilder:printcolumn:name="Namespace",type=string,JSONPath=`.status.namespace`
-// +kubebuilder:printcolumn:name="Expiration",type=date,JSONPath=`.spec.expirationDate`
+// +kubebuilder:printcolumn:name="Expiration",type=date,JSONPath=`.status.expiresAt`
 // +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // EphemeralApplication is the Schema for the ephemeralapplications API
@@ -0,0 +1,240 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// ValidateSource checks that the application source is consistently defined
+// A source is either a path in a Git repository or a chart in a Helm repository,
+// multi-component environments define one source per component. The name of the
+// EphemeralApplication is used to check the names of the ArgoCD Applications of the components
+func (s *EphemeralApplicationSpec) ValidateSource(name string) error {
+	if len(s.Components) == 0 {
+		return s.ResolvedComponents()[0].validate("spec")
+	}
+
+	if s.RepoURL != "" || s.Path != "" || s.Helm != nil || s.Kustomize != nil {
+		return fmt.Errorf("spec.components is mutually exclusive with spec.repoURL, spec.path, spec.helm and spec.kustomize")
+	}
+
+	names := make(map[string]bool, len(s.Components))
+	for i, component := range s.Components {
+		field := fmt.Sprintf("spec.components[%d]", i)
+		if component.Name == "" {
+			return fmt.Errorf("%s.name is required", field)
+		}
+		if names[component.Name] {
+			return fmt.Errorf("%s.name %q is duplicated", field, component.Name)
+		}
+		names[component.Name] = true
+
+		if errs := validation.IsDNS1123Label(component.Name); len(errs) > 0 {
+			return fmt.Errorf("%s.name %q is invalid: %s", field, component.Name, strings.Join(errs, ", "))
+		}
+		// ArgoCD records the name of the application <name>-<component> in a label of every resource
+		if name != "" {
+			appName := name + "-" + component.Name
+			if errs := validation.IsValidLabelValue(appName); len(errs) > 0 {
+				return fmt.Errorf("%s.name %q gives the invalid ArgoCD application name %q: %s", field, component.Name, appName, strings.Join(errs, ", "))
+			}
+		}
+
+		if err := component.validate(field); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ValidateResources checks the secrets and configmaps injected in the ephemeral namespace
+// Inline resources need a name to be created with, copied ones need the source namespace
+func (s *EphemeralApplicationSpec) ValidateResources() error {
+	for i, secret := range s.Secrets {
+		field := fmt.Sprintf("spec.secrets[%d]", i)
+		if secret.Generate != nil {
+			if err := secret.Generate.validate(field + ".generate"); err != nil {
+				return err
+			}
+			if len(secret.Values) > 0 || secret.SourceNamespace != "" {
+				return fmt.Errorf("%s.generate is mutually exclusive with values and sourceNamespace", field)
+			}
+			if secret.Name == "" && secret.TargetName == "" {
+				return fmt.Errorf("%s.name or %s.targetName is required when generate is set", field, field)
+			}
+			continue
+		}
+		if err := validateTemplates(field+".templates", secret.Templates); err != nil {
+			return err
+		}
+		// Secrets with templates and no source namespace are created inline too
+		if len(secret.Values) > 0 || (len(secret.Templates) > 0 && secret.SourceNamespace == "") {
+			if secret.Name == "" && secret.TargetName == "" {
+				return fmt.Errorf("%s.name or %s.targetName is required when values are set", field, field)
+			}
+			if len(secret.Keys) > 0 || len(secret.ExcludeKeys) > 0 || len(secret.RenameKeys) > 0 {
+				return fmt.Errorf("%s.keys, %s.excludeKeys and %s.renameKeys only apply to copied secrets", field, field, field)
+			}
+			continue
+		}
+		if secret.Name == "" {
+			return fmt.Errorf("%s.name is required", field)
+		}
+		if secret.SourceNamespace == "" {
+			return fmt.Errorf("%s.sourceNamespace is required", field)
+		}
+		if len(secret.Keys) > 0 && len(secret.ExcludeKeys) > 0 {
+			return fmt.Errorf("%s.keys and %s.excludeKeys are mutually exclusive", field, field)
+		}
+	}
+
+	for i, cm := range s.ConfigMaps {
+		field := fmt.Sprintf("spec.configMaps[%d]", i)
+		if cm.Name == "" {
+			return fmt.Errorf("%s.name is required", field)
+		}
+		if len(cm.Data) == 0 && len(cm.Templates) == 0 && cm.SourceNamespace == "" {
+			return fmt.Errorf("either %s.data, %s.templates or %s.sourceNamespace must be set", field, field, field)
+		}
+		if err := validateTemplates(field+".templates", cm.Templates); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ValidateNamespaceName checks that the requested namespace is a valid DNS-1123 label
+func (s *EphemeralApplicationSpec) ValidateNamespaceName() error {
+	if s.NamespaceName == "" {
+		return nil
+	}
+	if errs := validation.IsDNS1123Label(s.NamespaceName); len(errs) > 0 {
+		return fmt.Errorf("spec.namespaceName %q is invalid: %s", s.NamespaceName, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// ValidateDestination checks that the destination selects exactly one cluster
+func (s *EphemeralApplicationSpec) ValidateDestination() error {
+	if s.Destination == nil {
+		return nil
+	}
+	hasName := s.Destination.Name != ""
+	hasSecret := s.Destination.KubeconfigSecretRef != nil
+	if hasName == hasSecret {
+		return fmt.Errorf("exactly one of spec.destination.name or spec.destination.kubeconfigSecretRef must be set")
+	}
+	if hasSecret && s.Destination.KubeconfigSecretRef.Name == "" {
+		return fmt.Errorf("spec.destination.kubeconfigSecretRef.name is required")
+	}
+	return nil
+}
+
+// ResolvedComponents returns the components of the environment
+// A single-source spec is returned as one unnamed component built from the top-level fields
+func (s *EphemeralApplicationSpec) ResolvedComponents() []Component {
+	if len(s.Components) > 0 {
+		return s.Components
+	}
+
+	return []Component{{
+		RepoURL:        s.RepoURL,
+		Path:           s.Path,
+		TargetRevision: s.TargetRevision,
+		Helm:           s.Helm,
+		Kustomize:      s.Kustomize,
+	}}
+}
+
+// validate checks the component source, field is the path used in error messages
+func (c *Component) validate(field string) error {
+	if c.RepoURL == "" {
+		return fmt.Errorf("%s.repoURL is required", field)
+	}
+
+	chart := ""
+	if c.Helm != nil {
+		chart = c.Helm.Chart
+	}
+
+	if chart != "" && c.Path != "" {
+		return fmt.Errorf("%s.path and %s.helm.chart are mutually exclusive", field, field)
+	}
+	if chart == "" && c.Path == "" {
+		return fmt.Errorf("either %s.path or %s.helm.chart must be set", field, field)
+	}
+
+	if c.Helm != nil {
+		for i, param := range c.Helm.Parameters {
+			if param.Name == "" {
+				return fmt.Errorf("%s.helm.parameters[%d].name is required", field, i)
+			}
+		}
+	}
+
+	if c.Kustomize != nil {
+		if c.Helm != nil {
+			return fmt.Errorf("%s.helm and %s.kustomize are mutually exclusive", field, field)
+		}
+		for i, patch := range c.Kustomize.Patches {
+			if patch.Patch == "" {
+				return fmt.Errorf("%s.kustomize.patches[%d].patch is required", field, i)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validate checks the generated values, field is the path used in error messages
+func (g *SecretGenerator) validate(field string) error {
+	if len(g.Passwords) == 0 && g.KeyPair == nil && g.TLS == nil {
+		return fmt.Errorf("%s must set passwords, keyPair or tls", field)
+	}
+
+	// Password keys can not overwrite the keys of the generated key pair and certificate
+	keys := map[string]bool{}
+	if g.KeyPair != nil {
+		keys["privateKey"], keys["publicKey"] = true, true
+	}
+	if g.TLS != nil {
+		keys["tls.crt"], keys["tls.key"], keys["ca.crt"] = true, true, true
+	}
+	for i, password := range g.Passwords {
+		if password.Key == "" {
+			return fmt.Errorf("%s.passwords[%d].key is required", field, i)
+		}
+		if keys[password.Key] {
+			return fmt.Errorf("%s.passwords[%d].key %q is duplicated", field, i, password.Key)
+		}
+		keys[password.Key] = true
+	}
+
+	if g.TLS != nil {
+		for i, name := range g.TLS.DNSNames {
+			if errs := validation.IsDNS1123Subdomain(strings.TrimPrefix(name, "*.")); len(errs) > 0 {
+				return fmt.Errorf("%s.tls.dnsNames[%d] %q is invalid: %s", field, i, name, strings.Join(errs, ", "))
+			}
+		}
+		if g.TLS.Validity != nil && g.TLS.Validity.Duration <= 0 {
+			return fmt.Errorf("%s.tls.validity must be positive", field)
+		}
+	}
+
+	return nil
+}
+
+// validateTemplates checks that the templates of a secret or configmap can be parsed
+func validateTemplates(field string, templates map[string]string) error {
+	for key, text := range templates {
+		if _, err := template.New(key).Parse(text); err != nil {
+			return fmt.Errorf("%s[%s] is invalid: %w", field, key, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,346 @@
+package v1alpha1
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    EphemeralApplicationSpec
+		wantErr bool
+	}{
+		{
+			name: "git path",
+			spec: EphemeralApplicationSpec{RepoURL: "https://github.com/example/app.git", Path: "manifests"},
+		},
+		{
+			name: "helm chart",
+			spec: EphemeralApplicationSpec{
+				RepoURL: "https://charts.example.com",
+				Helm:    &HelmSource{Chart: "backend"},
+			},
+		},
+		{
+			name: "helm chart in git path",
+			spec: EphemeralApplicationSpec{
+				RepoURL: "https://github.com/example/app.git",
+				Path:    "charts/backend",
+				Helm:    &HelmSource{ValueFiles: []string{"values-preview.yaml"}},
+			},
+		},
+		{
+			name: "chart and path",
+			spec: EphemeralApplicationSpec{
+				RepoURL: "https://charts.example.com",
+				Path:    "manifests",
+				Helm:    &HelmSource{Chart: "backend"},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "neither chart nor path",
+			spec:    EphemeralApplicationSpec{RepoURL: "https://github.com/example/app.git"},
+			wantErr: true,
+		},
+		{
+			name: "parameter without name",
+			spec: EphemeralApplicationSpec{
+				RepoURL: "https://charts.example.com",
+				Helm: &HelmSource{
+					Chart:      "backend",
+					Parameters: []HelmParameter{{Value: "pr-42"}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "helm and kustomize",
+			spec: EphemeralApplicationSpec{
+				RepoURL:   "https://github.com/example/app.git",
+				Path:      "manifests",
+				Helm:      &HelmSource{},
+				Kustomize: &KustomizeSource{},
+			},
+			wantErr: true,
+		},
+		{
+			name: "empty kustomize patch",
+			spec: EphemeralApplicationSpec{
+				RepoURL:   "https://github.com/example/app.git",
+				Path:      "overlays/preview",
+				Kustomize: &KustomizeSource{Patches: []KustomizePatch{{}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "components",
+			spec: EphemeralApplicationSpec{
+				Components: []Component{
+					{Name: "frontend", RepoURL: "https://github.com/example/frontend.git", Path: "manifests"},
+					{Name: "database", RepoURL: "https://charts.example.com", Helm: &HelmSource{Chart: "postgresql"}},
+				},
+			},
+		},
+		{
+			name: "components and top-level source",
+			spec: EphemeralApplicationSpec{
+				RepoURL:    "https://github.com/example/app.git",
+				Path:       "manifests",
+				Components: []Component{{Name: "frontend", RepoURL: "https://github.com/example/frontend.git", Path: "manifests"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "component without name",
+			spec: EphemeralApplicationSpec{
+				Components: []Component{{RepoURL: "https://github.com/example/frontend.git", Path: "manifests"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicated component name",
+			spec: EphemeralApplicationSpec{
+				Components: []Component{
+					{Name: "frontend", RepoURL: "https://github.com/example/frontend.git", Path: "manifests"},
+					{Name: "frontend", RepoURL: "https://github.com/example/backend.git", Path: "manifests"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "component name is not a DNS-1123 label",
+			spec: EphemeralApplicationSpec{
+				Components: []Component{{Name: "Front_End", RepoURL: "https://github.com/example/frontend.git", Path: "manifests"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "application name of the component too long",
+			spec: EphemeralApplicationSpec{
+				Components: []Component{{Name: strings.Repeat("a", 56), RepoURL: "https://github.com/example/frontend.git", Path: "manifests"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "component without source",
+			spec: EphemeralApplicationSpec{
+				Components: []Component{{Name: "frontend", RepoURL: "https://github.com/example/frontend.git"}},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.spec.ValidateSource("preview")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSource() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateResources(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    EphemeralApplicationSpec
+		wantErr bool
+	}{
+		{
+			name: "copied secret and inline configmap",
+			spec: EphemeralApplicationSpec{
+				Secrets:    []SecretReference{{Name: "db", SourceNamespace: "shared"}},
+				ConfigMaps: []ConfigMapReference{{Name: "settings", Data: map[string]string{"key": "value"}}},
+			},
+		},
+		{
+			name: "inline secret named by targetName",
+			spec: EphemeralApplicationSpec{
+				Secrets: []SecretReference{{TargetName: "api-key", Values: map[string]string{"key": "value"}}},
+			},
+		},
+		{
+			name: "inline secret without name",
+			spec: EphemeralApplicationSpec{
+				Secrets: []SecretReference{{Values: map[string]string{"key": "value"}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "copied secret without source namespace",
+			spec: EphemeralApplicationSpec{
+				Secrets: []SecretReference{{Name: "db"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "copied secret with selected, renamed and templated keys",
+			spec: EphemeralApplicationSpec{
+				Secrets: []SecretReference{{
+					Name:            "db",
+					SourceNamespace: "shared",
+					Keys:            []string{"username", "password"},
+					RenameKeys:      map[string]string{"username": "DB_USER"},
+					Templates:       map[string]string{"DB_NAME": "app_{{ .Namespace }}"},
+				}},
+			},
+		},
+		{
+			name: "inline secret made of templates",
+			spec: EphemeralApplicationSpec{
+				Secrets: []SecretReference{{Name: "urls", Templates: map[string]string{"url": "{{ .PreviewURL }}"}}},
+			},
+		},
+		{
+			name: "secret with invalid template",
+			spec: EphemeralApplicationSpec{
+				Secrets: []SecretReference{{Name: "urls", Templates: map[string]string{"url": "{{ .PreviewURL"}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "inline secret with selected keys",
+			spec: EphemeralApplicationSpec{
+				Secrets: []SecretReference{{Name: "db", Values: map[string]string{"a": "b"}, Keys: []string{"a"}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "copied secret with keys and excluded keys",
+			spec: EphemeralApplicationSpec{
+				Secrets: []SecretReference{{
+					Name:            "db",
+					SourceNamespace: "shared",
+					Keys:            []string{"username"},
+					ExcludeKeys:     []string{"password"},
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "generated secret",
+			spec: EphemeralApplicationSpec{
+				Secrets: []SecretReference{{Name: "db", Generate: &SecretGenerator{
+					Passwords: []PasswordGenerator{{Key: "password"}},
+					TLS:       &TLSGenerator{DNSNames: []string{"*.preview.example.com"}},
+				}}},
+			},
+		},
+		{
+			name: "generated secret without values",
+			spec: EphemeralApplicationSpec{
+				Secrets: []SecretReference{{Name: "db", Generate: &SecretGenerator{}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "generated secret with source namespace",
+			spec: EphemeralApplicationSpec{
+				Secrets: []SecretReference{{Name: "db", SourceNamespace: "shared", Generate: &SecretGenerator{
+					Passwords: []PasswordGenerator{{Key: "password"}},
+				}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "generated password overwriting the certificate",
+			spec: EphemeralApplicationSpec{
+				Secrets: []SecretReference{{Name: "tls", Generate: &SecretGenerator{
+					Passwords: []PasswordGenerator{{Key: "tls.key"}},
+					TLS:       &TLSGenerator{},
+				}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "configmap merging data over its source",
+			spec: EphemeralApplicationSpec{
+				ConfigMaps: []ConfigMapReference{{
+					Name:            "settings",
+					SourceNamespace: "shared",
+					TargetName:      "app-settings",
+					Data:            map[string]string{"key": "value"},
+				}},
+			},
+		},
+		{
+			name: "configmap made of templates",
+			spec: EphemeralApplicationSpec{
+				ConfigMaps: []ConfigMapReference{{
+					Name:      "urls",
+					Templates: map[string]string{"PUBLIC_URL": "{{ .PreviewURL }}"},
+				}},
+			},
+		},
+		{
+			name: "configmap without data nor source namespace",
+			spec: EphemeralApplicationSpec{
+				ConfigMaps: []ConfigMapReference{{Name: "settings"}},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.spec.ValidateResources()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateResources() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateNamespaceName(t *testing.T) {
+	tests := []struct {
+		namespaceName string
+		wantErr       bool
+	}{
+		{namespaceName: ""},
+		{namespaceName: "feature-login"},
+		{namespaceName: "Feature_Login", wantErr: true},
+		{namespaceName: "-leading-dash", wantErr: true},
+		{namespaceName: "this-is-a-very-long-namespace-name-that-exceeds-kubernetes-limits", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.namespaceName, func(t *testing.T) {
+			spec := EphemeralApplicationSpec{NamespaceName: tt.namespaceName}
+			err := spec.ValidateNamespaceName()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateNamespaceName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateDestination(t *testing.T) {
+	tests := []struct {
+		name        string
+		destination *Destination
+		wantErr     bool
+	}{
+		{name: "in-cluster"},
+		{name: "ArgoCD cluster", destination: &Destination{Name: "staging"}},
+		{name: "kubeconfig secret", destination: &Destination{KubeconfigSecretRef: &KubeconfigSecretReference{Name: "staging"}}},
+		{name: "empty destination", destination: &Destination{}, wantErr: true},
+		{
+			name:        "both cluster and kubeconfig",
+			destination: &Destination{Name: "staging", KubeconfigSecretRef: &KubeconfigSecretReference{Name: "staging"}},
+			wantErr:     true,
+		},
+		{name: "kubeconfig secret without name", destination: &Destination{KubeconfigSecretRef: &KubeconfigSecretReference{}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := EphemeralApplicationSpec{Destination: tt.destination}
+			err := spec.ValidateDestination()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateDestination() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
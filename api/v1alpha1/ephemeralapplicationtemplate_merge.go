@@ -0,0 +1,147 @@
+package v1alpha1
+
+// MergeTemplate returns the spec with the template defaults filled in
+// Fields set on the spec override the template, components are merged by name,
+// secrets by target name and configmaps by name
+func (s *EphemeralApplicationSpec) MergeTemplate(template *EphemeralApplicationTemplateSpec) EphemeralApplicationSpec {
+	merged := *s.DeepCopy()
+	defaults := template.DeepCopy()
+
+	// An instance switching between a single source and components does not inherit the other kind
+	switch {
+	case len(merged.Components) > 0 && len(defaults.Components) == 0:
+		defaults.RepoURL, defaults.Path, defaults.TargetRevision = "", "", ""
+		defaults.Helm, defaults.Kustomize = nil, nil
+	case merged.RepoURL != "" && len(defaults.Components) > 0:
+		defaults.Components = nil
+	}
+
+	source := mergeComponent(Component{
+		RepoURL:        defaults.RepoURL,
+		Path:           defaults.Path,
+		TargetRevision: defaults.TargetRevision,
+		Helm:           defaults.Helm,
+		Kustomize:      defaults.Kustomize,
+	}, Component{
+		RepoURL:        merged.RepoURL,
+		Path:           merged.Path,
+		TargetRevision: merged.TargetRevision,
+		Helm:           merged.Helm,
+		Kustomize:      merged.Kustomize,
+	})
+	merged.RepoURL = source.RepoURL
+	merged.Path = source.Path
+	merged.TargetRevision = source.TargetRevision
+	merged.Helm = source.Helm
+	merged.Kustomize = source.Kustomize
+
+	merged.Components = mergeComponents(defaults.Components, merged.Components)
+	merged.Secrets = mergeSecrets(defaults.Secrets, merged.Secrets)
+	merged.ConfigMaps = mergeConfigMaps(defaults.ConfigMaps, merged.ConfigMaps)
+
+	if merged.SyncPolicy == nil {
+		merged.SyncPolicy = defaults.SyncPolicy
+	}
+
+	return merged
+}
+
+// mergeComponent overrides the fields of base that are set in override
+func mergeComponent(base, override Component) Component {
+	if override.RepoURL != "" {
+		base.RepoURL = override.RepoURL
+	}
+	if override.Path != "" {
+		base.Path = override.Path
+	}
+	if override.TargetRevision != "" {
+		base.TargetRevision = override.TargetRevision
+	}
+	if override.Helm != nil {
+		base.Helm = override.Helm
+	}
+	if override.Kustomize != nil {
+		base.Kustomize = override.Kustomize
+	}
+	return base
+}
+
+// mergeComponents merges components with the same name, new components are appended
+func mergeComponents(base, overrides []Component) []Component {
+	merged := append([]Component{}, base...)
+	for _, override := range overrides {
+		found := false
+		for i := range merged {
+			if merged[i].Name == override.Name {
+				merged[i] = mergeComponent(merged[i], override)
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, override)
+		}
+	}
+	if len(merged) == 0 {
+		return nil
+	}
+	return merged
+}
+
+// mergeSecrets replaces secrets copied to the same target name, new secrets are appended
+func mergeSecrets(base, overrides []SecretReference) []SecretReference {
+	targetName := func(secret SecretReference) string {
+		if secret.TargetName != "" {
+			return secret.TargetName
+		}
+		return secret.Name
+	}
+
+	merged := append([]SecretReference{}, base...)
+	for _, override := range overrides {
+		found := false
+		for i := range merged {
+			if targetName(merged[i]) == targetName(override) {
+				merged[i] = override
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, override)
+		}
+	}
+	if len(merged) == 0 {
+		return nil
+	}
+	return merged
+}
+
+// mergeConfigMaps replaces configmaps with the same name, new configmaps are appended
+func mergeConfigMaps(base, overrides []ConfigMapReference) []ConfigMapReference {
+	targetName := func(cm ConfigMapReference) string {
+		if cm.TargetName != "" {
+			return cm.TargetName
+		}
+		return cm.Name
+	}
+
+	merged := append([]ConfigMapReference{}, base...)
+	for _, override := range overrides {
+		found := false
+		for i := range merged {
+			if targetName(merged[i]) == targetName(override) {
+				merged[i] = override
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, override)
+		}
+	}
+	if len(merged) == 0 {
+		return nil
+	}
+	return merged
+}
@@ -0,0 +1,104 @@
+package v1alpha1
+
+import (
+	"testing"
+)
+
+func TestMergeTemplate_OverridesTargetRevision(t *testing.T) {
+	template := &EphemeralApplicationTemplateSpec{
+		RepoURL:        "https://github.com/example/app.git",
+		Path:           "manifests",
+		TargetRevision: "main",
+		Secrets:        []SecretReference{{Name: "db-credentials", SourceNamespace: "shared-secrets"}},
+		ConfigMaps:     []ConfigMapReference{{Name: "app-config", Data: map[string]string{"LOG_LEVEL": "info"}}},
+		SyncPolicy:     &SyncPolicy{Automated: &AutomatedSyncPolicy{Prune: true}},
+	}
+	spec := &EphemeralApplicationSpec{
+		TemplateRef:    &TemplateReference{Name: "backend"},
+		TargetRevision: "feature-x",
+	}
+
+	got := spec.MergeTemplate(template)
+
+	if got.RepoURL != "https://github.com/example/app.git" || got.Path != "manifests" {
+		t.Errorf("expected source from template, got repoURL '%s' path '%s'", got.RepoURL, got.Path)
+	}
+	if got.TargetRevision != "feature-x" {
+		t.Errorf("expected targetRevision 'feature-x', got '%s'", got.TargetRevision)
+	}
+	if len(got.Secrets) != 1 || len(got.ConfigMaps) != 1 {
+		t.Errorf("expected secrets and configmaps from template, got %v and %v", got.Secrets, got.ConfigMaps)
+	}
+	if got.SyncPolicy == nil || got.SyncPolicy.Automated == nil || !got.SyncPolicy.Automated.Prune {
+		t.Errorf("expected sync policy from template, got %+v", got.SyncPolicy)
+	}
+
+	// The template must not be modified through the merged spec
+	got.ConfigMaps[0].Data["LOG_LEVEL"] = "debug"
+	if template.ConfigMaps[0].Data["LOG_LEVEL"] != "info" {
+		t.Error("expected template to be left untouched")
+	}
+}
+
+func TestMergeTemplate_MergesByName(t *testing.T) {
+	template := &EphemeralApplicationTemplateSpec{
+		Components: []Component{
+			{Name: "frontend", RepoURL: "https://github.com/example/frontend.git", Path: "manifests", TargetRevision: "main"},
+			{Name: "backend", RepoURL: "https://github.com/example/backend.git", Path: "manifests", TargetRevision: "main"},
+		},
+		Secrets: []SecretReference{
+			{Name: "db-credentials", SourceNamespace: "shared-secrets"},
+			{Name: "api-keys", SourceNamespace: "shared-secrets"},
+		},
+		ConfigMaps: []ConfigMapReference{{Name: "app-config", Data: map[string]string{"LOG_LEVEL": "info"}}},
+	}
+	spec := &EphemeralApplicationSpec{
+		Components: []Component{{Name: "backend", TargetRevision: "pr-42"}},
+		Secrets: []SecretReference{
+			{Name: "db-credentials-staging", SourceNamespace: "staging", TargetName: "db-credentials"},
+		},
+		ConfigMaps: []ConfigMapReference{{Name: "feature-flags", Data: map[string]string{"NEW_UI": "true"}}},
+	}
+
+	got := spec.MergeTemplate(template)
+
+	if len(got.Components) != 2 {
+		t.Fatalf("expected 2 components, got %d", len(got.Components))
+	}
+	backend := got.Components[1]
+	if backend.RepoURL != "https://github.com/example/backend.git" || backend.TargetRevision != "pr-42" {
+		t.Errorf("unexpected backend component: %+v", backend)
+	}
+	if got.Components[0].TargetRevision != "main" {
+		t.Errorf("expected frontend to keep targetRevision 'main', got '%s'", got.Components[0].TargetRevision)
+	}
+
+	if len(got.Secrets) != 2 || got.Secrets[0].SourceNamespace != "staging" {
+		t.Errorf("expected db-credentials to be replaced, got %+v", got.Secrets)
+	}
+	if len(got.ConfigMaps) != 2 || got.ConfigMaps[1].Name != "feature-flags" {
+		t.Errorf("expected feature-flags to be appended, got %+v", got.ConfigMaps)
+	}
+	if err := got.ValidateSource("preview"); err != nil {
+		t.Errorf("expected merged spec to be valid, got %v", err)
+	}
+}
+
+func TestMergeTemplate_ComponentsReplaceSingleSource(t *testing.T) {
+	template := &EphemeralApplicationTemplateSpec{
+		RepoURL: "https://github.com/example/app.git",
+		Path:    "manifests",
+	}
+	spec := &EphemeralApplicationSpec{
+		Components: []Component{{Name: "frontend", RepoURL: "https://github.com/example/frontend.git", Path: "manifests"}},
+	}
+
+	got := spec.MergeTemplate(template)
+
+	if got.RepoURL != "" || got.Path != "" {
+		t.Errorf("expected single source not to be inherited, got repoURL '%s' path '%s'", got.RepoURL, got.Path)
+	}
+	if err := got.ValidateSource("preview"); err != nil {
+		t.Errorf("expected merged spec to be valid, got %v", err)
+	}
+}
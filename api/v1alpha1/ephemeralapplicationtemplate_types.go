@@ -0,0 +1,76 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EphemeralApplicationTemplateSpec defines the defaults shared by the EphemeralApplications referencing the template
+// Every field can be overridden by the EphemeralApplication
+type EphemeralApplicationTemplateSpec struct {
+	// Description is a human readable summary of the environment the template creates
+	// +optional
+	Description string `json:"description,omitempty"`
+
+	// RepoURL is the Git repository URL containing the application manifests
+	// +optional
+	RepoURL string `json:"repoURL,omitempty"`
+
+	// Path is the path within the Git repository
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// TargetRevision is the Git revision to deploy (branch, tag, or commit)
+	// When Helm.Chart is set, it is the chart version
+	// +optional
+	TargetRevision string `json:"targetRevision,omitempty"`
+
+	// Helm defines Helm specific options for the application source
+	// +optional
+	Helm *HelmSource `json:"helm,omitempty"`
+
+	// Kustomize defines Kustomize overrides for the application source
+	// +optional
+	Kustomize *KustomizeSource `json:"kustomize,omitempty"`
+
+	// Components are the applications that make up the environment
+	// +optional
+	Components []Component `json:"components,omitempty"`
+
+	// Secrets to copy from other namespaces into the ephemeral namespace
+	// +optional
+	Secrets []SecretReference `json:"secrets,omitempty"`
+
+	// ConfigMaps to copy from other namespaces or create inline
+	// +optional
+	ConfigMaps []ConfigMapReference `json:"configMaps,omitempty"`
+
+	// SyncPolicy defines how the application should be synced
+	// +optional
+	SyncPolicy *SyncPolicy `json:"syncPolicy,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:shortName=ephtmpl
+// +kubebuilder:printcolumn:name="Description",type=string,JSONPath=`.spec.description`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// EphemeralApplicationTemplate is the Schema for the ephemeralapplicationtemplates API
+type EphemeralApplicationTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec EphemeralApplicationTemplateSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// EphemeralApplicationTemplateList contains a list of EphemeralApplicationTemplate
+type EphemeralApplicationTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []EphemeralApplicationTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&EphemeralApplicationTemplate{}, &EphemeralApplicationTemplateList{})
+}
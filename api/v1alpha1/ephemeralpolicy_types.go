@@ -0,0 +1,101 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EphemeralPolicySpec defines the guardrails applied to every EphemeralApplication in the cluster
+// When several policies exist all of them must be satisfied
+type EphemeralPolicySpec struct {
+	// MaxTTL is the maximum lifetime of an environment counted from its creation (e.g., "168h")
+	// Longer expirations are rejected by the webhook and capped by the operator
+	// +optional
+	MaxTTL *metav1.Duration `json:"maxTTL,omitempty"`
+
+	// MaxExtensions is the maximum number of times the expiry of an environment can be postponed
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxExtensions *int32 `json:"maxExtensions,omitempty"`
+
+	// AllowedRepoURLs are glob patterns the repository of every source must match
+	// (e.g., "https://github.com/my-org/*"). Any repository is allowed when empty
+	// +optional
+	AllowedRepoURLs []string `json:"allowedRepoURLs,omitempty"`
+
+	// AllowedSourceNamespaces are the namespaces secrets and configmaps can be copied from
+	// Any namespace is allowed when empty
+	// +optional
+	AllowedSourceNamespaces []string `json:"allowedSourceNamespaces,omitempty"`
+
+	// AllowedDestinations are glob patterns the name of the ArgoCD cluster of spec.destination
+	// must match (e.g., "preview-*"). Environments without destination are always allowed, those
+	// using a kubeconfig are rejected since their cluster is only known once it is read
+	// Any destination is allowed when empty
+	// +optional
+	AllowedDestinations []string `json:"allowedDestinations,omitempty"`
+
+	// MaxEnvironmentsPerNamespace is the maximum number of concurrent environments in a namespace
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxEnvironmentsPerNamespace *int32 `json:"maxEnvironmentsPerNamespace,omitempty"`
+
+	// MaxEnvironmentsPerUser is the maximum number of concurrent environments created by the same user
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxEnvironmentsPerUser *int32 `json:"maxEnvironmentsPerUser,omitempty"`
+
+	// Namespace provides the defaults of the ephemeral namespaces, labels, annotations and
+	// container defaults can be overridden by the environments while quotas and container
+	// maximums can only be lowered
+	// +optional
+	Namespace *NamespaceOptions `json:"namespace,omitempty"`
+
+	// AppProject configures the ArgoCD AppProject created for every environment when the
+	// operator runs with CREATE_APP_PROJECTS enabled
+	// +optional
+	AppProject *AppProjectPolicy `json:"appProject,omitempty"`
+}
+
+// AppProjectPolicy configures the AppProject of the environments, the destinations of the
+// project are always restricted to the ephemeral namespace
+type AppProjectPolicy struct {
+	// SourceRepos are additional repositories the applications may deploy from (e.g., remote
+	// Kustomize bases), the repositories of the environment are always allowed
+	// +optional
+	SourceRepos []string `json:"sourceRepos,omitempty"`
+
+	// ClusterResourceWhitelist are the cluster-scoped resources the environments may deploy
+	// No cluster-scoped resource is allowed when empty
+	// +optional
+	ClusterResourceWhitelist []metav1.GroupKind `json:"clusterResourceWhitelist,omitempty"`
+
+	// ClusterResourceBlacklist are the cluster-scoped resources the environments may never deploy
+	// +optional
+	ClusterResourceBlacklist []metav1.GroupKind `json:"clusterResourceBlacklist,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,shortName=ephpol
+// +kubebuilder:printcolumn:name="Max TTL",type=string,JSONPath=`.spec.maxTTL`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// EphemeralPolicy is the Schema for the ephemeralpolicies API
+type EphemeralPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec EphemeralPolicySpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// EphemeralPolicyList contains a list of EphemeralPolicy
+type EphemeralPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []EphemeralPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&EphemeralPolicy{}, &EphemeralPolicyList{})
+}
@@ -2,19 +2,31 @@ package main
 
 import (
 	"flag"
+	"net"
 	"os"
+	// Embed the time zone database, hibernation schedules are evaluated in any IANA time zone
+	_ "time/tzdata"
 
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
+	argov1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
 	ephemeralv1alpha1 "github.com/jbarea/argo-ephemeral-operator/api/v1alpha1"
 	"github.com/jbarea/argo-ephemeral-operator/internal/argocd"
+	"github.com/jbarea/argo-ephemeral-operator/internal/cluster"
 	"github.com/jbarea/argo-ephemeral-operator/internal/config"
 	"github.com/jbarea/argo-ephemeral-operator/internal/controller"
+	"github.com/jbarea/argo-ephemeral-operator/internal/naming"
+	webhookv1alpha1 "github.com/jbarea/argo-ephemeral-operator/internal/webhook/v1alpha1"
 )
 
 var (
@@ -25,6 +37,7 @@ var (
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 	utilruntime.Must(ephemeralv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(argov1alpha1.AddToScheme(scheme))
 }
 
 func main() {
@@ -54,9 +67,11 @@ func main() {
 	}
 
 	setupLog.Info("starting argo-ephemeral-operator",
+		"argoBackend", cfg.ArgoBackend,
 		"argoServer", cfg.ArgoServer,
 		"argoNamespace", cfg.ArgoNamespace,
 		"reconcileInterval", cfg.ReconcileInterval,
+		"watchArgoApplications", cfg.WatchArgoApplications,
 	)
 
 	// Override config with command line flags if provided
@@ -69,35 +84,74 @@ func main() {
 	cfg.EnableLeaderElection = enableLeaderElection
 
 	// Create manager
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+	mgrOptions := ctrl.Options{
 		Scheme:           scheme,
 		LeaderElection:   cfg.EnableLeaderElection,
 		LeaderElectionID: cfg.LeaderElectionID,
-	})
+		WebhookServer: webhook.NewServer(webhook.Options{
+			Port:    cfg.WebhookPort,
+			CertDir: cfg.WebhookCertDir,
+		}),
+	}
+
+	// Read secrets and configmaps from the API server instead of caching every one of the cluster
+	mgrOptions.Client = client.Options{
+		Cache: &client.CacheOptions{
+			DisableFor: []client.Object{&corev1.Secret{}, &corev1.ConfigMap{}},
+		},
+	}
+
+	// Only cache the Jobs running hooks
+	mgrOptions.Cache = cache.Options{
+		ByObject: map[client.Object]cache.ByObject{
+			&batchv1.Job{}: {Label: controller.HookJobSelector()},
+		},
+	}
+
+	// Only cache the ArgoCD Applications of the ArgoCD namespace and serve their reads from the cache
+	if cfg.WatchArgoApplications {
+		mgrOptions.Cache.ByObject[controller.NewArgoApplicationObject()] = cache.ByObject{
+			Namespaces: map[string]cache.Config{cfg.ArgoNamespace: {}},
+		}
+		mgrOptions.Client.Cache.Unstructured = true
+	}
+
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), mgrOptions)
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
 	// Create ArgoCD client
-	argoClient, err := argocd.NewClient(cfg.ArgoServer, cfg.ArgoPort, cfg.ArgoUsername, cfg.ArgoPassword, cfg.ArgoInsecure)
+	argoClient, err := newArgoClient(mgr, cfg)
 	if err != nil {
-		setupLog.Error(err, "unable to create ArgoCD client")
+		setupLog.Error(err, "unable to create ArgoCD client", "backend", cfg.ArgoBackend)
 		os.Exit(1)
 	}
 
+	nameGenerator := naming.NewDefaultGenerator()
+
 	// Setup reconciler
 	if err = (&controller.EphemeralApplicationReconciler{
 		Client:        mgr.GetClient(),
 		Scheme:        mgr.GetScheme(),
 		ArgoClient:    argoClient,
 		Config:        cfg,
-		NameGenerator: controller.NewDefaultNameGenerator(),
+		NameGenerator: nameGenerator,
+		Clusters:      cluster.NewCache(mgr.GetClient(), mgr.GetScheme(), cfg.ArgoNamespace),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "EphemeralApplication")
 		os.Exit(1)
 	}
 
+	// Setup admission webhooks, they require a serving certificate
+	if cfg.EnableWebhooks {
+		if err = webhookv1alpha1.SetupWebhookWithManager(mgr, nameGenerator, cfg.WebhookTrustedCreator); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "EphemeralApplication")
+			os.Exit(1)
+		}
+	}
+
 	// Add health and ready checks
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
@@ -114,3 +168,33 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// newArgoClient creates the ArgoCD client of the configured backend
+func newArgoClient(mgr ctrl.Manager, cfg *config.Config) (argocd.Client, error) {
+	if cfg.ArgoBackend != config.ArgoBackendKubernetes {
+		// Session tokens are requested on the default HTTPS port, without ARGO_PORT
+		return argocd.NewClient(argocd.Options{
+			ServerAddr:     net.JoinHostPort(cfg.ArgoServer, cfg.ArgoPort),
+			LoginAddr:      cfg.ArgoServer,
+			Token:          cfg.ArgoToken,
+			TokenFile:      cfg.ArgoTokenFile,
+			Username:       cfg.ArgoUsername,
+			Password:       cfg.ArgoPassword,
+			Insecure:       cfg.ArgoInsecure,
+			CAFile:         cfg.ArgoCAFile,
+			ClientCertFile: cfg.ArgoClientCertFile,
+			ClientKeyFile:  cfg.ArgoClientKeyFile,
+		})
+	}
+
+	// Read the Application resources from the API server, the cached client could return
+	// a stale resource version right after an update
+	c, err := client.New(mgr.GetConfig(), client.Options{
+		Scheme: mgr.GetScheme(),
+		Mapper: mgr.GetRESTMapper(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return argocd.NewKubernetesClient(c, cfg.ArgoNamespace), nil
+}
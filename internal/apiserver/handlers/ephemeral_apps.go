@@ -5,6 +5,7 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -54,6 +55,19 @@ func (h *EphemeralAppHandler) HandleSingle(w http.ResponseWriter, r *http.Reques
 
 	name := parts[0]
 
+	// Sub-resource actions: /api/v1/ephemeral-apps/{name}/{action}
+	if len(parts) > 1 && parts[1] != "" {
+		switch {
+		case parts[1] == "wake" && r.Method == http.MethodPost:
+			h.Wake(w, r, name)
+		case parts[1] == "wake":
+			respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		default:
+			respondError(w, "Not found", http.StatusNotFound)
+		}
+		return
+	}
+
 	switch r.Method {
 	case http.MethodGet:
 		h.Get(w, r, name)
@@ -118,6 +132,12 @@ func (h *EphemeralAppHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Either an absolute expirationDate or a relative ttl (e.g. "72h") is required
+	if err := ephApp.Spec.ValidateExpiration(); err != nil {
+		respondError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Set default namespace if not provided
 	if ephApp.Namespace == "" {
 		ephApp.Namespace = "default"
@@ -128,7 +148,7 @@ func (h *EphemeralAppHandler) Create(w http.ResponseWriter, r *http.Request) {
 		ephApp.Annotations = make(map[string]string)
 	}
 	if user != nil {
-		ephApp.Annotations["ephemeral.argo.io/created-by"] = user.Username
+		ephApp.Annotations[ephemeralv1alpha1.CreatedByAnnotation] = user.Username
 	}
 
 	if err := h.client.Create(ctx, &ephApp); err != nil {
@@ -180,14 +200,24 @@ func (h *EphemeralAppHandler) Update(w http.ResponseWriter, r *http.Request, nam
 		return
 	}
 
-	// Apply simple patches (only spec.expirationDate for now)
+	// Apply simple patches (only spec.expirationDate or spec.ttl for now)
 	if spec, ok := patch["spec"].(map[string]interface{}); ok {
 		if expDate, ok := spec["expirationDate"].(string); ok {
 			var parsedTime metav1.Time
 			if err := parsedTime.UnmarshalText([]byte(expDate)); err == nil {
-				ephApp.Spec.ExpirationDate = parsedTime
+				ephApp.Spec.ExpirationDate = &parsedTime
+				ephApp.Spec.TTL = nil
 			}
 		}
+		if ttl, ok := spec["ttl"].(string); ok {
+			duration, err := time.ParseDuration(ttl)
+			if err != nil {
+				respondError(w, "Invalid ttl: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			ephApp.Spec.TTL = &metav1.Duration{Duration: duration}
+			ephApp.Spec.ExpirationDate = nil
+		}
 	}
 
 	// Update resource
@@ -199,6 +229,50 @@ func (h *EphemeralAppHandler) Update(w http.ResponseWriter, r *http.Request, nam
 	respondJSON(w, http.StatusOK, ephApp)
 }
 
+// Wake handles POST /api/v1/ephemeral-apps/{name}/wake
+// A hibernating environment is woken up and stays awake until its next scheduled sleep
+func (h *EphemeralAppHandler) Wake(w http.ResponseWriter, r *http.Request, name string) {
+	ctx := r.Context()
+
+	// Parse namespace from query param
+	namespace := r.URL.Query().Get("namespace")
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{}
+	key := client.ObjectKey{
+		Namespace: namespace,
+		Name:      name,
+	}
+
+	if err := h.client.Get(ctx, key, ephApp); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			respondError(w, "Not found", http.StatusNotFound)
+			return
+		}
+		respondError(w, "Failed to get ephemeral app", http.StatusInternalServerError)
+		return
+	}
+
+	if ephApp.Spec.Hibernation == nil {
+		respondError(w, "Ephemeral app has no hibernation schedule", http.StatusConflict)
+		return
+	}
+
+	if ephApp.Annotations == nil {
+		ephApp.Annotations = make(map[string]string)
+	}
+	ephApp.Annotations[ephemeralv1alpha1.WakeRequestedAnnotation] = time.Now().UTC().Format(time.RFC3339)
+
+	if err := h.client.Update(ctx, ephApp); err != nil {
+		respondError(w, "Failed to wake ephemeral app", http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusAccepted, ephApp)
+}
+
 // Delete handles DELETE /api/v1/ephemeral-apps/{name}
 func (h *EphemeralAppHandler) Delete(w http.ResponseWriter, r *http.Request, name string) {
 	ctx := r.Context()
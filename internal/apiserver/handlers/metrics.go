@@ -33,11 +33,11 @@ type MetricsResponse struct {
 
 // EnvironmentSummary is a simplified view of an environment
 type EnvironmentSummary struct {
-	Name           string      `json:"name"`
-	Namespace      string      `json:"namespace"`
-	Phase          string      `json:"phase"`
-	ExpirationDate metav1.Time `json:"expirationDate"`
-	CreatedAt      metav1.Time `json:"createdAt"`
+	Name           string       `json:"name"`
+	Namespace      string       `json:"namespace"`
+	Phase          string       `json:"phase"`
+	ExpirationDate *metav1.Time `json:"expirationDate,omitempty"`
+	CreatedAt      metav1.Time  `json:"createdAt"`
 }
 
 // GetMetrics handles GET /api/v1/metrics
@@ -82,11 +82,16 @@ func (h *MetricsHandler) GetMetrics(w http.ResponseWriter, r *http.Request) {
 
 		// Add to recent list (limit to 10)
 		if len(metrics.RecentEnvironments) < 10 {
+			// The recorded expiry includes the policy limits
+			expiresAt := env.Status.ExpiresAt
+			if expiresAt == nil {
+				expiresAt = env.ExpiresAt()
+			}
 			metrics.RecentEnvironments = append(metrics.RecentEnvironments, EnvironmentSummary{
 				Name:           env.Name,
 				Namespace:      env.Status.Namespace,
 				Phase:          phase,
-				ExpirationDate: env.Spec.ExpirationDate,
+				ExpirationDate: expiresAt,
 				CreatedAt:      env.CreationTimestamp,
 			})
 		}
@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	ephemeralv1alpha1 "github.com/jbarea/argo-ephemeral-operator/api/v1alpha1"
+	"github.com/jbarea/argo-ephemeral-operator/internal/apiserver/auth"
+)
+
+// TemplateHandler handles EphemeralApplicationTemplate CRUD operations
+type TemplateHandler struct {
+	client client.Client
+}
+
+// NewTemplateHandler creates a new handler
+func NewTemplateHandler(client client.Client) *TemplateHandler {
+	return &TemplateHandler{client: client}
+}
+
+// List handles GET /api/v1/templates
+func (h *TemplateHandler) List(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := r.Context()
+
+	opts := []client.ListOption{}
+	if namespace := r.URL.Query().Get("namespace"); namespace != "" {
+		opts = append(opts, client.InNamespace(namespace))
+	}
+
+	list := &ephemeralv1alpha1.EphemeralApplicationTemplateList{}
+	if err := h.client.List(ctx, list, opts...); err != nil {
+		respondError(w, "Failed to list templates", http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, list)
+}
+
+// HandleSingle routes single resource operations
+func (h *TemplateHandler) HandleSingle(w http.ResponseWriter, r *http.Request) {
+	// Extract name from path: /api/v1/templates/{name}
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/templates/")
+	parts := strings.Split(path, "/")
+	if len(parts) == 0 || parts[0] == "" {
+		respondError(w, "Name required", http.StatusBadRequest)
+		return
+	}
+
+	name := parts[0]
+
+	switch r.Method {
+	case http.MethodGet:
+		h.Get(w, r, name)
+	case http.MethodPut:
+		h.Update(w, r, name)
+	case http.MethodDelete:
+		h.Delete(w, r, name)
+	default:
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// Get retrieves a single EphemeralApplicationTemplate
+func (h *TemplateHandler) Get(w http.ResponseWriter, r *http.Request, name string) {
+	template, ok := h.fetch(w, r, name)
+	if !ok {
+		return
+	}
+
+	respondJSON(w, http.StatusOK, template)
+}
+
+// Create handles POST /api/v1/templates/create
+func (h *TemplateHandler) Create(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := r.Context()
+	user, _ := auth.GetUserFromContext(ctx)
+
+	// Read request body
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		respondError(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	var template ephemeralv1alpha1.EphemeralApplicationTemplate
+	if err := json.Unmarshal(body, &template); err != nil {
+		respondError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Set default namespace if not provided
+	if template.Namespace == "" {
+		template.Namespace = "default"
+	}
+
+	// Add annotation with creator info
+	if template.Annotations == nil {
+		template.Annotations = make(map[string]string)
+	}
+	if user != nil {
+		template.Annotations[ephemeralv1alpha1.CreatedByAnnotation] = user.Username
+	}
+
+	if err := h.client.Create(ctx, &template); err != nil {
+		respondError(w, "Failed to create template: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, template)
+}
+
+// Update handles PUT /api/v1/templates/{name}, replacing the template spec
+func (h *TemplateHandler) Update(w http.ResponseWriter, r *http.Request, name string) {
+	template, ok := h.fetch(w, r, name)
+	if !ok {
+		return
+	}
+
+	// Read request body
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		respondError(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	var update ephemeralv1alpha1.EphemeralApplicationTemplate
+	if err := json.Unmarshal(body, &update); err != nil {
+		respondError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	template.Spec = update.Spec
+
+	if err := h.client.Update(r.Context(), template); err != nil {
+		respondError(w, "Failed to update template: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, template)
+}
+
+// Delete handles DELETE /api/v1/templates/{name}
+func (h *TemplateHandler) Delete(w http.ResponseWriter, r *http.Request, name string) {
+	template, ok := h.fetch(w, r, name)
+	if !ok {
+		return
+	}
+
+	if err := h.client.Delete(r.Context(), template); err != nil {
+		respondError(w, "Failed to delete template", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// fetch gets the template named in the request, writing the error response if it fails
+func (h *TemplateHandler) fetch(w http.ResponseWriter, r *http.Request, name string) (*ephemeralv1alpha1.EphemeralApplicationTemplate, bool) {
+	// Parse namespace from query param, default to "default"
+	namespace := r.URL.Query().Get("namespace")
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	template := &ephemeralv1alpha1.EphemeralApplicationTemplate{}
+	key := client.ObjectKey{
+		Namespace: namespace,
+		Name:      name,
+	}
+
+	if err := h.client.Get(r.Context(), key, template); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			respondError(w, "Not found", http.StatusNotFound)
+			return nil, false
+		}
+		respondError(w, "Failed to get template", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	return template, true
+}
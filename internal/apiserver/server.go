@@ -35,11 +35,15 @@ func (s *Server) Routes() http.Handler {
 	// Create handlers
 	ephemeralHandler := handlers.NewEphemeralAppHandler(s.client)
 	metricsHandler := handlers.NewMetricsHandler(s.client)
+	templateHandler := handlers.NewTemplateHandler(s.client)
 
 	// API routes (require authentication)
 	mux.HandleFunc("/api/v1/ephemeral-apps", ephemeralHandler.List)
 	mux.HandleFunc("/api/v1/ephemeral-apps/", ephemeralHandler.HandleSingle)
 	mux.HandleFunc("/api/v1/ephemeral-apps/create", ephemeralHandler.Create)
+	mux.HandleFunc("/api/v1/templates", templateHandler.List)
+	mux.HandleFunc("/api/v1/templates/", templateHandler.HandleSingle)
+	mux.HandleFunc("/api/v1/templates/create", templateHandler.Create)
 	mux.HandleFunc("/api/v1/metrics", metricsHandler.GetMetrics)
 
 	// Apply middleware chain (order matters!)
@@ -0,0 +1,213 @@
+package argocd
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenSource provides the bearer token sent to the ArgoCD API server
+type tokenSource interface {
+	// Token returns the current token
+	Token() (string, error)
+	// Refresh returns a new token after the server rejected the current one
+	Refresh() (string, error)
+}
+
+// newTokenSource returns the token source for the configured credentials, an API token
+// takes precedence over a token file, which takes precedence over username and password
+func newTokenSource(opts Options) (tokenSource, error) {
+	switch {
+	case opts.Token != "":
+		return staticTokenSource(opts.Token), nil
+	case opts.TokenFile != "":
+		return &fileTokenSource{path: opts.TokenFile}, nil
+	case opts.Username != "" && opts.Password != "":
+		tlsConfig, err := newTLSConfig(opts)
+		if err != nil {
+			return nil, err
+		}
+		loginAddr := opts.LoginAddr
+		if loginAddr == "" {
+			loginAddr = opts.ServerAddr
+		}
+		return &sessionTokenSource{
+			serverURL: "https://" + loginAddr,
+			username:  opts.Username,
+			password:  opts.Password,
+			httpClient: &http.Client{
+				Transport: &http.Transport{TLSClientConfig: tlsConfig},
+				Timeout:   30 * time.Second,
+			},
+		}, nil
+	default:
+		return nil, errors.New("an API token, a token file or a username and password are required")
+	}
+}
+
+// staticTokenSource is a long-lived API token
+type staticTokenSource string
+
+func (s staticTokenSource) Token() (string, error) {
+	return string(s), nil
+}
+
+func (s staticTokenSource) Refresh() (string, error) {
+	return "", errors.New("the ArgoCD API token was rejected")
+}
+
+// fileTokenSource reads the token from a file, typically a mounted Secret, and reads it
+// again whenever the file changes so rotated tokens are picked up without a restart
+type fileTokenSource struct {
+	path string
+
+	mu      sync.Mutex
+	token   string
+	modTime time.Time
+}
+
+func (s *fileTokenSource) Token() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read ArgoCD token file: %w", err)
+	}
+	if s.token != "" && info.ModTime().Equal(s.modTime) {
+		return s.token, nil
+	}
+	return s.read(info.ModTime())
+}
+
+func (s *fileTokenSource) Refresh() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read ArgoCD token file: %w", err)
+	}
+	return s.read(info.ModTime())
+}
+
+// read loads the token from the file, the caller must hold the lock
+func (s *fileTokenSource) read(modTime time.Time) (string, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read ArgoCD token file: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("ArgoCD token file %s is empty", s.path)
+	}
+	s.token = token
+	s.modTime = modTime
+	return token, nil
+}
+
+// sessionTokenSource logs in with a username and password to get session tokens
+type sessionTokenSource struct {
+	serverURL  string
+	username   string
+	password   string
+	httpClient *http.Client
+
+	mu    sync.Mutex
+	token string
+}
+
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type LoginResponse struct {
+	Token string `json:"token"`
+}
+
+func (s *sessionTokenSource) Token() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" {
+		return s.token, nil
+	}
+	return s.login()
+}
+
+func (s *sessionTokenSource) Refresh() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.login()
+}
+
+// login requests a new session token, the caller must hold the lock
+func (s *sessionTokenSource) login() (string, error) {
+	reqBody, err := json.Marshal(LoginRequest{
+		Username: s.username,
+		Password: s.password,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := s.httpClient.Post(s.serverURL+"/api/v1/session", "application/json", bytes.NewBuffer(reqBody))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("failed to login: %s", string(bodyBytes))
+	}
+
+	var loginResponse LoginResponse
+	if err := json.NewDecoder(resp.Body).Decode(&loginResponse); err != nil {
+		return "", err
+	}
+
+	s.token = loginResponse.Token
+	return s.token, nil
+}
+
+// newTLSConfig builds the TLS configuration used to talk to the ArgoCD API server
+func newTLSConfig(opts Options) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: opts.Insecure,
+	}
+
+	if opts.CAFile != "" {
+		caData, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ArgoCD CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caData) {
+			return nil, fmt.Errorf("no certificates found in ArgoCD CA bundle %s", opts.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if opts.ClientCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.ClientCertFile, opts.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load ArgoCD client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
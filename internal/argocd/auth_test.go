@@ -0,0 +1,182 @@
+package argocd
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewTokenSource(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Options
+		wantType string
+		wantErr  bool
+	}{
+		{
+			name:     "token takes precedence",
+			opts:     Options{Token: "token", TokenFile: "/tmp/token", Username: "admin", Password: "secret"},
+			wantType: "static",
+		},
+		{
+			name:     "token file",
+			opts:     Options{TokenFile: "/tmp/token", Username: "admin", Password: "secret"},
+			wantType: "file",
+		},
+		{
+			name:     "username and password",
+			opts:     Options{ServerAddr: "argocd:443", Username: "admin", Password: "secret"},
+			wantType: "session",
+		},
+		{
+			name:    "no credentials",
+			opts:    Options{Username: "admin"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, err := newTokenSource(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newTokenSource() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			var gotType string
+			switch source.(type) {
+			case staticTokenSource:
+				gotType = "static"
+			case *fileTokenSource:
+				gotType = "file"
+			case *sessionTokenSource:
+				gotType = "session"
+			}
+			if gotType != tt.wantType {
+				t.Errorf("expected %s token source, got %T", tt.wantType, source)
+			}
+		})
+	}
+}
+
+func TestNewTokenSource_LoginAddr(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantURL string
+	}{
+		{
+			name:    "login without the gRPC port",
+			opts:    Options{ServerAddr: "argocd:8080", LoginAddr: "argocd", Username: "admin", Password: "secret"},
+			wantURL: "https://argocd",
+		},
+		{
+			name:    "login at the server address",
+			opts:    Options{ServerAddr: "argocd:8443", Username: "admin", Password: "secret"},
+			wantURL: "https://argocd:8443",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, err := newTokenSource(tt.opts)
+			if err != nil {
+				t.Fatalf("newTokenSource() failed: %v", err)
+			}
+			session, ok := source.(*sessionTokenSource)
+			if !ok {
+				t.Fatalf("expected session token source, got %T", source)
+			}
+			if session.serverURL != tt.wantURL {
+				t.Errorf("expected login at %s, got %s", tt.wantURL, session.serverURL)
+			}
+		})
+	}
+}
+
+func TestFileTokenSource_Rotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("first\n"), 0o600); err != nil {
+		t.Fatalf("failed to write token: %v", err)
+	}
+
+	source := &fileTokenSource{path: path}
+	token, err := source.Token()
+	if err != nil {
+		t.Fatalf("Token() failed: %v", err)
+	}
+	if token != "first" {
+		t.Errorf("expected token 'first', got '%s'", token)
+	}
+
+	if err := os.WriteFile(path, []byte("second"), 0o600); err != nil {
+		t.Fatalf("failed to write token: %v", err)
+	}
+	// Make sure the modification time moves even on coarse grained file systems
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatalf("failed to touch token: %v", err)
+	}
+
+	token, err = source.Token()
+	if err != nil {
+		t.Fatalf("Token() failed: %v", err)
+	}
+	if token != "second" {
+		t.Errorf("expected rotated token 'second', got '%s'", token)
+	}
+}
+
+func TestSessionTokenSource_VerifiesServer(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req LoginRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(LoginResponse{Token: "session-token"})
+	}))
+	defer server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	caData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, caData, 0o600); err != nil {
+		t.Fatalf("failed to write CA bundle: %v", err)
+	}
+
+	opts := Options{
+		ServerAddr: strings.TrimPrefix(server.URL, "https://"),
+		Username:   "admin",
+		Password:   "secret",
+	}
+
+	// The server certificate is not trusted without the CA bundle
+	source, err := newTokenSource(opts)
+	if err != nil {
+		t.Fatalf("newTokenSource() failed: %v", err)
+	}
+	if _, err := source.Token(); err == nil {
+		t.Error("expected login to fail against an untrusted server")
+	}
+
+	opts.CAFile = caFile
+	source, err = newTokenSource(opts)
+	if err != nil {
+		t.Fatalf("newTokenSource() failed: %v", err)
+	}
+	token, err := source.Token()
+	if err != nil {
+		t.Fatalf("Token() failed: %v", err)
+	}
+	if token != "session-token" {
+		t.Errorf("expected token 'session-token', got '%s'", token)
+	}
+}
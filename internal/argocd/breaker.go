@@ -0,0 +1,134 @@
+package argocd
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// breakerThreshold is the number of consecutive failed calls that opens the circuit
+	breakerThreshold = 5
+	// breakerCooldown is how long calls fail fast before a single call is let through
+	breakerCooldown = 30 * time.Second
+)
+
+// Availability is implemented by clients that stop calling ArgoCD while it is unavailable
+type Availability interface {
+	// Unavailable returns an error wrapping ErrUnavailable while calls are suspended
+	Unavailable() error
+	// Notify registers a channel that receives a value whenever the availability changes,
+	// sends never block so the channel should be buffered
+	Notify(ch chan<- struct{})
+}
+
+// circuitBreaker suspends calls to ArgoCD after consecutive transient failures, once the
+// cool down has elapsed a single probe call is let through and closes the circuit on success
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu          sync.Mutex
+	failures    int
+	open        bool
+	probing     bool
+	openedAt    time.Time
+	lastErr     error
+	subscribers []chan<- struct{}
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// allow returns an error when the call must not be made
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.open {
+		return nil
+	}
+	if !b.probing && b.now().Sub(b.openedAt) >= b.cooldown {
+		b.probing = true
+		return nil
+	}
+	return b.unavailableLocked()
+}
+
+// record updates the circuit with the result of a call, only transient errors count as
+// failures, any other answer proves ArgoCD is reachable
+func (b *circuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil || !isTransient(err) {
+		b.failures = 0
+		if b.open {
+			b.open = false
+			b.probing = false
+			b.notifyLocked()
+		}
+		return
+	}
+
+	b.failures++
+	b.lastErr = err
+	switch {
+	case b.open:
+		// The probe failed, wait for another cool down
+		b.probing = false
+		b.openedAt = b.now()
+	case b.failures >= b.threshold:
+		b.open = true
+		b.openedAt = b.now()
+		b.notifyLocked()
+	}
+}
+
+// Unavailable returns an error wrapping ErrUnavailable while the circuit is open
+func (b *circuitBreaker) Unavailable() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.open {
+		return nil
+	}
+	return b.unavailableLocked()
+}
+
+// Notify registers a channel notified when the circuit opens or closes
+func (b *circuitBreaker) Notify(ch chan<- struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subscribers = append(b.subscribers, ch)
+}
+
+// unavailableLocked returns the error of an open circuit, the caller must hold the lock
+func (b *circuitBreaker) unavailableLocked() error {
+	return fmt.Errorf("%w: calls suspended after %d consecutive failures, last error: %v", ErrUnavailable, b.failures, b.lastErr)
+}
+
+// notifyLocked signals the subscribers without blocking, the caller must hold the lock
+func (b *circuitBreaker) notifyLocked() {
+	breakerOpen.Set(boolToFloat(b.open))
+	for _, ch := range b.subscribers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
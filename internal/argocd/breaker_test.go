@@ -0,0 +1,61 @@
+package argocd
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	breaker := newCircuitBreaker(2, time.Minute)
+	breaker.now = func() time.Time { return now }
+	notifications := make(chan struct{}, 1)
+	breaker.Notify(notifications)
+
+	unavailable := fmt.Errorf("%w: connection refused", ErrUnavailable)
+
+	// Errors other than unavailability prove ArgoCD is reachable
+	breaker.record(unavailable)
+	breaker.record(fmt.Errorf("%w: missing", ErrNotFound))
+	breaker.record(unavailable)
+	if err := breaker.allow(); err != nil {
+		t.Fatalf("expected the circuit to stay closed, got %v", err)
+	}
+
+	breaker.record(unavailable)
+	if err := breaker.allow(); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("expected the circuit to open, got %v", err)
+	}
+	if breaker.Unavailable() == nil {
+		t.Error("expected Unavailable() to report the open circuit")
+	}
+	select {
+	case <-notifications:
+	default:
+		t.Error("expected a notification when the circuit opens")
+	}
+
+	// A single probe is let through after the cool down
+	now = now.Add(time.Minute)
+	if err := breaker.allow(); err != nil {
+		t.Fatalf("expected a probe after the cool down, got %v", err)
+	}
+	if err := breaker.allow(); err == nil {
+		t.Fatal("expected a single probe at a time")
+	}
+
+	breaker.record(nil)
+	if err := breaker.allow(); err != nil {
+		t.Fatalf("expected the circuit to close after a successful probe, got %v", err)
+	}
+	if breaker.Unavailable() != nil {
+		t.Error("expected Unavailable() to be nil once closed")
+	}
+	select {
+	case <-notifications:
+	default:
+		t.Error("expected a notification when the circuit closes")
+	}
+}
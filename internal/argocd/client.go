@@ -1,20 +1,18 @@
 package argocd
 
 import (
-	"bytes"
 	"context"
-	"crypto/tls"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"log"
-	"net/http"
-	"strings"
 	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
 
 	"github.com/argoproj/argo-cd/v2/pkg/apiclient"
 	"github.com/argoproj/argo-cd/v2/pkg/apiclient/application"
+	"github.com/argoproj/argo-cd/v2/pkg/apiclient/project"
 	"github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
 )
 
@@ -23,77 +21,81 @@ type Client interface {
 	DoRequestWithRetry(requestFunc func(appClient application.ApplicationServiceClient) error) error
 	// CreateApplication creates an ArgoCD Application
 	CreateApplication(ctx context.Context, newApp *application.ApplicationCreateRequest) (*v1alpha1.Application, error)
+	// UpdateApplication updates an existing ArgoCD Application
+	UpdateApplication(ctx context.Context, updateReq *application.ApplicationUpdateRequest) (*v1alpha1.Application, error)
 	// GetApplication retrieves an ArgoCD Application
 	GetApplication(ctx context.Context, query application.ApplicationQuery) (*v1alpha1.Application, error)
 	// GetApplication retrieves an ArgoCD Application
 	GetApplications(ctx context.Context) (*v1alpha1.ApplicationList, error)
 	// // DeleteApplication deletes an ArgoCD Application
 	DeleteApplication(ctx context.Context, name string, namespace string) error
+	// CreateProject creates an ArgoCD AppProject
+	CreateProject(ctx context.Context, appProject *v1alpha1.AppProject) (*v1alpha1.AppProject, error)
+	// UpdateProject updates an existing ArgoCD AppProject
+	UpdateProject(ctx context.Context, appProject *v1alpha1.AppProject) (*v1alpha1.AppProject, error)
+	// GetProject retrieves an ArgoCD AppProject
+	GetProject(ctx context.Context, name string) (*v1alpha1.AppProject, error)
+	// DeleteProject deletes an ArgoCD AppProject
+	DeleteProject(ctx context.Context, name string) error
+}
+
+// Options configures the connection to the ArgoCD API server
+type Options struct {
+	// ServerAddr is the host and port of the ArgoCD API server
+	ServerAddr string
+	// LoginAddr is where session tokens are requested, https://<LoginAddr>/api/v1/session.
+	// ServerAddr is used when it is empty
+	LoginAddr string
+	// Token is a long-lived ArgoCD API token
+	Token string
+	// TokenFile holds an ArgoCD API token, it is read again when the file changes
+	TokenFile string
+	// Username and Password request session tokens when no API token is configured
+	Username string
+	Password string
+	// Insecure skips the verification of the server certificate
+	Insecure bool
+	// CAFile is the CA bundle used to verify the server certificate
+	CAFile string
+	// ClientCertFile and ClientKeyFile are presented to the server for mutual TLS
+	ClientCertFile string
+	ClientKeyFile  string
 }
 
 // clientImpl implements the Client interface
 type clientImpl struct {
-	argocdClient apiclient.Client
-	tokenLock    sync.Mutex
-	username     string
-	password     string
-	serverAddr   string
-	insecure     bool
-}
+	opts    Options
+	tokens  tokenSource
+	breaker *circuitBreaker
+	// backoff spaces the retries of calls failing with a transient error
+	backoff wait.Backoff
 
-type LoginRequest struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+	clientLock   sync.Mutex
+	argocdClient apiclient.Client
+	// services are kept open between calls, gRPC reconnects on its own
+	services *serviceClients
+	// token is the token argocdClient was created with
+	token string
 }
 
-type LoginResponse struct {
-	Token string `json:"token"`
+// serviceClients are the ArgoCD API services used by the client and their connections
+type serviceClients struct {
+	app     application.ApplicationServiceClient
+	project project.ProjectServiceClient
+	closers []io.Closer
 }
 
-func getAuthToken(serverAddr string, username string, password string) (string, error) {
-	loginURL := serverAddr + "/api/v1/session"
-	loginRequest := LoginRequest{
-		Username: username,
-		Password: password,
-	}
-
-	reqBody, err := json.Marshal(loginRequest)
-	if err != nil {
-		return "", err
-	}
-
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-	}
-	client := &http.Client{Transport: tr}
-
-	resp, err := client.Post(loginURL, "application/json", bytes.NewBuffer(reqBody))
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("failed to login: %s", string(bodyBytes))
-	}
-
-	var loginResponse LoginResponse
-	if err := json.NewDecoder(resp.Body).Decode(&loginResponse); err != nil {
-		return "", err
-	}
-
-	return loginResponse.Token, nil
-}
-
-func createArgcdClient(serverAddr string, authToken string, insecure bool) (apiclient.Client, error) {
+func createArgcdClient(opts Options, authToken string) (apiclient.Client, error) {
 
 	clientOpts := &apiclient.ClientOptions{
-		ServerAddr: serverAddr,
-		AuthToken:  authToken,
-		Insecure:   insecure,
-		GRPCWeb:    false,
-		PlainText:  false,
+		ServerAddr:        opts.ServerAddr,
+		AuthToken:         authToken,
+		Insecure:          opts.Insecure,
+		CertFile:          opts.CAFile,
+		ClientCertFile:    opts.ClientCertFile,
+		ClientCertKeyFile: opts.ClientKeyFile,
+		GRPCWeb:           false,
+		PlainText:         false,
 	}
 
 	client, err := apiclient.NewClient(clientOpts)
@@ -103,67 +105,171 @@ func createArgcdClient(serverAddr string, authToken string, insecure bool) (apic
 	return client, nil
 }
 
-func NewClient(serverAddr string, port string, username string, password string, insecure bool) (Client, error) {
+func NewClient(opts Options) (Client, error) {
 
-	authToken, err := getAuthToken("https://"+serverAddr, username, password)
+	tokens, err := newTokenSource(opts)
 	if err != nil {
-		log.Fatalf("Client can't get Authorization Token from ArgoCD with the crendetials provided")
 		return nil, err
 	}
 
-	client, err := createArgcdClient(serverAddr+":"+port, authToken, insecure)
+	authToken, err := tokens.Token()
 	if err != nil {
-		log.Fatalf("Failed to create ArgoCD client: %v", err)
-		return nil, err
+		return nil, fmt.Errorf("%w: can't get an authorization token with the credentials provided: %w", ErrUnauthorized, err)
+	}
+
+	client, err := createArgcdClient(opts, authToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ArgoCD client: %w", err)
 	}
 
 	return &clientImpl{
+		opts:    opts,
+		tokens:  tokens,
+		breaker: newCircuitBreaker(breakerThreshold, breakerCooldown),
+		backoff: wait.Backoff{
+			Duration: 200 * time.Millisecond,
+			Factor:   2,
+			Jitter:   0.5,
+			Steps:    4,
+			Cap:      5 * time.Second,
+		},
 		argocdClient: client,
-		username:     username,
-		password:     password,
-		serverAddr:   serverAddr,
-		insecure:     insecure,
+		token:        authToken,
 	}, nil
 }
 
-func (c *clientImpl) DoRequestWithRetry(requestFunc func(appClient application.ApplicationServiceClient) error) error {
-
-	conn, appClient, err := c.argocdClient.NewApplicationClient()
+// serviceClients returns the service clients for the current token, the connections are
+// reopened when the token was rotated or, with refresh, after the server rejected it
+func (c *clientImpl) serviceClients(refresh bool) (*serviceClients, error) {
+	c.clientLock.Lock()
+	defer c.clientLock.Unlock()
+
+	var authToken string
+	var err error
+	if refresh {
+		authToken, err = c.tokens.Refresh()
+	} else {
+		authToken, err = c.tokens.Token()
+	}
 	if err != nil {
-		return fmt.Errorf("failed to open a connection to ArgoCD server: %v", err)
+		return nil, fmt.Errorf("%w: error renewing auth token: %w", ErrUnauthorized, err)
 	}
-	defer conn.Close()
 
-	err = requestFunc(appClient)
+	if authToken != c.token {
+		argocdClient, err := createArgcdClient(c.opts, authToken)
+		if err != nil {
+			return nil, fmt.Errorf("error recreating ArgoCD client with new token: %w", err)
+		}
+		c.closeConnection()
+		c.argocdClient = argocdClient
+		c.token = authToken
+	}
 
-	if err != nil && isUnauthorized(err) {
+	if c.services == nil {
+		appConn, appClient, err := c.argocdClient.NewApplicationClient()
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to open a connection to ArgoCD server: %w", ErrUnavailable, err)
+		}
+		projectConn, projectClient, err := c.argocdClient.NewProjectClient()
+		if err != nil {
+			_ = appConn.Close()
+			return nil, fmt.Errorf("%w: failed to open a connection to ArgoCD server: %w", ErrUnavailable, err)
+		}
+		c.services = &serviceClients{
+			app:     appClient,
+			project: projectClient,
+			closers: []io.Closer{appConn, projectConn},
+		}
+	}
+	return c.services, nil
+}
 
-		c.tokenLock.Lock()
-		defer c.tokenLock.Unlock()
+// closeConnection closes the open connections, the caller must hold the lock
+func (c *clientImpl) closeConnection() {
+	if c.services != nil {
+		for _, closer := range c.services.closers {
+			_ = closer.Close()
+		}
+	}
+	c.services = nil
+}
 
-		authToken, err := getAuthToken(c.serverAddr, c.username, c.password)
-		if err != nil {
-			return fmt.Errorf("error renewing auth token: %v", err)
+// DoRequestWithRetry runs the request, errors of the ArgoCD API are wrapped with the sentinel
+// error of their status code
+func (c *clientImpl) DoRequestWithRetry(requestFunc func(appClient application.ApplicationServiceClient) error) error {
+	return c.do(context.Background(), "Request", true, func(services *serviceClients) error {
+		return requestFunc(services.app)
+	})
+}
+
+// do runs the request through the circuit breaker, it retries once with a new token when the
+// token is rejected and, for idempotent requests, with exponential backoff while ArgoCD is
+// unavailable. Creates are not retried since they may have been applied before the failure,
+// the next reconcile finds the created object instead
+func (c *clientImpl) do(ctx context.Context, method string, idempotent bool, requestFunc func(services *serviceClients) error) error {
+	backoff := c.backoff
+	refresh, refreshed := false, false
+	for {
+		if err := c.breaker.allow(); err != nil {
+			requestFailures.WithLabelValues(method, errorReason(err)).Inc()
+			return err
 		}
 
-		c.argocdClient, err = createArgcdClient(c.serverAddr, authToken, c.insecure)
-		if err != nil {
-			return fmt.Errorf("error recreating ArgoCD client with new token: %v", err)
+		start := time.Now()
+		services, err := c.serviceClients(refresh)
+		if err == nil {
+			err = fromGRPCError(requestFunc(services))
 		}
+		refresh = false
+		requestDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+		c.breaker.record(err)
+		if err == nil {
+			return nil
+		}
+		requestFailures.WithLabelValues(method, errorReason(err)).Inc()
+
+		switch {
+		case errors.Is(err, ErrUnauthorized) && !refreshed:
+			refresh, refreshed = true, true
+		case idempotent && isTransient(err) && backoff.Steps > 0:
+			if sleep(ctx, backoff.Step()) != nil {
+				return err
+			}
+		default:
+			return err
+		}
+	}
+}
 
-		err = requestFunc(appClient)
+// sleep waits for the delay, it returns early with the error of the context when it is done
+func sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
 	}
+}
 
-	return err
+// Unavailable returns an error wrapping ErrUnavailable while the circuit breaker is open
+func (c *clientImpl) Unavailable() error {
+	return c.breaker.Unavailable()
+}
+
+// Notify registers a channel notified when the circuit breaker opens or closes
+func (c *clientImpl) Notify(ch chan<- struct{}) {
+	c.breaker.Notify(ch)
 }
 
 func (c *clientImpl) GetApplications(ctx context.Context) (*v1alpha1.ApplicationList, error) {
 
 	var apps *v1alpha1.ApplicationList
-	err := c.DoRequestWithRetry(func(appClient application.ApplicationServiceClient) error {
-		appList, err := appClient.List(ctx, &application.ApplicationQuery{})
+	err := c.do(ctx, "List", true, func(services *serviceClients) error {
+		appList, err := services.app.List(ctx, &application.ApplicationQuery{})
 		if err != nil {
-			return fmt.Errorf("failed to get all applications: %v", err)
+			return fmt.Errorf("failed to get all applications: %w", err)
 		}
 		apps = appList
 		return err
@@ -180,10 +286,10 @@ func (c *clientImpl) CreateApplication(ctx context.Context, newApp *application.
 	}
 
 	var applicationCreated *v1alpha1.Application
-	err := c.DoRequestWithRetry(func(appClient application.ApplicationServiceClient) error {
-		app, err := appClient.Create(ctx, newApp)
+	err := c.do(ctx, "Create", false, func(services *serviceClients) error {
+		app, err := services.app.Create(ctx, newApp)
 		if err != nil {
-			return fmt.Errorf("application can not be created: %v", err)
+			return fmt.Errorf("application can not be created: %w", err)
 		}
 		applicationCreated = app
 		return err
@@ -192,6 +298,25 @@ func (c *clientImpl) CreateApplication(ctx context.Context, newApp *application.
 	return applicationCreated, err
 }
 
+func (c *clientImpl) UpdateApplication(ctx context.Context, updateReq *application.ApplicationUpdateRequest) (*v1alpha1.Application, error) {
+
+	if updateReq == nil || updateReq.Application == nil {
+		return nil, errors.New("application must be defined")
+	}
+
+	var applicationUpdated *v1alpha1.Application
+	err := c.do(ctx, "Update", true, func(services *serviceClients) error {
+		app, err := services.app.Update(ctx, updateReq)
+		if err != nil {
+			return fmt.Errorf("application can not be updated: %w", err)
+		}
+		applicationUpdated = app
+		return err
+	})
+
+	return applicationUpdated, err
+}
+
 func (c *clientImpl) GetApplication(ctx context.Context, query application.ApplicationQuery) (*v1alpha1.Application, error) {
 
 	if isEmpty(query) {
@@ -199,10 +324,10 @@ func (c *clientImpl) GetApplication(ctx context.Context, query application.Appli
 	}
 
 	var foundApp *v1alpha1.Application
-	err := c.DoRequestWithRetry(func(appClient application.ApplicationServiceClient) error {
-		app, err := appClient.Get(ctx, &query)
+	err := c.do(ctx, "Get", true, func(services *serviceClients) error {
+		app, err := services.app.Get(ctx, &query)
 		if err != nil {
-			log.Fatalf("Application not found with query: %v", query)
+			return fmt.Errorf("application can not be retrieved: %w", err)
 		}
 		foundApp = app
 		return nil
@@ -217,8 +342,8 @@ func (c *clientImpl) DeleteApplication(ctx context.Context, name string, namespa
 		return errors.New("application name and namespace must be defined")
 	}
 
-	return c.DoRequestWithRetry(func(appClient application.ApplicationServiceClient) error {
-		_, err := appClient.Delete(ctx, &application.ApplicationDeleteRequest{
+	return c.do(ctx, "Delete", true, func(services *serviceClients) error {
+		_, err := services.app.Delete(ctx, &application.ApplicationDeleteRequest{
 			Name: &name,
 			// FIXME: AppNamespace is not working as expected, we should investigate why.
 			//AppNamespace: &namespace,
@@ -227,6 +352,75 @@ func (c *clientImpl) DeleteApplication(ctx context.Context, name string, namespa
 	})
 }
 
+func (c *clientImpl) CreateProject(ctx context.Context, appProject *v1alpha1.AppProject) (*v1alpha1.AppProject, error) {
+
+	if appProject == nil {
+		return nil, errors.New("project must be defined")
+	}
+
+	var projectCreated *v1alpha1.AppProject
+	err := c.do(ctx, "CreateProject", false, func(services *serviceClients) error {
+		created, err := services.project.Create(ctx, &project.ProjectCreateRequest{Project: appProject})
+		if err != nil {
+			return fmt.Errorf("project can not be created: %w", err)
+		}
+		projectCreated = created
+		return nil
+	})
+
+	return projectCreated, err
+}
+
+func (c *clientImpl) UpdateProject(ctx context.Context, appProject *v1alpha1.AppProject) (*v1alpha1.AppProject, error) {
+
+	if appProject == nil {
+		return nil, errors.New("project must be defined")
+	}
+
+	var projectUpdated *v1alpha1.AppProject
+	err := c.do(ctx, "UpdateProject", true, func(services *serviceClients) error {
+		updated, err := services.project.Update(ctx, &project.ProjectUpdateRequest{Project: appProject})
+		if err != nil {
+			return fmt.Errorf("project can not be updated: %w", err)
+		}
+		projectUpdated = updated
+		return nil
+	})
+
+	return projectUpdated, err
+}
+
+func (c *clientImpl) GetProject(ctx context.Context, name string) (*v1alpha1.AppProject, error) {
+
+	if name == "" {
+		return nil, errors.New("project name parameter must be defined")
+	}
+
+	var foundProject *v1alpha1.AppProject
+	err := c.do(ctx, "GetProject", true, func(services *serviceClients) error {
+		found, err := services.project.Get(ctx, &project.ProjectQuery{Name: name})
+		if err != nil {
+			return fmt.Errorf("project can not be retrieved: %w", err)
+		}
+		foundProject = found
+		return nil
+	})
+
+	return foundProject, err
+}
+
+func (c *clientImpl) DeleteProject(ctx context.Context, name string) error {
+
+	if name == "" {
+		return errors.New("project name must be defined")
+	}
+
+	return c.do(ctx, "DeleteProject", true, func(services *serviceClients) error {
+		_, err := services.project.Delete(ctx, &project.ProjectQuery{Name: name})
+		return err
+	})
+}
+
 func isEmpty(query application.ApplicationQuery) bool {
 	fields := []interface{}{
 		query.Name,
@@ -248,10 +442,3 @@ func isEmpty(query application.ApplicationQuery) bool {
 
 	return true
 }
-
-func isUnauthorized(err error) bool {
-	if err != nil && strings.Contains(err.Error(), "401") {
-		return true
-	}
-	return false
-}
@@ -0,0 +1,184 @@
+package argocd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/argoproj/argo-cd/v2/pkg/apiclient"
+	"github.com/argoproj/argo-cd/v2/pkg/apiclient/application"
+	"github.com/argoproj/argo-cd/v2/pkg/apiclient/project"
+	"github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+)
+
+// fakeArgoCDClient opens connections to a fakeApplicationClient and an unused project client
+type fakeArgoCDClient struct {
+	apiclient.Client
+	appClient   *fakeApplicationClient
+	connections int
+}
+
+func (f *fakeArgoCDClient) NewApplicationClient() (io.Closer, application.ApplicationServiceClient, error) {
+	f.connections++
+	return io.NopCloser(nil), f.appClient, nil
+}
+
+func (f *fakeArgoCDClient) NewProjectClient() (io.Closer, project.ProjectServiceClient, error) {
+	return io.NopCloser(nil), nil, nil
+}
+
+// fakeApplicationClient answers Get with the queued errors, then succeeds
+type fakeApplicationClient struct {
+	application.ApplicationServiceClient
+	errs  []error
+	calls int
+}
+
+func (f *fakeApplicationClient) Get(ctx context.Context, query *application.ApplicationQuery, opts ...grpc.CallOption) (*v1alpha1.Application, error) {
+	f.calls++
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return nil, err
+	}
+	app := &v1alpha1.Application{}
+	app.Name = *query.Name
+	return app, nil
+}
+
+func (f *fakeApplicationClient) Create(ctx context.Context, req *application.ApplicationCreateRequest, opts ...grpc.CallOption) (*v1alpha1.Application, error) {
+	f.calls++
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return nil, err
+	}
+	return req.Application, nil
+}
+
+func newTestClient(appClient *fakeApplicationClient) (*clientImpl, *fakeArgoCDClient) {
+	argocdClient := &fakeArgoCDClient{appClient: appClient}
+	return &clientImpl{
+		tokens:       staticTokenSource("token"),
+		breaker:      newCircuitBreaker(breakerThreshold, breakerCooldown),
+		backoff:      wait.Backoff{Duration: time.Millisecond, Factor: 2, Steps: 3},
+		argocdClient: argocdClient,
+		token:        "token",
+	}, argocdClient
+}
+
+func TestClient_RetriesTransientErrors(t *testing.T) {
+	appClient := &fakeApplicationClient{errs: []error{
+		status.Error(codes.Unavailable, "connection refused"),
+		status.Error(codes.Unavailable, "connection refused"),
+	}}
+	c, argocdClient := newTestClient(appClient)
+
+	name := "preview"
+	for i := 0; i < 2; i++ {
+		app, err := c.GetApplication(context.Background(), application.ApplicationQuery{Name: &name})
+		if err != nil {
+			t.Fatalf("GetApplication failed: %v", err)
+		}
+		if app.Name != "preview" {
+			t.Errorf("expected application 'preview', got '%s'", app.Name)
+		}
+	}
+	if appClient.calls != 4 {
+		t.Errorf("expected 4 calls, got %d", appClient.calls)
+	}
+	if argocdClient.connections != 1 {
+		t.Errorf("expected the connection to be reused, got %d connections", argocdClient.connections)
+	}
+}
+
+func TestClient_DoesNotRetryNotFound(t *testing.T) {
+	appClient := &fakeApplicationClient{errs: []error{status.Error(codes.NotFound, "missing")}}
+	c, _ := newTestClient(appClient)
+
+	name := "preview"
+	_, err := c.GetApplication(context.Background(), application.ApplicationQuery{Name: &name})
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if appClient.calls != 1 {
+		t.Errorf("expected a single call, got %d", appClient.calls)
+	}
+}
+
+func TestClient_OpensCircuitWhenUnavailable(t *testing.T) {
+	appClient := &fakeApplicationClient{}
+	for i := 0; i < breakerThreshold; i++ {
+		appClient.errs = append(appClient.errs, status.Error(codes.Unavailable, "connection refused"))
+	}
+	c, _ := newTestClient(appClient)
+	c.backoff.Steps = 0
+
+	name := "preview"
+	for i := 0; i < breakerThreshold; i++ {
+		if _, err := c.GetApplication(context.Background(), application.ApplicationQuery{Name: &name}); !IsUnavailable(err) {
+			t.Fatalf("expected ErrUnavailable, got %v", err)
+		}
+	}
+	if c.Unavailable() == nil {
+		t.Fatal("expected the circuit breaker to be open")
+	}
+
+	// Calls fail fast without reaching ArgoCD
+	if _, err := c.GetApplication(context.Background(), application.ApplicationQuery{Name: &name}); !IsUnavailable(err) {
+		t.Fatalf("expected ErrUnavailable, got %v", err)
+	}
+	if appClient.calls != breakerThreshold {
+		t.Errorf("expected %d calls, got %d", breakerThreshold, appClient.calls)
+	}
+}
+
+func TestClient_DoesNotRetryCreate(t *testing.T) {
+	appClient := &fakeApplicationClient{errs: []error{status.Error(codes.Unavailable, "connection reset")}}
+	c, _ := newTestClient(appClient)
+
+	app := &application.ApplicationCreateRequest{Application: &v1alpha1.Application{}}
+	if _, err := c.CreateApplication(context.Background(), app); !IsUnavailable(err) {
+		t.Fatalf("expected ErrUnavailable, got %v", err)
+	}
+	if appClient.calls != 1 {
+		t.Errorf("expected a single call, got %d", appClient.calls)
+	}
+}
+
+func TestClient_StopsRetryingWhenContextIsDone(t *testing.T) {
+	appClient := &fakeApplicationClient{errs: []error{
+		status.Error(codes.Unavailable, "connection refused"),
+		status.Error(codes.Unavailable, "connection refused"),
+	}}
+	c, _ := newTestClient(appClient)
+	c.backoff.Duration = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	name := "preview"
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.GetApplication(ctx, application.ApplicationQuery{Name: &name})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !IsUnavailable(err) {
+			t.Fatalf("expected ErrUnavailable, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the retries to stop with the context")
+	}
+	if appClient.calls != 1 {
+		t.Errorf("expected a single call, got %d", appClient.calls)
+	}
+}
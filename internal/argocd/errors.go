@@ -0,0 +1,104 @@
+package argocd
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Errors returned by the Client, they wrap the original error and can be checked with errors.Is
+var (
+	// ErrNotFound is returned when the application or the project does not exist
+	ErrNotFound = errors.New("ArgoCD application not found")
+	// ErrUnauthorized is returned when the credentials are missing or rejected
+	ErrUnauthorized = errors.New("unauthorized by ArgoCD")
+	// ErrPermissionDenied is returned when the credentials lack a permission, ArgoCD also
+	// answers with it for applications that do not exist to avoid disclosing their names
+	ErrPermissionDenied = errors.New("permission denied by ArgoCD")
+	// ErrUnavailable is returned when ArgoCD can not be reached, the request may be retried
+	ErrUnavailable = errors.New("ArgoCD is unavailable")
+)
+
+// IsNotFound reports whether err is ErrNotFound
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// IsPermissionDenied reports whether err is ErrPermissionDenied
+func IsPermissionDenied(err error) bool {
+	return errors.Is(err, ErrPermissionDenied)
+}
+
+// IsUnavailable reports whether err is ErrUnavailable
+func IsUnavailable(err error) bool {
+	return errors.Is(err, ErrUnavailable)
+}
+
+// fromGRPCError wraps an error of the ArgoCD API with the sentinel error of its status code
+func fromGRPCError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var sentinel error
+	switch status.Code(err) {
+	case codes.NotFound:
+		sentinel = ErrNotFound
+	case codes.Unauthenticated:
+		sentinel = ErrUnauthorized
+	case codes.PermissionDenied:
+		sentinel = ErrPermissionDenied
+	case codes.Unavailable, codes.DeadlineExceeded:
+		sentinel = ErrUnavailable
+	default:
+		return err
+	}
+	return fmt.Errorf("%w: %w", sentinel, err)
+}
+
+// FromKubernetesError wraps an error of the Kubernetes API on an Application resource with
+// the matching sentinel error
+func FromKubernetesError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var sentinel error
+	switch {
+	case apierrors.IsNotFound(err):
+		sentinel = ErrNotFound
+	case apierrors.IsUnauthorized(err):
+		sentinel = ErrUnauthorized
+	case apierrors.IsForbidden(err):
+		sentinel = ErrPermissionDenied
+	case apierrors.IsServiceUnavailable(err), apierrors.IsTimeout(err), apierrors.IsServerTimeout(err):
+		sentinel = ErrUnavailable
+	default:
+		return err
+	}
+	return fmt.Errorf("%w: %w", sentinel, err)
+}
+
+// isTransient reports whether a failed call may succeed when retried
+func isTransient(err error) bool {
+	return errors.Is(err, ErrUnavailable)
+}
+
+// errorReason returns the metrics label of a failed call
+func errorReason(err error) string {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return "not_found"
+	case errors.Is(err, ErrUnauthorized):
+		return "unauthorized"
+	case errors.Is(err, ErrPermissionDenied):
+		return "permission_denied"
+	case errors.Is(err, ErrUnavailable):
+		return "unavailable"
+	default:
+		return "other"
+	}
+}
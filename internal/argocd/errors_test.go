@@ -0,0 +1,62 @@
+package argocd
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestFromGRPCError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{name: "not found", err: status.Error(codes.NotFound, "app not found"), want: ErrNotFound},
+		{name: "unauthenticated", err: status.Error(codes.Unauthenticated, "invalid session"), want: ErrUnauthorized},
+		{name: "permission denied", err: status.Error(codes.PermissionDenied, "permission denied"), want: ErrPermissionDenied},
+		{name: "unavailable", err: status.Error(codes.Unavailable, "connection refused"), want: ErrUnavailable},
+		{
+			name: "wrapped status",
+			err:  fmt.Errorf("application can not be retrieved: %w", status.Error(codes.NotFound, "app not found")),
+			want: ErrNotFound,
+		},
+		{name: "other code", err: status.Error(codes.InvalidArgument, "invalid spec")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fromGRPCError(tt.err)
+			if !errors.Is(got, tt.err) {
+				t.Errorf("expected the original error to be wrapped, got %v", got)
+			}
+			for _, sentinel := range []error{ErrNotFound, ErrUnauthorized, ErrPermissionDenied, ErrUnavailable} {
+				if errors.Is(got, sentinel) != (sentinel == tt.want) {
+					t.Errorf("unexpected errors.Is(%v, %v) = %v", got, sentinel, !(sentinel == tt.want))
+				}
+			}
+		})
+	}
+}
+
+func TestFromKubernetesError(t *testing.T) {
+	resource := schema.GroupResource{Group: "argoproj.io", Resource: "applications"}
+
+	if err := FromKubernetesError(apierrors.NewNotFound(resource, "preview")); !IsNotFound(err) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if err := FromKubernetesError(apierrors.NewForbidden(resource, "preview", errors.New("denied"))); !IsPermissionDenied(err) {
+		t.Errorf("expected ErrPermissionDenied, got %v", err)
+	}
+	if err := FromKubernetesError(apierrors.NewServiceUnavailable("down")); !IsUnavailable(err) {
+		t.Errorf("expected ErrUnavailable, got %v", err)
+	}
+	if err := FromKubernetesError(nil); err != nil {
+		t.Errorf("expected nil, got %v", err)
+	}
+}
@@ -26,10 +26,14 @@ func BuildIgnoreDifferences(ephApp *ephemeralv1alpha1.EphemeralApplication) []v1
 
 	// Ignore injected configmaps
 	for _, cm := range ephApp.Spec.ConfigMaps {
+		name := cm.Name
+		if cm.TargetName != "" {
+			name = cm.TargetName
+		}
 		ignoreDiffs = append(ignoreDiffs, v1alpha1.ResourceIgnoreDifferences{
 			Group: "",
 			Kind:  "ConfigMap",
-			Name:  cm.Name,
+			Name:  name,
 			// Ignore data to prevent ArgoCD from reverting it
 			JSONPointers: []string{"/data", "/binaryData"},
 		})
@@ -0,0 +1,37 @@
+package argocd
+
+import (
+	"testing"
+
+	ephemeralv1alpha1 "github.com/jbarea/argo-ephemeral-operator/api/v1alpha1"
+)
+
+func TestBuildIgnoreDifferences_TargetNames(t *testing.T) {
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{
+		Spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+			Secrets: []ephemeralv1alpha1.SecretReference{
+				{Name: "db-credentials", SourceNamespace: "shared", TargetName: "db"},
+			},
+			ConfigMaps: []ephemeralv1alpha1.ConfigMapReference{
+				{Name: "app-config", SourceNamespace: "shared", TargetName: "settings"},
+				{Name: "flags", Data: map[string]string{"NEW_UI": "true"}},
+			},
+		},
+	}
+
+	got := BuildIgnoreDifferences(ephApp)
+
+	expected := []struct{ kind, name string }{
+		{"Secret", "db"},
+		{"ConfigMap", "settings"},
+		{"ConfigMap", "flags"},
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d ignore differences, got %+v", len(expected), got)
+	}
+	for i, want := range expected {
+		if got[i].Kind != want.kind || got[i].Name != want.name {
+			t.Errorf("expected %s %s, got %s %s", want.kind, want.name, got[i].Kind, got[i].Name)
+		}
+	}
+}
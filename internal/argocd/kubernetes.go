@@ -0,0 +1,153 @@
+package argocd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/argoproj/argo-cd/v2/pkg/apiclient/application"
+	"github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+)
+
+// kubernetesClient implements the Client interface by managing the Application custom
+// resources directly, it needs no ArgoCD API credentials, only RBAC on the resources
+type kubernetesClient struct {
+	client    client.Client
+	namespace string
+}
+
+// NewKubernetesClient returns a Client that manages Application resources through the
+// Kubernetes API in the given namespace, the scheme of c must include the ArgoCD types
+func NewKubernetesClient(c client.Client, namespace string) Client {
+	return &kubernetesClient{
+		client:    c,
+		namespace: namespace,
+	}
+}
+
+// DoRequestWithRetry is only supported by the ArgoCD API client
+func (c *kubernetesClient) DoRequestWithRetry(requestFunc func(appClient application.ApplicationServiceClient) error) error {
+	return errors.New("ArgoCD API requests are not supported by the kubernetes backend")
+}
+
+func (c *kubernetesClient) CreateApplication(ctx context.Context, newApp *application.ApplicationCreateRequest) (*v1alpha1.Application, error) {
+	if newApp == nil || newApp.Application == nil {
+		return nil, errors.New("application must be defined")
+	}
+
+	app := newApp.Application.DeepCopy()
+	if app.Namespace == "" {
+		app.Namespace = c.namespace
+	}
+	// Like a cascading delete through the ArgoCD API, the managed resources are pruned
+	// before the Application resource goes away
+	controllerutil.AddFinalizer(app, v1alpha1.ResourcesFinalizerName)
+
+	if err := c.client.Create(ctx, app); err != nil {
+		return nil, fmt.Errorf("application can not be created: %w", FromKubernetesError(err))
+	}
+	return app, nil
+}
+
+func (c *kubernetesClient) UpdateApplication(ctx context.Context, updateReq *application.ApplicationUpdateRequest) (*v1alpha1.Application, error) {
+	if updateReq == nil || updateReq.Application == nil {
+		return nil, errors.New("application must be defined")
+	}
+
+	app := updateReq.Application.DeepCopy()
+	if app.Namespace == "" {
+		app.Namespace = c.namespace
+	}
+	if err := c.client.Update(ctx, app); err != nil {
+		return nil, fmt.Errorf("application can not be updated: %w", FromKubernetesError(err))
+	}
+	return app, nil
+}
+
+func (c *kubernetesClient) GetApplication(ctx context.Context, query application.ApplicationQuery) (*v1alpha1.Application, error) {
+	if query.Name == nil || *query.Name == "" {
+		return nil, errors.New("application name parameter must be defined")
+	}
+
+	namespace := c.namespace
+	if query.AppNamespace != nil && *query.AppNamespace != "" {
+		namespace = *query.AppNamespace
+	}
+
+	app := &v1alpha1.Application{}
+	if err := c.client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: *query.Name}, app); err != nil {
+		return nil, FromKubernetesError(err)
+	}
+	return app, nil
+}
+
+func (c *kubernetesClient) GetApplications(ctx context.Context) (*v1alpha1.ApplicationList, error) {
+	apps := &v1alpha1.ApplicationList{}
+	if err := c.client.List(ctx, apps, client.InNamespace(c.namespace)); err != nil {
+		return nil, fmt.Errorf("failed to get all applications: %w", FromKubernetesError(err))
+	}
+	return apps, nil
+}
+
+func (c *kubernetesClient) DeleteApplication(ctx context.Context, name string, namespace string) error {
+	if name == "" || namespace == "" {
+		return errors.New("application name and namespace must be defined")
+	}
+
+	app := &v1alpha1.Application{}
+	app.Name = name
+	app.Namespace = namespace
+	return FromKubernetesError(c.client.Delete(ctx, app))
+}
+
+func (c *kubernetesClient) CreateProject(ctx context.Context, appProject *v1alpha1.AppProject) (*v1alpha1.AppProject, error) {
+	if appProject == nil {
+		return nil, errors.New("project must be defined")
+	}
+
+	created := appProject.DeepCopy()
+	created.Namespace = c.namespace
+	if err := c.client.Create(ctx, created); err != nil {
+		return nil, fmt.Errorf("project can not be created: %w", FromKubernetesError(err))
+	}
+	return created, nil
+}
+
+func (c *kubernetesClient) UpdateProject(ctx context.Context, appProject *v1alpha1.AppProject) (*v1alpha1.AppProject, error) {
+	if appProject == nil {
+		return nil, errors.New("project must be defined")
+	}
+
+	updated := appProject.DeepCopy()
+	updated.Namespace = c.namespace
+	if err := c.client.Update(ctx, updated); err != nil {
+		return nil, fmt.Errorf("project can not be updated: %w", FromKubernetesError(err))
+	}
+	return updated, nil
+}
+
+func (c *kubernetesClient) GetProject(ctx context.Context, name string) (*v1alpha1.AppProject, error) {
+	if name == "" {
+		return nil, errors.New("project name parameter must be defined")
+	}
+
+	appProject := &v1alpha1.AppProject{}
+	if err := c.client.Get(ctx, client.ObjectKey{Namespace: c.namespace, Name: name}, appProject); err != nil {
+		return nil, FromKubernetesError(err)
+	}
+	return appProject, nil
+}
+
+func (c *kubernetesClient) DeleteProject(ctx context.Context, name string) error {
+	if name == "" {
+		return errors.New("project name must be defined")
+	}
+
+	appProject := &v1alpha1.AppProject{}
+	appProject.Name = name
+	appProject.Namespace = c.namespace
+	return FromKubernetesError(c.client.Delete(ctx, appProject))
+}
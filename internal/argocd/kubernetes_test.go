@@ -0,0 +1,111 @@
+package argocd
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/argoproj/argo-cd/v2/pkg/apiclient/application"
+	"github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+)
+
+func TestKubernetesClient_Lifecycle(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to register ArgoCD types: %v", err)
+	}
+	c := NewKubernetesClient(fake.NewClientBuilder().WithScheme(scheme).Build(), "argocd")
+	ctx := context.Background()
+
+	_, err := c.CreateApplication(ctx, &application.ApplicationCreateRequest{
+		Application: &v1alpha1.Application{
+			ObjectMeta: metav1.ObjectMeta{Name: "preview"},
+			Spec:       v1alpha1.ApplicationSpec{Project: "default"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateApplication failed: %v", err)
+	}
+
+	name := "preview"
+	app, err := c.GetApplication(ctx, application.ApplicationQuery{Name: &name})
+	if err != nil {
+		t.Fatalf("GetApplication failed: %v", err)
+	}
+	if app.Namespace != "argocd" {
+		t.Errorf("expected application in namespace 'argocd', got '%s'", app.Namespace)
+	}
+	if !controllerutil.ContainsFinalizer(app, v1alpha1.ResourcesFinalizerName) {
+		t.Errorf("expected finalizer %s, got %v", v1alpha1.ResourcesFinalizerName, app.Finalizers)
+	}
+
+	app.Spec.Project = "ephemeral"
+	if _, err := c.UpdateApplication(ctx, &application.ApplicationUpdateRequest{Application: app}); err != nil {
+		t.Fatalf("UpdateApplication failed: %v", err)
+	}
+
+	apps, err := c.GetApplications(ctx)
+	if err != nil {
+		t.Fatalf("GetApplications failed: %v", err)
+	}
+	if len(apps.Items) != 1 || apps.Items[0].Spec.Project != "ephemeral" {
+		t.Fatalf("expected the updated application to be listed, got %+v", apps.Items)
+	}
+
+	if err := c.DeleteApplication(ctx, "preview", "argocd"); err != nil {
+		t.Fatalf("DeleteApplication failed: %v", err)
+	}
+	if err := c.DeleteApplication(ctx, "missing", "argocd"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected a not found error deleting a missing application, got %v", err)
+	}
+}
+
+func TestKubernetesClient_GetApplicationRequiresName(t *testing.T) {
+	c := NewKubernetesClient(fake.NewClientBuilder().Build(), "argocd")
+
+	if _, err := c.GetApplication(context.Background(), application.ApplicationQuery{}); err == nil {
+		t.Error("expected an error without an application name")
+	}
+}
+
+func TestKubernetesClient_ProjectLifecycle(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to register ArgoCD types: %v", err)
+	}
+	c := NewKubernetesClient(fake.NewClientBuilder().WithScheme(scheme).Build(), "argocd")
+	ctx := context.Background()
+
+	_, err := c.CreateProject(ctx, &v1alpha1.AppProject{
+		ObjectMeta: metav1.ObjectMeta{Name: "ephemeral-preview"},
+		Spec:       v1alpha1.AppProjectSpec{SourceRepos: []string{"https://github.com/example/app.git"}},
+	})
+	if err != nil {
+		t.Fatalf("CreateProject failed: %v", err)
+	}
+
+	appProject, err := c.GetProject(ctx, "ephemeral-preview")
+	if err != nil {
+		t.Fatalf("GetProject failed: %v", err)
+	}
+	if appProject.Namespace != "argocd" {
+		t.Errorf("expected project in namespace 'argocd', got '%s'", appProject.Namespace)
+	}
+
+	appProject.Spec.SourceRepos = append(appProject.Spec.SourceRepos, "https://github.com/example/bases.git")
+	if _, err := c.UpdateProject(ctx, appProject); err != nil {
+		t.Fatalf("UpdateProject failed: %v", err)
+	}
+
+	if err := c.DeleteProject(ctx, "ephemeral-preview"); err != nil {
+		t.Fatalf("DeleteProject failed: %v", err)
+	}
+	if _, err := c.GetProject(ctx, "ephemeral-preview"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected a not found error getting a deleted project, got %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+package argocd
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	requestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "ephemeral_argocd_request_duration_seconds",
+			Help:    "Duration of the calls to the ArgoCD API, including failed ones",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"method"},
+	)
+
+	requestFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ephemeral_argocd_request_failures_total",
+			Help: "Number of failed calls to the ArgoCD API by reason",
+		},
+		[]string{"method", "reason"},
+	)
+
+	breakerOpen = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "ephemeral_argocd_circuit_breaker_open",
+			Help: "Whether calls to the ArgoCD API are suspended by the circuit breaker",
+		},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(requestDuration, requestFailures, breakerOpen)
+}
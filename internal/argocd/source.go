@@ -0,0 +1,108 @@
+package argocd
+
+import (
+	v1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	ephemeralv1alpha1 "github.com/jbarea/argo-ephemeral-operator/api/v1alpha1"
+)
+
+// DefaultTargetRevision is used when neither the EphemeralApplication nor its template set a revision
+const DefaultTargetRevision = "HEAD"
+
+// BuildApplicationSource translates an EphemeralApplication component into an ArgoCD application source
+func BuildApplicationSource(component ephemeralv1alpha1.Component) *v1alpha1.ApplicationSource {
+	source := &v1alpha1.ApplicationSource{
+		RepoURL:        component.RepoURL,
+		Path:           component.Path,
+		TargetRevision: component.TargetRevision,
+	}
+
+	if source.TargetRevision == "" {
+		source.TargetRevision = DefaultTargetRevision
+	}
+
+	if component.Helm != nil {
+		source.Chart = component.Helm.Chart
+		source.Helm = buildHelmSource(component.Helm)
+	}
+
+	if component.Kustomize != nil {
+		source.Kustomize = buildKustomizeSource(component.Kustomize)
+	}
+
+	return source
+}
+
+// buildHelmSource translates the Helm options into ArgoCD Helm options
+func buildHelmSource(helm *ephemeralv1alpha1.HelmSource) *v1alpha1.ApplicationSourceHelm {
+	helmSource := &v1alpha1.ApplicationSourceHelm{
+		ReleaseName: helm.ReleaseName,
+	}
+
+	if len(helm.ValueFiles) > 0 {
+		helmSource.ValueFiles = append([]string{}, helm.ValueFiles...)
+	}
+
+	if helm.Values != nil && len(helm.Values.Raw) > 0 {
+		helmSource.ValuesObject = helm.Values.DeepCopy()
+	}
+
+	for _, param := range helm.Parameters {
+		helmSource.Parameters = append(helmSource.Parameters, v1alpha1.HelmParameter{
+			Name:        param.Name,
+			Value:       param.Value,
+			ForceString: param.ForceString,
+		})
+	}
+
+	return helmSource
+}
+
+// buildKustomizeSource translates the Kustomize overrides into ArgoCD Kustomize options
+func buildKustomizeSource(kustomize *ephemeralv1alpha1.KustomizeSource) *v1alpha1.ApplicationSourceKustomize {
+	kustomizeSource := &v1alpha1.ApplicationSourceKustomize{
+		NamePrefix: kustomize.NamePrefix,
+		NameSuffix: kustomize.NameSuffix,
+	}
+
+	for _, image := range kustomize.Images {
+		kustomizeSource.Images = append(kustomizeSource.Images, v1alpha1.KustomizeImage(image))
+	}
+
+	if len(kustomize.CommonLabels) > 0 {
+		kustomizeSource.CommonLabels = make(map[string]string, len(kustomize.CommonLabels))
+		for key, value := range kustomize.CommonLabels {
+			kustomizeSource.CommonLabels[key] = value
+		}
+	}
+
+	if len(kustomize.CommonAnnotations) > 0 {
+		kustomizeSource.CommonAnnotations = make(map[string]string, len(kustomize.CommonAnnotations))
+		for key, value := range kustomize.CommonAnnotations {
+			kustomizeSource.CommonAnnotations[key] = value
+		}
+	}
+
+	for _, patch := range kustomize.Patches {
+		kustomizePatch := v1alpha1.KustomizePatch{
+			Patch: patch.Patch,
+		}
+		if patch.Target != nil {
+			kustomizePatch.Target = &v1alpha1.KustomizeSelector{
+				KustomizeResId: v1alpha1.KustomizeResId{
+					KustomizeGvk: v1alpha1.KustomizeGvk{
+						Group:   patch.Target.Group,
+						Version: patch.Target.Version,
+						Kind:    patch.Target.Kind,
+					},
+					Name:      patch.Target.Name,
+					Namespace: patch.Target.Namespace,
+				},
+				LabelSelector:      patch.Target.LabelSelector,
+				AnnotationSelector: patch.Target.AnnotationSelector,
+			}
+		}
+		kustomizeSource.Patches = append(kustomizeSource.Patches, kustomizePatch)
+	}
+
+	return kustomizeSource
+}
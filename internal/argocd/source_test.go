@@ -0,0 +1,142 @@
+package argocd
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	ephemeralv1alpha1 "github.com/jbarea/argo-ephemeral-operator/api/v1alpha1"
+)
+
+func TestBuildApplicationSource_Git(t *testing.T) {
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{
+		Spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+			RepoURL:        "https://github.com/example/app.git",
+			Path:           "manifests",
+			TargetRevision: "main",
+		},
+	}
+
+	got := BuildApplicationSource(ephApp.Spec.ResolvedComponents()[0])
+
+	if got.RepoURL != "https://github.com/example/app.git" || got.Path != "manifests" || got.TargetRevision != "main" {
+		t.Errorf("unexpected source: %+v", got)
+	}
+	if got.Helm != nil {
+		t.Errorf("expected no helm options, got %+v", got.Helm)
+	}
+}
+
+func TestBuildApplicationSource_HelmChart(t *testing.T) {
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{
+		Spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+			RepoURL:        "https://charts.example.com",
+			TargetRevision: "1.2.3",
+			Helm: &ephemeralv1alpha1.HelmSource{
+				Chart:       "backend",
+				ReleaseName: "preview",
+				ValueFiles:  []string{"values-preview.yaml"},
+				Values:      &runtime.RawExtension{Raw: []byte(`{"replicaCount":1}`)},
+				Parameters: []ephemeralv1alpha1.HelmParameter{
+					{Name: "image.tag", Value: "pr-42", ForceString: true},
+				},
+			},
+		},
+	}
+
+	got := BuildApplicationSource(ephApp.Spec.ResolvedComponents()[0])
+
+	if got.Chart != "backend" {
+		t.Errorf("expected chart 'backend', got '%s'", got.Chart)
+	}
+	if got.Path != "" {
+		t.Errorf("expected empty path, got '%s'", got.Path)
+	}
+	if got.Helm == nil {
+		t.Fatal("expected helm options to be set")
+	}
+	if got.Helm.ReleaseName != "preview" {
+		t.Errorf("expected release name 'preview', got '%s'", got.Helm.ReleaseName)
+	}
+	if len(got.Helm.ValueFiles) != 1 || got.Helm.ValueFiles[0] != "values-preview.yaml" {
+		t.Errorf("unexpected value files: %v", got.Helm.ValueFiles)
+	}
+	if got.Helm.ValuesObject == nil || string(got.Helm.ValuesObject.Raw) != `{"replicaCount":1}` {
+		t.Errorf("unexpected values object: %v", got.Helm.ValuesObject)
+	}
+	if len(got.Helm.Parameters) != 1 || got.Helm.Parameters[0].Name != "image.tag" ||
+		got.Helm.Parameters[0].Value != "pr-42" || !got.Helm.Parameters[0].ForceString {
+		t.Errorf("unexpected parameters: %+v", got.Helm.Parameters)
+	}
+}
+
+func TestBuildApplicationSource_Kustomize(t *testing.T) {
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{
+		Spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+			RepoURL: "https://github.com/example/app.git",
+			Path:    "overlays/preview",
+			Kustomize: &ephemeralv1alpha1.KustomizeSource{
+				Images:       []string{"app=registry.example.com/app:pr-42"},
+				NamePrefix:   "pr-42-",
+				CommonLabels: map[string]string{"preview": "pr-42"},
+				Patches: []ephemeralv1alpha1.KustomizePatch{
+					{
+						Patch: `[{"op":"replace","path":"/spec/replicas","value":1}]`,
+						Target: &ephemeralv1alpha1.KustomizePatchTarget{
+							Kind: "Deployment",
+							Name: "app",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	got := BuildApplicationSource(ephApp.Spec.ResolvedComponents()[0])
+
+	if got.Kustomize == nil {
+		t.Fatal("expected kustomize options to be set")
+	}
+	if len(got.Kustomize.Images) != 1 || string(got.Kustomize.Images[0]) != "app=registry.example.com/app:pr-42" {
+		t.Errorf("unexpected images: %v", got.Kustomize.Images)
+	}
+	if got.Kustomize.NamePrefix != "pr-42-" {
+		t.Errorf("expected name prefix 'pr-42-', got '%s'", got.Kustomize.NamePrefix)
+	}
+	if got.Kustomize.CommonLabels["preview"] != "pr-42" {
+		t.Errorf("unexpected common labels: %v", got.Kustomize.CommonLabels)
+	}
+	if len(got.Kustomize.Patches) != 1 {
+		t.Fatalf("expected 1 patch, got %d", len(got.Kustomize.Patches))
+	}
+	target := got.Kustomize.Patches[0].Target
+	if target == nil || target.Kind != "Deployment" || target.Name != "app" {
+		t.Errorf("unexpected patch target: %+v", target)
+	}
+}
+
+func TestBuildApplicationSource_Component(t *testing.T) {
+	component := ephemeralv1alpha1.Component{
+		Name:           "database",
+		RepoURL:        "https://charts.example.com",
+		TargetRevision: "15.0.0",
+		Helm:           &ephemeralv1alpha1.HelmSource{Chart: "postgresql"},
+	}
+
+	got := BuildApplicationSource(component)
+
+	if got.RepoURL != "https://charts.example.com" || got.Chart != "postgresql" || got.TargetRevision != "15.0.0" {
+		t.Errorf("unexpected source: %+v", got)
+	}
+}
+
+func TestBuildApplicationSource_DefaultTargetRevision(t *testing.T) {
+	got := BuildApplicationSource(ephemeralv1alpha1.Component{
+		RepoURL: "https://github.com/example/app.git",
+		Path:    "manifests",
+	})
+
+	if got.TargetRevision != "HEAD" {
+		t.Errorf("expected targetRevision 'HEAD', got '%s'", got.TargetRevision)
+	}
+}
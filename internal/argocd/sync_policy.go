@@ -0,0 +1,70 @@
+package argocd
+
+import (
+	v1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	ephemeralv1alpha1 "github.com/jbarea/argo-ephemeral-operator/api/v1alpha1"
+)
+
+// respectIgnoreDifferencesOption is always set so ArgoCD does not revert injected secrets and configmaps
+const respectIgnoreDifferencesOption = "RespectIgnoreDifferences=true"
+
+// BuildSyncPolicy translates the EphemeralApplication sync policy into an ArgoCD sync policy
+// When no sync policy is specified, the application is auto-synced with prune and self-heal enabled
+func BuildSyncPolicy(ephApp *ephemeralv1alpha1.EphemeralApplication) *v1alpha1.SyncPolicy {
+	policy := ephApp.Spec.SyncPolicy
+
+	if policy == nil {
+		return &v1alpha1.SyncPolicy{
+			Automated: &v1alpha1.SyncPolicyAutomated{
+				Prune:    true,
+				SelfHeal: true,
+			},
+			SyncOptions: v1alpha1.SyncOptions{respectIgnoreDifferencesOption},
+		}
+	}
+
+	syncPolicy := &v1alpha1.SyncPolicy{
+		SyncOptions: buildSyncOptions(policy.SyncOptions),
+	}
+
+	// A sync policy without an automated block means manual sync
+	if policy.Automated != nil {
+		syncPolicy.Automated = &v1alpha1.SyncPolicyAutomated{
+			Prune:      policy.Automated.Prune || policy.Prune,
+			SelfHeal:   policy.Automated.SelfHeal || policy.SelfHeal,
+			AllowEmpty: policy.Automated.AllowEmpty,
+		}
+	}
+
+	if policy.Retry != nil {
+		syncPolicy.Retry = &v1alpha1.RetryStrategy{
+			Limit: policy.Retry.Limit,
+		}
+		if policy.Retry.Backoff != nil {
+			syncPolicy.Retry.Backoff = &v1alpha1.Backoff{
+				Duration:    policy.Retry.Backoff.Duration,
+				MaxDuration: policy.Retry.Backoff.MaxDuration,
+			}
+			if policy.Retry.Backoff.Factor != nil {
+				factor := *policy.Retry.Backoff.Factor
+				syncPolicy.Retry.Backoff.Factor = &factor
+			}
+		}
+	}
+
+	return syncPolicy
+}
+
+// buildSyncOptions merges the user sync options with the options required by the operator
+func buildSyncOptions(options []string) v1alpha1.SyncOptions {
+	syncOptions := v1alpha1.SyncOptions{respectIgnoreDifferencesOption}
+
+	for _, option := range options {
+		if option == "" || option == respectIgnoreDifferencesOption {
+			continue
+		}
+		syncOptions = append(syncOptions, option)
+	}
+
+	return syncOptions
+}
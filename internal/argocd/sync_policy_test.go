@@ -0,0 +1,89 @@
+package argocd
+
+import (
+	"testing"
+
+	ephemeralv1alpha1 "github.com/jbarea/argo-ephemeral-operator/api/v1alpha1"
+)
+
+func TestBuildSyncPolicy_Default(t *testing.T) {
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{}
+
+	got := BuildSyncPolicy(ephApp)
+
+	if got.Automated == nil {
+		t.Fatal("expected automated sync policy by default")
+	}
+	if !got.Automated.Prune || !got.Automated.SelfHeal {
+		t.Errorf("expected prune and selfHeal enabled, got %+v", got.Automated)
+	}
+	if len(got.SyncOptions) != 1 || got.SyncOptions[0] != respectIgnoreDifferencesOption {
+		t.Errorf("expected only %q sync option, got %v", respectIgnoreDifferencesOption, got.SyncOptions)
+	}
+}
+
+func TestBuildSyncPolicy_Manual(t *testing.T) {
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{
+		Spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+			SyncPolicy: &ephemeralv1alpha1.SyncPolicy{
+				SyncOptions: []string{"CreateNamespace=true", respectIgnoreDifferencesOption},
+			},
+		},
+	}
+
+	got := BuildSyncPolicy(ephApp)
+
+	if got.Automated != nil {
+		t.Errorf("expected manual sync, got automated %+v", got.Automated)
+	}
+
+	expected := []string{respectIgnoreDifferencesOption, "CreateNamespace=true"}
+	if len(got.SyncOptions) != len(expected) {
+		t.Fatalf("expected sync options %v, got %v", expected, got.SyncOptions)
+	}
+	for i, option := range expected {
+		if got.SyncOptions[i] != option {
+			t.Errorf("sync option %d: expected '%s', got '%s'", i, option, got.SyncOptions[i])
+		}
+	}
+}
+
+func TestBuildSyncPolicy_AutomatedWithRetry(t *testing.T) {
+	factor := int64(2)
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{
+		Spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+			SyncPolicy: &ephemeralv1alpha1.SyncPolicy{
+				Automated: &ephemeralv1alpha1.AutomatedSyncPolicy{
+					Prune: true,
+				},
+				SelfHeal: true,
+				Retry: &ephemeralv1alpha1.RetryPolicy{
+					Limit: 5,
+					Backoff: &ephemeralv1alpha1.BackoffPolicy{
+						Duration:    "5s",
+						Factor:      &factor,
+						MaxDuration: "3m",
+					},
+				},
+			},
+		},
+	}
+
+	got := BuildSyncPolicy(ephApp)
+
+	if got.Automated == nil || !got.Automated.Prune || !got.Automated.SelfHeal {
+		t.Errorf("expected automated prune and selfHeal, got %+v", got.Automated)
+	}
+	if got.Retry == nil || got.Retry.Limit != 5 {
+		t.Fatalf("expected retry limit 5, got %+v", got.Retry)
+	}
+	if got.Retry.Backoff == nil {
+		t.Fatal("expected retry backoff to be set")
+	}
+	if got.Retry.Backoff.Duration != "5s" || got.Retry.Backoff.MaxDuration != "3m" {
+		t.Errorf("unexpected backoff durations: %+v", got.Retry.Backoff)
+	}
+	if got.Retry.Backoff.Factor == nil || *got.Retry.Backoff.Factor != 2 {
+		t.Errorf("expected backoff factor 2, got %v", got.Retry.Backoff.Factor)
+	}
+}
@@ -0,0 +1,217 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	ephemeralv1alpha1 "github.com/jbarea/argo-ephemeral-operator/api/v1alpha1"
+)
+
+const (
+	// InClusterServer is the ArgoCD destination of the cluster the operator runs in
+	InClusterServer = "https://kubernetes.default.svc"
+	// inClusterName is the name ArgoCD gives to the cluster it runs in
+	inClusterName = "in-cluster"
+
+	// secretTypeLabel and secretTypeCluster identify the ArgoCD cluster secrets
+	secretTypeLabel   = "argocd.argoproj.io/secret-type"
+	secretTypeCluster = "cluster"
+
+	// defaultKubeconfigKey is the key of the kubeconfig when the reference does not set one
+	defaultKubeconfigKey = "kubeconfig"
+)
+
+// ErrClusterNotFound is returned when the destination does not match any ArgoCD cluster
+var ErrClusterNotFound = errors.New("cluster not found")
+
+// Target is a cluster ephemeral environments are deployed to
+type Target struct {
+	// Client manages the namespace and the injected resources of the environments
+	Client client.Client
+	// Server is the API server URL used as the destination of the ArgoCD Applications
+	Server string
+}
+
+// Cache resolves destinations to clusters and keeps one client per remote cluster, the clients
+// are rebuilt when the secret holding the credentials of the cluster changes
+type Cache struct {
+	local         client.Client
+	scheme        *runtime.Scheme
+	argoNamespace string
+
+	// NewClient builds the client of a remote cluster, it can be replaced before the first use
+	NewClient func(config *rest.Config) (client.Client, error)
+
+	mu      sync.Mutex
+	targets map[string]cachedTarget
+}
+
+// cachedTarget is a remote cluster with the version of the secret it was built from
+type cachedTarget struct {
+	target          Target
+	resourceVersion string
+}
+
+// NewCache returns a Cache, local is the client of the cluster the operator runs in and is
+// used to read the ArgoCD cluster secrets and the kubeconfig secrets
+func NewCache(local client.Client, scheme *runtime.Scheme, argoNamespace string) *Cache {
+	return &Cache{
+		local:         local,
+		scheme:        scheme,
+		argoNamespace: argoNamespace,
+		NewClient: func(config *rest.Config) (client.Client, error) {
+			return client.New(config, client.Options{Scheme: scheme})
+		},
+		targets: map[string]cachedTarget{},
+	}
+}
+
+// Get returns the cluster selected by the destination of an environment living in namespace,
+// a nil destination selects the cluster the operator runs in
+func (c *Cache) Get(ctx context.Context, destination *ephemeralv1alpha1.Destination, namespace string) (Target, error) {
+	switch {
+	case destination == nil:
+		return c.inCluster(), nil
+	case destination.KubeconfigSecretRef != nil:
+		return c.fromKubeconfigSecret(ctx, destination.KubeconfigSecretRef, namespace)
+	case destination.Name != "":
+		return c.fromArgoCluster(ctx, destination.Name)
+	default:
+		return Target{}, fmt.Errorf("destination must set a cluster name or a kubeconfig secret")
+	}
+}
+
+func (c *Cache) inCluster() Target {
+	return Target{Client: c.local, Server: InClusterServer}
+}
+
+// fromArgoCluster returns a cluster registered in ArgoCD, its credentials are read from the
+// cluster secret in the ArgoCD namespace
+func (c *Cache) fromArgoCluster(ctx context.Context, name string) (Target, error) {
+	secrets := &corev1.SecretList{}
+	err := c.local.List(ctx, secrets,
+		client.InNamespace(c.argoNamespace),
+		client.MatchingLabels{secretTypeLabel: secretTypeCluster})
+	if err != nil {
+		return Target{}, fmt.Errorf("failed to list ArgoCD cluster secrets: %w", err)
+	}
+
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		if string(secret.Data["name"]) != name {
+			continue
+		}
+		server := string(secret.Data["server"])
+		if server == InClusterServer {
+			return c.inCluster(), nil
+		}
+		return c.cached("argocd/"+name, secret.ResourceVersion, func() (*rest.Config, error) {
+			return restConfigFromClusterSecret(secret)
+		})
+	}
+
+	// ArgoCD knows the cluster it runs in without a secret
+	if name == inClusterName {
+		return c.inCluster(), nil
+	}
+	return Target{}, fmt.Errorf("%w: no ArgoCD cluster named %q in namespace %s", ErrClusterNotFound, name, c.argoNamespace)
+}
+
+// fromKubeconfigSecret returns the cluster of a kubeconfig stored in a secret of namespace
+func (c *Cache) fromKubeconfigSecret(ctx context.Context, ref *ephemeralv1alpha1.KubeconfigSecretReference, namespace string) (Target, error) {
+	secret := &corev1.Secret{}
+	if err := c.local.Get(ctx, client.ObjectKey{Namespace: namespace, Name: ref.Name}, secret); err != nil {
+		return Target{}, fmt.Errorf("failed to get kubeconfig secret %s/%s: %w", namespace, ref.Name, err)
+	}
+
+	key := ref.Key
+	if key == "" {
+		key = defaultKubeconfigKey
+	}
+	kubeconfig, ok := secret.Data[key]
+	if !ok {
+		return Target{}, fmt.Errorf("kubeconfig secret %s/%s has no key %q", namespace, ref.Name, key)
+	}
+
+	cacheKey := fmt.Sprintf("kubeconfig/%s/%s/%s", namespace, ref.Name, key)
+	return c.cached(cacheKey, secret.ResourceVersion, func() (*rest.Config, error) {
+		return clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	})
+}
+
+// cached returns the cluster stored under key, building its client again when the secret
+// it was built from has a different version
+func (c *Cache) cached(key, resourceVersion string, restConfig func() (*rest.Config, error)) (Target, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cached, ok := c.targets[key]; ok && cached.resourceVersion == resourceVersion {
+		return cached.target, nil
+	}
+
+	config, err := restConfig()
+	if err != nil {
+		return Target{}, fmt.Errorf("invalid credentials for cluster %s: %w", key, err)
+	}
+	remote, err := c.NewClient(config)
+	if err != nil {
+		return Target{}, fmt.Errorf("failed to create client for cluster %s: %w", key, err)
+	}
+
+	target := Target{Client: remote, Server: config.Host}
+	c.targets[key] = cachedTarget{target: target, resourceVersion: resourceVersion}
+	return target, nil
+}
+
+// clusterConfig is the connection config stored in the "config" key of an ArgoCD cluster secret
+// Exec and cloud provider authentication are not supported
+type clusterConfig struct {
+	Username        string `json:"username,omitempty"`
+	Password        string `json:"password,omitempty"`
+	BearerToken     string `json:"bearerToken,omitempty"`
+	TLSClientConfig struct {
+		Insecure   bool   `json:"insecure"`
+		ServerName string `json:"serverName,omitempty"`
+		CAData     []byte `json:"caData,omitempty"`
+		CertData   []byte `json:"certData,omitempty"`
+		KeyData    []byte `json:"keyData,omitempty"`
+	} `json:"tlsClientConfig"`
+}
+
+// restConfigFromClusterSecret builds the client config of an ArgoCD cluster secret
+func restConfigFromClusterSecret(secret *corev1.Secret) (*rest.Config, error) {
+	server := string(secret.Data["server"])
+	if server == "" {
+		return nil, fmt.Errorf("secret %s has no server", secret.Name)
+	}
+
+	var config clusterConfig
+	if raw := secret.Data["config"]; len(raw) > 0 {
+		if err := json.Unmarshal(raw, &config); err != nil {
+			return nil, fmt.Errorf("secret %s has an invalid config: %w", secret.Name, err)
+		}
+	}
+
+	return &rest.Config{
+		Host:        server,
+		Username:    config.Username,
+		Password:    config.Password,
+		BearerToken: config.BearerToken,
+		TLSClientConfig: rest.TLSClientConfig{
+			Insecure:   config.TLSClientConfig.Insecure,
+			ServerName: config.TLSClientConfig.ServerName,
+			CAData:     config.TLSClientConfig.CAData,
+			CertData:   config.TLSClientConfig.CertData,
+			KeyData:    config.TLSClientConfig.KeyData,
+		},
+	}, nil
+}
@@ -0,0 +1,147 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	ephemeralv1alpha1 "github.com/jbarea/argo-ephemeral-operator/api/v1alpha1"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: staging
+  cluster:
+    server: https://staging.example.com:6443
+contexts:
+- name: staging
+  context:
+    cluster: staging
+    user: operator
+current-context: staging
+users:
+- name: operator
+  user:
+    token: secret-token
+`
+
+// newTestCache returns a Cache whose remote clients are fake clients, it records the configs
+func newTestCache(objs ...client.Object) (*Cache, *[]*rest.Config) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+
+	local := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	cache := NewCache(local, scheme, "argocd")
+	configs := &[]*rest.Config{}
+	cache.NewClient = func(config *rest.Config) (client.Client, error) {
+		*configs = append(*configs, config)
+		return fake.NewClientBuilder().WithScheme(scheme).Build(), nil
+	}
+	return cache, configs
+}
+
+func TestCache_ArgoCluster(t *testing.T) {
+	clusterSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cluster-staging",
+			Namespace: "argocd",
+			Labels:    map[string]string{secretTypeLabel: secretTypeCluster},
+		},
+		Data: map[string][]byte{
+			"name":   []byte("staging"),
+			"server": []byte("https://staging.example.com"),
+			"config": []byte(`{"bearerToken":"secret-token","tlsClientConfig":{"insecure":false,"caData":"Y2E="}}`),
+		},
+	}
+	cache, configs := newTestCache(clusterSecret)
+	ctx := context.Background()
+
+	target, err := cache.Get(ctx, &ephemeralv1alpha1.Destination{Name: "staging"}, "default")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if target.Server != "https://staging.example.com" {
+		t.Errorf("expected the server of the cluster secret, got %s", target.Server)
+	}
+	if len(*configs) != 1 || (*configs)[0].BearerToken != "secret-token" || string((*configs)[0].CAData) != "ca" {
+		t.Fatalf("expected a client built from the cluster secret, got %+v", *configs)
+	}
+
+	if _, err := cache.Get(ctx, &ephemeralv1alpha1.Destination{Name: "staging"}, "default"); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if len(*configs) != 1 {
+		t.Errorf("expected the client to be reused, got %d clients", len(*configs))
+	}
+
+	// Rotated credentials build a new client
+	clusterSecret.Data["config"] = []byte(`{"bearerToken":"rotated-token"}`)
+	if err := cache.local.Update(ctx, clusterSecret); err != nil {
+		t.Fatalf("failed to update cluster secret: %v", err)
+	}
+	if _, err := cache.Get(ctx, &ephemeralv1alpha1.Destination{Name: "staging"}, "default"); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if len(*configs) != 2 || (*configs)[1].BearerToken != "rotated-token" {
+		t.Errorf("expected a new client with the rotated token, got %+v", *configs)
+	}
+}
+
+func TestCache_InCluster(t *testing.T) {
+	cache, configs := newTestCache()
+	ctx := context.Background()
+
+	for _, destination := range []*ephemeralv1alpha1.Destination{nil, {Name: inClusterName}} {
+		target, err := cache.Get(ctx, destination, "default")
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if target.Server != InClusterServer || target.Client != cache.local {
+			t.Errorf("expected the local cluster for %+v, got %s", destination, target.Server)
+		}
+	}
+	if len(*configs) != 0 {
+		t.Errorf("expected no remote client, got %d", len(*configs))
+	}
+
+	_, err := cache.Get(ctx, &ephemeralv1alpha1.Destination{Name: "missing"}, "default")
+	if !errors.Is(err, ErrClusterNotFound) {
+		t.Errorf("expected ErrClusterNotFound, got %v", err)
+	}
+}
+
+func TestCache_KubeconfigSecret(t *testing.T) {
+	kubeconfigSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "staging-kubeconfig", Namespace: "team-a"},
+		Data:       map[string][]byte{"config": []byte(testKubeconfig)},
+	}
+	cache, configs := newTestCache(kubeconfigSecret)
+	ctx := context.Background()
+
+	destination := &ephemeralv1alpha1.Destination{
+		KubeconfigSecretRef: &ephemeralv1alpha1.KubeconfigSecretReference{Name: "staging-kubeconfig", Key: "config"},
+	}
+	target, err := cache.Get(ctx, destination, "team-a")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if target.Server != "https://staging.example.com:6443" {
+		t.Errorf("expected the server of the kubeconfig, got %s", target.Server)
+	}
+	if len(*configs) != 1 || (*configs)[0].BearerToken != "secret-token" {
+		t.Errorf("expected a client built from the kubeconfig, got %+v", *configs)
+	}
+
+	// The secret is only looked up in the namespace of the environment
+	if _, err := cache.Get(ctx, destination, "team-b"); err == nil {
+		t.Error("expected an error reading a kubeconfig secret from another namespace")
+	}
+}
@@ -7,9 +7,17 @@ import (
 	"time"
 )
 
+const (
+	// ArgoBackendGRPC manages ArgoCD Applications through the ArgoCD API server
+	ArgoBackendGRPC = "grpc"
+	// ArgoBackendKubernetes manages the Application resources through the Kubernetes API
+	ArgoBackendKubernetes = "kubernetes"
+)
+
 // Config holds the operator configuration
 type Config struct {
 	// ArgoCD configuration
+	ArgoBackend   string
 	ArgoServer    string
 	ArgoPort      string
 	ArgoUsername  string
@@ -17,24 +25,61 @@ type Config struct {
 	ArgoNamespace string
 	ArgoInsecure  bool
 
+	// ArgoCD API token, read from ArgoTokenFile when set so rotated tokens are picked up
+	ArgoToken     string
+	ArgoTokenFile string
+
+	// TLS configuration of the ArgoCD API server connection
+	ArgoCAFile         string
+	ArgoClientCertFile string
+	ArgoClientKeyFile  string
+
 	// Operator configuration
 	MetricsAddr          string
 	ProbeAddr            string
 	LeaderElectionID     string
 	EnableLeaderElection bool
 	ReconcileInterval    time.Duration
+
+	// WatchArgoApplications watches the ArgoCD Application resources in ArgoNamespace
+	// instead of polling their status through the ArgoCD API
+	WatchArgoApplications bool
+
+	// CreateAppProjects creates an ArgoCD AppProject per environment restricted to its
+	// namespace instead of deploying every environment with the default project
+	CreateAppProjects bool
+
+	// PreviewDomain is the domain environments are exposed under, each one at
+	// <namespace>.<PreviewDomain>
+	PreviewDomain string
+
+	// Admission webhook configuration
+	EnableWebhooks bool
+	WebhookPort    int
+	WebhookCertDir string
+
+	// WebhookTrustedCreator is the user allowed to create environments on behalf of other
+	// users, every other user is recorded as the creator of the environments it creates
+	WebhookTrustedCreator string
 }
 
 // LoadConfig loads configuration from environment variables
 func LoadConfig() (*Config, error) {
 	cfg := &Config{
 		// ArgoCD defaults
+		ArgoBackend:   getEnvOrDefault("ARGO_BACKEND", ArgoBackendGRPC),
 		ArgoServer:    getEnvOrDefault("ARGO_SERVER", "argocd-server.argocd.svc.cluster.local"),
 		ArgoNamespace: getEnvOrDefault("ARGO_NAMESPACE", "argocd"),
 		ArgoPort:      getEnvOrDefault("ARGO_PORT", "8080"),
 		ArgoUsername:  getEnvOrDefault("ARGO_USERNAME", "admin"),
-		ArgoPassword:  getEnvOrDefault("ARGO_PASSWORD", "admin"),
-		ArgoInsecure:  getEnvBoolOrDefault("ARGO_INSECURE", true),
+		ArgoPassword:  os.Getenv("ARGO_PASSWORD"),
+		ArgoInsecure:  getEnvBoolOrDefault("ARGO_INSECURE", false),
+
+		ArgoToken:          os.Getenv("ARGO_TOKEN"),
+		ArgoTokenFile:      os.Getenv("ARGO_TOKEN_FILE"),
+		ArgoCAFile:         os.Getenv("ARGO_CA_FILE"),
+		ArgoClientCertFile: os.Getenv("ARGO_CLIENT_CERT_FILE"),
+		ArgoClientKeyFile:  os.Getenv("ARGO_CLIENT_KEY_FILE"),
 
 		// Operator defaults
 		MetricsAddr:          getEnvOrDefault("METRICS_ADDR", ":8080"),
@@ -42,6 +87,17 @@ func LoadConfig() (*Config, error) {
 		LeaderElectionID:     getEnvOrDefault("LEADER_ELECTION_ID", "argo-ephemeral-operator-lock"),
 		EnableLeaderElection: getEnvBoolOrDefault("ENABLE_LEADER_ELECTION", false),
 		ReconcileInterval:    getEnvDurationOrDefault("RECONCILE_INTERVAL", 5*time.Minute),
+
+		WatchArgoApplications: getEnvBoolOrDefault("WATCH_ARGO_APPLICATIONS", false),
+		CreateAppProjects:     getEnvBoolOrDefault("CREATE_APP_PROJECTS", false),
+		PreviewDomain:         os.Getenv("PREVIEW_DOMAIN"),
+
+		// Webhook defaults
+		EnableWebhooks: getEnvBoolOrDefault("ENABLE_WEBHOOKS", false),
+		WebhookPort:    getEnvIntOrDefault("WEBHOOK_PORT", 9443),
+		WebhookCertDir: getEnvOrDefault("WEBHOOK_CERT_DIR", "/tmp/k8s-webhook-server/serving-certs"),
+
+		WebhookTrustedCreator: os.Getenv("WEBHOOK_TRUSTED_CREATOR"),
 	}
 
 	if err := cfg.Validate(); err != nil {
@@ -53,14 +109,21 @@ func LoadConfig() (*Config, error) {
 
 // Validate validates the configuration
 func (c *Config) Validate() error {
-	if c.ArgoServer == "" {
-		return fmt.Errorf("ARGO_SERVER is required")
-	}
-	if c.ArgoUsername == "" {
-		return fmt.Errorf("ARGO_USERNAME is required")
-	}
-	if c.ArgoPassword == "" {
-		return fmt.Errorf("ARGO_PASSWORD is required")
+	switch c.ArgoBackend {
+	case ArgoBackendGRPC:
+		if c.ArgoServer == "" {
+			return fmt.Errorf("ARGO_SERVER is required")
+		}
+		if c.ArgoToken == "" && c.ArgoTokenFile == "" && (c.ArgoUsername == "" || c.ArgoPassword == "") {
+			return fmt.Errorf("ARGO_TOKEN, ARGO_TOKEN_FILE or ARGO_USERNAME and ARGO_PASSWORD are required")
+		}
+		if (c.ArgoClientCertFile == "") != (c.ArgoClientKeyFile == "") {
+			return fmt.Errorf("ARGO_CLIENT_CERT_FILE and ARGO_CLIENT_KEY_FILE must be set together")
+		}
+	case ArgoBackendKubernetes:
+		// The Application resources are managed with the operator service account
+	default:
+		return fmt.Errorf("ARGO_BACKEND must be %q or %q, got %q", ArgoBackendGRPC, ArgoBackendKubernetes, c.ArgoBackend)
 	}
 	if c.ArgoNamespace == "" {
 		return fmt.Errorf("ARGO_NAMESPACE is required")
@@ -87,6 +150,17 @@ func getEnvBoolOrDefault(key string, defaultValue bool) bool {
 	return defaultValue
 }
 
+// getEnvIntOrDefault returns the integer value of an environment variable or a default value
+func getEnvIntOrDefault(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
 // getEnvDurationOrDefault returns the duration value of an environment variable or a default value
 func getEnvDurationOrDefault(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
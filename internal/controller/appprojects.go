@@ -0,0 +1,143 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	ephemeralv1alpha1 "github.com/jbarea/argo-ephemeral-operator/api/v1alpha1"
+	"github.com/jbarea/argo-ephemeral-operator/internal/argocd"
+	"github.com/jbarea/argo-ephemeral-operator/internal/policy"
+)
+
+const (
+	// defaultProject is the ArgoCD project used when no AppProject is created per environment
+	defaultProject = "default"
+
+	// appProjectPrefix is prepended to the namespace to name the AppProject of an environment,
+	// so it never matches the projects managed outside the operator such as the default one
+	appProjectPrefix = "ephemeral-env-"
+)
+
+// reconcileAppProject creates or updates the AppProject of an environment so its applications
+// can only deploy to the ephemeral namespace, and records its name in the status
+// Nothing is done unless CreateAppProjects is enabled
+func (r *EphemeralApplicationReconciler) reconcileAppProject(
+	ctx context.Context,
+	ephApp *ephemeralv1alpha1.EphemeralApplication,
+	namespace string,
+	policies policy.Policies,
+) error {
+	if !r.Config.CreateAppProjects {
+		return nil
+	}
+	logger := log.FromContext(ctx)
+
+	desired := r.buildAppProject(ephApp, namespace, policies.AppProject())
+
+	existing, err := r.ArgoClient.GetProject(ctx, desired.Name)
+	switch {
+	// ArgoCD answers PermissionDenied instead of NotFound for projects that do not exist
+	case argocd.IsNotFound(err) || argocd.IsPermissionDenied(err):
+		logger.Info("creating ArgoCD project", "name", desired.Name)
+		if _, err := r.ArgoClient.CreateProject(ctx, desired); err != nil {
+			return fmt.Errorf("failed to create ArgoCD project %s: %w", desired.Name, err)
+		}
+	case err != nil:
+		return fmt.Errorf("failed to get ArgoCD project %s: %w", desired.Name, err)
+	case !ownedBy(existing.Labels, ephApp):
+		return fmt.Errorf("ArgoCD project %s already exists and is not owned by this environment", desired.Name)
+	default:
+		existing.Spec = desired.Spec
+		if existing.Labels == nil {
+			existing.Labels = map[string]string{}
+		}
+		for k, v := range desired.Labels {
+			existing.Labels[k] = v
+		}
+		if _, err := r.ArgoClient.UpdateProject(ctx, existing); err != nil {
+			return fmt.Errorf("failed to update ArgoCD project %s: %w", desired.Name, err)
+		}
+	}
+
+	ephApp.Status.AppProject = desired.Name
+	return nil
+}
+
+// buildAppProject builds the AppProject of an environment, it is named after the ephemeral
+// namespace with appProjectPrefix and only allows the repositories of the components and those of the policies
+func (r *EphemeralApplicationReconciler) buildAppProject(
+	ephApp *ephemeralv1alpha1.EphemeralApplication,
+	namespace string,
+	settings ephemeralv1alpha1.AppProjectPolicy,
+) *v1alpha1.AppProject {
+	var sourceRepos []string
+	for _, component := range ephApp.Spec.ResolvedComponents() {
+		if !slices.Contains(sourceRepos, component.RepoURL) {
+			sourceRepos = append(sourceRepos, component.RepoURL)
+		}
+	}
+	for _, repo := range settings.SourceRepos {
+		if !slices.Contains(sourceRepos, repo) {
+			sourceRepos = append(sourceRepos, repo)
+		}
+	}
+
+	return &v1alpha1.AppProject{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      appProjectPrefix + namespace,
+			Namespace: r.Config.ArgoNamespace,
+			Labels:    applicationLabels(ephApp),
+		},
+		Spec: v1alpha1.AppProjectSpec{
+			Description: fmt.Sprintf("Ephemeral environment %s/%s", ephApp.Namespace, ephApp.Name),
+			SourceRepos: sourceRepos,
+			Destinations: []v1alpha1.ApplicationDestination{{
+				Server:    destinationServer(ephApp),
+				Namespace: namespace,
+			}},
+			ClusterResourceWhitelist: settings.ClusterResourceWhitelist,
+			ClusterResourceBlacklist: settings.ClusterResourceBlacklist,
+		},
+	}
+}
+
+// applicationProject returns the ArgoCD project of the applications of an environment
+func applicationProject(ephApp *ephemeralv1alpha1.EphemeralApplication) string {
+	if ephApp.Status.AppProject == "" {
+		return defaultProject
+	}
+	return ephApp.Status.AppProject
+}
+
+// deleteAppProject deletes the AppProject of an environment once its applications are gone, a
+// project that is not owned by the environment is left alone
+func (r *EphemeralApplicationReconciler) deleteAppProject(ctx context.Context, ephApp *ephemeralv1alpha1.EphemeralApplication) error {
+	logger := log.FromContext(ctx)
+	name := ephApp.Status.AppProject
+	if name == "" {
+		return nil
+	}
+
+	existing, err := r.ArgoClient.GetProject(ctx, name)
+	switch {
+	case argocd.IsNotFound(err) || argocd.IsPermissionDenied(err):
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to get ArgoCD project %s: %w", name, err)
+	case !ownedBy(existing.Labels, ephApp):
+		logger.Info("ArgoCD project is not owned by this environment, skipping its deletion", "name", name)
+		return nil
+	}
+
+	logger.Info("deleting ArgoCD project", "name", name)
+	err = r.ArgoClient.DeleteProject(ctx, name)
+	if err != nil && !argocd.IsNotFound(err) && !argocd.IsPermissionDenied(err) {
+		return fmt.Errorf("failed to delete ArgoCD project %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	ephemeralv1alpha1 "github.com/jbarea/argo-ephemeral-operator/api/v1alpha1"
+	"github.com/jbarea/argo-ephemeral-operator/internal/config"
+	"github.com/jbarea/argo-ephemeral-operator/internal/naming"
+)
+
+func TestReconcile_CreatesAndDeletesAppProject(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = networkingv1.AddToScheme(scheme)
+	_ = ephemeralv1alpha1.AddToScheme(scheme)
+
+	namespaceKind := metav1.GroupKind{Kind: "Namespace"}
+	projectPolicy := &ephemeralv1alpha1.EphemeralPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec: ephemeralv1alpha1.EphemeralPolicySpec{AppProject: &ephemeralv1alpha1.AppProjectPolicy{
+			SourceRepos:              []string{"https://github.com/example/bases.git"},
+			ClusterResourceBlacklist: []metav1.GroupKind{namespaceKind},
+		}},
+	}
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "preview",
+			Namespace:  "default",
+			Finalizers: []string{finalizerName},
+		},
+		Spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+			RepoURL: "https://github.com/example/app.git",
+			Path:    "manifests",
+			TTL:     &metav1.Duration{Duration: time.Hour},
+		},
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(projectPolicy, ephApp).
+		WithStatusSubresource(ephApp).
+		Build()
+
+	argoClient := newMockArgoClient()
+	reconciler := &EphemeralApplicationReconciler{
+		Client:     fakeClient,
+		Scheme:     scheme,
+		ArgoClient: argoClient,
+		Config: &config.Config{
+			ArgoNamespace:     "argocd",
+			ReconcileInterval: time.Minute,
+			CreateAppProjects: true,
+		},
+		NameGenerator: naming.NewDefaultGenerator(),
+	}
+
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ephApp)}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	updated := &ephemeralv1alpha1.EphemeralApplication{}
+	if err := fakeClient.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("failed to get EphemeralApplication: %v", err)
+	}
+	namespace := updated.Status.Namespace
+	projectName := appProjectPrefix + namespace
+	if updated.Status.AppProject != projectName {
+		t.Fatalf("expected project %q in status, got %q", projectName, updated.Status.AppProject)
+	}
+
+	project, ok := argoClient.projects[projectName]
+	if !ok {
+		t.Fatalf("expected ArgoCD project %s to be created", projectName)
+	}
+	if len(project.Spec.Destinations) != 1 || project.Spec.Destinations[0].Namespace != namespace {
+		t.Errorf("expected the project to only allow namespace %s, got %+v", namespace, project.Spec.Destinations)
+	}
+	if len(project.Spec.SourceRepos) != 2 {
+		t.Errorf("expected the application and policy repositories, got %v", project.Spec.SourceRepos)
+	}
+	if len(project.Spec.ClusterResourceBlacklist) != 1 || project.Spec.ClusterResourceBlacklist[0] != namespaceKind {
+		t.Errorf("expected the policy blacklist, got %v", project.Spec.ClusterResourceBlacklist)
+	}
+	if got := argoClient.apps["preview"].Spec.Project; got != projectName {
+		t.Errorf("expected the application in project %s, got %s", projectName, got)
+	}
+
+	if err := fakeClient.Delete(ctx, updated); err != nil {
+		t.Fatalf("failed to delete EphemeralApplication: %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if _, ok := argoClient.projects[projectName]; ok {
+		t.Errorf("expected ArgoCD project %s to be deleted", projectName)
+	}
+}
+
+func TestReconcile_DoesNotAdoptAppProject(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = networkingv1.AddToScheme(scheme)
+	_ = ephemeralv1alpha1.AddToScheme(scheme)
+
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "preview",
+			Namespace:  "default",
+			Finalizers: []string{finalizerName},
+		},
+		Spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+			RepoURL:       "https://github.com/example/app.git",
+			Path:          "manifests",
+			TTL:           &metav1.Duration{Duration: time.Hour},
+			NamespaceName: "ephemeral-preview",
+		},
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(ephApp).
+		WithStatusSubresource(ephApp).
+		Build()
+
+	// A project with the same name owned by another environment
+	projectName := appProjectPrefix + "ephemeral-preview"
+	argoClient := newMockArgoClient()
+	argoClient.projects[projectName] = &v1alpha1.AppProject{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   projectName,
+			Labels: map[string]string{ownerLabel: "other", ownerNamespaceLabel: "default"},
+		},
+		Spec: v1alpha1.AppProjectSpec{SourceRepos: []string{"*"}},
+	}
+	reconciler := &EphemeralApplicationReconciler{
+		Client:     fakeClient,
+		Scheme:     scheme,
+		ArgoClient: argoClient,
+		Config: &config.Config{
+			ArgoNamespace:     "argocd",
+			ReconcileInterval: time.Minute,
+			CreateAppProjects: true,
+		},
+		NameGenerator: naming.NewDefaultGenerator(),
+	}
+
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ephApp)}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	updated := &ephemeralv1alpha1.EphemeralApplication{}
+	if err := fakeClient.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("failed to get EphemeralApplication: %v", err)
+	}
+	if updated.Status.Phase != ephemeralv1alpha1.PhaseFailed || updated.Status.AppProject != "" {
+		t.Errorf("expected phase Failed without project, got %s %q", updated.Status.Phase, updated.Status.AppProject)
+	}
+	if repos := argoClient.projects[projectName].Spec.SourceRepos; len(repos) != 1 || repos[0] != "*" {
+		t.Errorf("expected the existing project to be left alone, got %v", repos)
+	}
+
+	// The project is not deleted with the environment either
+	updated.Status.AppProject = projectName
+	if err := reconciler.deleteAppProject(ctx, updated); err != nil {
+		t.Fatalf("deleteAppProject failed: %v", err)
+	}
+	if _, ok := argoClient.projects[projectName]; !ok {
+		t.Error("expected the existing project to be kept")
+	}
+}
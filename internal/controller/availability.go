@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	ephemeralv1alpha1 "github.com/jbarea/argo-ephemeral-operator/api/v1alpha1"
+	"github.com/jbarea/argo-ephemeral-operator/internal/argocd"
+)
+
+// argoUnavailableCondition is true while calls to ArgoCD are suspended by the circuit breaker
+const argoUnavailableCondition = "ArgoUnavailable"
+
+// argoUnavailable returns an error while the ArgoCD client suspends calls, clients without
+// a circuit breaker are always considered available
+func (r *EphemeralApplicationReconciler) argoUnavailable() error {
+	availability, ok := r.ArgoClient.(argocd.Availability)
+	if !ok {
+		return nil
+	}
+	return availability.Unavailable()
+}
+
+// reconcileArgoAvailability sets the ArgoUnavailable condition while ArgoCD is unavailable
+// and clears it once ArgoCD is back
+func (r *EphemeralApplicationReconciler) reconcileArgoAvailability(ctx context.Context, ephApp *ephemeralv1alpha1.EphemeralApplication) error {
+	unavailable := r.argoUnavailable()
+	current := meta.FindStatusCondition(ephApp.Status.Conditions, argoUnavailableCondition)
+
+	switch {
+	case unavailable != nil && (current == nil || current.Status != metav1.ConditionTrue):
+		r.setCondition(ephApp, argoUnavailableCondition, metav1.ConditionTrue, "CircuitOpen", unavailable.Error())
+	case unavailable == nil && current != nil && current.Status == metav1.ConditionTrue:
+		r.setCondition(ephApp, argoUnavailableCondition, metav1.ConditionFalse, "Available", "ArgoCD is reachable")
+	default:
+		return nil
+	}
+	return r.updateStatus(ctx, ephApp)
+}
+
+// availabilityEvents returns a channel that receives an event whenever the availability of
+// ArgoCD changes, so every EphemeralApplication gets its condition updated
+func (r *EphemeralApplicationReconciler) availabilityEvents(mgr ctrl.Manager) (<-chan event.GenericEvent, error) {
+	availability, ok := r.ArgoClient.(argocd.Availability)
+	if !ok {
+		return nil, nil
+	}
+
+	changes := make(chan struct{}, 1)
+	availability.Notify(changes)
+
+	events := make(chan event.GenericEvent)
+	err := mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-changes:
+				select {
+				case events <- event.GenericEvent{Object: &ephemeralv1alpha1.EphemeralApplication{}}:
+				case <-ctx.Done():
+					return nil
+				}
+			}
+		}
+	}))
+	return events, err
+}
+
+// findAllApplications maps an availability change to every EphemeralApplication
+func (r *EphemeralApplicationReconciler) findAllApplications(ctx context.Context, _ client.Object) []reconcile.Request {
+	logger := log.FromContext(ctx)
+
+	list := &ephemeralv1alpha1.EphemeralApplicationList{}
+	if err := r.List(ctx, list); err != nil {
+		logger.Error(err, "failed to list EphemeralApplications")
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(list.Items))
+	for _, ephApp := range list.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: client.ObjectKeyFromObject(&ephApp),
+		})
+	}
+	return requests
+}
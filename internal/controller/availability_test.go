@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	ephemeralv1alpha1 "github.com/jbarea/argo-ephemeral-operator/api/v1alpha1"
+	"github.com/jbarea/argo-ephemeral-operator/internal/argocd"
+	"github.com/jbarea/argo-ephemeral-operator/internal/config"
+	"github.com/jbarea/argo-ephemeral-operator/internal/naming"
+)
+
+func TestReconcile_SurfacesArgoUnavailable(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = networkingv1.AddToScheme(scheme)
+	_ = ephemeralv1alpha1.AddToScheme(scheme)
+
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test-app",
+			Namespace:  "default",
+			Finalizers: []string{finalizerName},
+		},
+		Spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+			RepoURL:        "https://github.com/example/app.git",
+			Path:           "manifests",
+			ExpirationDate: &metav1.Time{Time: time.Now().Add(time.Hour)},
+		},
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(ephApp).
+		WithStatusSubresource(ephApp).
+		Build()
+
+	argoClient := newMockArgoClient()
+	argoClient.unavailable = fmt.Errorf("%w: circuit open", argocd.ErrUnavailable)
+
+	reconciler := &EphemeralApplicationReconciler{
+		Client:        fakeClient,
+		Scheme:        scheme,
+		ArgoClient:    argoClient,
+		Config:        &config.Config{ArgoNamespace: "argocd", ReconcileInterval: time.Minute},
+		NameGenerator: naming.NewDefaultGenerator(),
+	}
+
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ephApp)}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	updated := &ephemeralv1alpha1.EphemeralApplication{}
+	if err := fakeClient.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("failed to get EphemeralApplication: %v", err)
+	}
+	if !meta.IsStatusConditionTrue(updated.Status.Conditions, argoUnavailableCondition) {
+		t.Errorf("expected condition %s to be true, got %v", argoUnavailableCondition, updated.Status.Conditions)
+	}
+	if updated.Status.Namespace != "" || updated.Status.Phase == ephemeralv1alpha1.PhaseFailed {
+		t.Errorf("expected the environment to wait while ArgoCD is unavailable, got phase %q", updated.Status.Phase)
+	}
+
+	// ArgoCD is back
+	argoClient.unavailable = nil
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if err := fakeClient.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("failed to get EphemeralApplication: %v", err)
+	}
+	if !meta.IsStatusConditionFalse(updated.Status.Conditions, argoUnavailableCondition) {
+		t.Errorf("expected condition %s to be false, got %v", argoUnavailableCondition, updated.Status.Conditions)
+	}
+	if updated.Status.Phase != ephemeralv1alpha1.PhaseCreating {
+		t.Errorf("expected phase Creating, got %s", updated.Status.Phase)
+	}
+}
@@ -0,0 +1,216 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/argoproj/argo-cd/v2/pkg/apiclient/application"
+	"github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	ephemeralv1alpha1 "github.com/jbarea/argo-ephemeral-operator/api/v1alpha1"
+	"github.com/jbarea/argo-ephemeral-operator/internal/argocd"
+)
+
+// componentApplicationName returns the ArgoCD Application name for a component
+// The single-source spec keeps the EphemeralApplication name
+func componentApplicationName(ephApp *ephemeralv1alpha1.EphemeralApplication, component ephemeralv1alpha1.Component) string {
+	if component.Name == "" {
+		return ephApp.Name
+	}
+	return fmt.Sprintf("%s-%s", ephApp.Name, component.Name)
+}
+
+// argoApplicationNames returns the names of the ArgoCD Applications recorded in the status
+func argoApplicationNames(ephApp *ephemeralv1alpha1.EphemeralApplication) []string {
+	var names []string
+	if ephApp.Status.ArgoApplicationName != "" {
+		names = append(names, ephApp.Status.ArgoApplicationName)
+	}
+	for _, component := range ephApp.Status.Components {
+		names = append(names, component.ArgoApplicationName)
+	}
+	return names
+}
+
+// reconcileComponentApplications creates, updates and deletes ArgoCD Applications so that
+// there is exactly one per component, the status records the applications that exist
+// afterwards even if an error is returned so that they are cleaned up later
+func (r *EphemeralApplicationReconciler) reconcileComponentApplications(ctx context.Context, ephApp *ephemeralv1alpha1.EphemeralApplication, namespace string) error {
+	logger := log.FromContext(ctx)
+
+	existing := argoApplicationNames(ephApp)
+	live := make(map[string]bool, len(existing))
+	for _, name := range existing {
+		live[name] = true
+	}
+
+	componentNames := make(map[string]string)
+	for _, status := range ephApp.Status.Components {
+		componentNames[status.ArgoApplicationName] = status.Name
+	}
+
+	var desired []string
+	var reconcileErr error
+	for _, component := range ephApp.Spec.ResolvedComponents() {
+		name := componentApplicationName(ephApp, component)
+		desired = append(desired, name)
+		componentNames[name] = component.Name
+
+		if live[name] {
+			logger.Info("updating ArgoCD application", "name", name)
+			reconcileErr = r.updateComponentApplication(ctx, ephApp, component, name, namespace)
+		} else {
+			logger.Info("creating ArgoCD application", "name", name)
+			reconcileErr = r.createComponentApplication(ctx, ephApp, component, name, namespace)
+			if reconcileErr == nil {
+				live[name] = true
+			}
+		}
+		if reconcileErr != nil {
+			break
+		}
+	}
+
+	// Delete applications of removed components
+	if reconcileErr == nil {
+		for _, name := range existing {
+			if slices.Contains(desired, name) {
+				continue
+			}
+			logger.Info("deleting ArgoCD application of removed component", "name", name)
+			err := r.ArgoClient.DeleteApplication(ctx, name, r.Config.ArgoNamespace)
+			// ArgoCD answers PermissionDenied instead of NotFound for applications that do not exist
+			if err != nil && !argocd.IsNotFound(err) && !argocd.IsPermissionDenied(err) {
+				reconcileErr = fmt.Errorf("failed to delete ArgoCD application %s: %w", name, err)
+				break
+			}
+			delete(live, name)
+		}
+	}
+
+	r.recordComponentApplications(ephApp, append(desired, existing...), live, componentNames)
+	return reconcileErr
+}
+
+// createComponentApplication creates the ArgoCD Application for a component
+func (r *EphemeralApplicationReconciler) createComponentApplication(
+	ctx context.Context,
+	ephApp *ephemeralv1alpha1.EphemeralApplication,
+	component ephemeralv1alpha1.Component,
+	name, namespace string,
+) error {
+	_, err := r.ArgoClient.CreateApplication(ctx, &application.ApplicationCreateRequest{
+		Application: &v1alpha1.Application{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   name,
+				Labels: applicationLabels(ephApp),
+			},
+			Spec: r.buildApplicationSpec(ephApp, component, namespace),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create ArgoCD application %s: %w", name, err)
+	}
+	return nil
+}
+
+// updateComponentApplication replaces the spec of the live ArgoCD Application of a component
+func (r *EphemeralApplicationReconciler) updateComponentApplication(
+	ctx context.Context,
+	ephApp *ephemeralv1alpha1.EphemeralApplication,
+	component ephemeralv1alpha1.Component,
+	name, namespace string,
+) error {
+	// Fetch the live application so metadata set by ArgoCD is preserved
+	appQuery := application.ApplicationQuery{
+		Name:         &name,
+		AppNamespace: &r.Config.ArgoNamespace,
+	}
+	argoApp, err := r.ArgoClient.GetApplication(ctx, appQuery)
+	if err != nil {
+		return fmt.Errorf("failed to get ArgoCD application %s: %w", name, err)
+	}
+
+	argoApp.Spec = r.buildApplicationSpec(ephApp, component, namespace)
+	// Label applications created before they were watched
+	if argoApp.Labels == nil {
+		argoApp.Labels = make(map[string]string)
+	}
+	for key, value := range applicationLabels(ephApp) {
+		argoApp.Labels[key] = value
+	}
+	if _, err := r.ArgoClient.UpdateApplication(ctx, &application.ApplicationUpdateRequest{
+		Application: argoApp,
+	}); err != nil {
+		return fmt.Errorf("failed to update ArgoCD application %s: %w", name, err)
+	}
+	return nil
+}
+
+// recordComponentApplications stores the live ArgoCD Applications in the status
+// The application of the single-source spec is kept in ArgoApplicationName, component
+// applications in Components, in spec order followed by the ones still pending deletion
+func (r *EphemeralApplicationReconciler) recordComponentApplications(
+	ephApp *ephemeralv1alpha1.EphemeralApplication,
+	names []string,
+	live map[string]bool,
+	componentNames map[string]string,
+) {
+	previous := make(map[string]ephemeralv1alpha1.ComponentStatus, len(ephApp.Status.Components))
+	for _, status := range ephApp.Status.Components {
+		previous[status.ArgoApplicationName] = status
+	}
+
+	ephApp.Status.ArgoApplicationName = ""
+	var statuses []ephemeralv1alpha1.ComponentStatus
+	recorded := make(map[string]bool, len(names))
+	for _, name := range names {
+		if !live[name] || recorded[name] {
+			continue
+		}
+		recorded[name] = true
+
+		if componentNames[name] == "" {
+			ephApp.Status.ArgoApplicationName = name
+			continue
+		}
+
+		status := previous[name]
+		status.Name = componentNames[name]
+		status.ArgoApplicationName = name
+		statuses = append(statuses, status)
+	}
+	ephApp.Status.Components = statuses
+}
+
+// refreshComponentStatus fetches every ArgoCD Application of the environment and records
+// its sync and health status, it reports whether all of them are synced and healthy
+func (r *EphemeralApplicationReconciler) refreshComponentStatus(ctx context.Context, ephApp *ephemeralv1alpha1.EphemeralApplication) (synced, healthy bool, err error) {
+	names := argoApplicationNames(ephApp)
+	if len(names) == 0 {
+		return false, false, nil
+	}
+
+	synced, healthy = true, true
+	for _, name := range names {
+		syncStatus, healthStatus, err := r.getApplicationStatus(ctx, name)
+		if err != nil {
+			return false, false, err
+		}
+
+		synced = synced && syncStatus == "Synced"
+		healthy = healthy && healthStatus == "Healthy"
+
+		for i := range ephApp.Status.Components {
+			if ephApp.Status.Components[i].ArgoApplicationName == name {
+				ephApp.Status.Components[i].SyncStatus = syncStatus
+				ephApp.Status.Components[i].HealthStatus = healthStatus
+			}
+		}
+	}
+
+	return synced, healthy, nil
+}
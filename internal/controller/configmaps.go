@@ -3,10 +3,12 @@ package controller
 import (
 	"context"
 	"fmt"
+	"maps"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
@@ -14,46 +16,60 @@ import (
 )
 
 // copyConfigMaps copies configmaps from source namespaces or creates them inline
+// Source configmaps are read from the local cluster and written with the target client,
+// like secrets a configmap that fails does not prevent the others from being copied
 func (r *EphemeralApplicationReconciler) copyConfigMaps(
 	ctx context.Context,
+	target client.Client,
 	ephApp *ephemeralv1alpha1.EphemeralApplication,
 	targetNamespace string,
 ) error {
 	logger := log.FromContext(ctx)
 
+	names := make(map[string]bool, len(ephApp.Spec.ConfigMaps))
+	for _, cmRef := range ephApp.Spec.ConfigMaps {
+		names[configMapTargetName(cmRef)] = true
+	}
+	pruneInjectedStatus(ephApp, configMapKind, names)
+
 	if len(ephApp.Spec.ConfigMaps) == 0 {
 		return nil
 	}
 
 	logger.Info("copying configmaps to ephemeral namespace", "count", len(ephApp.Spec.ConfigMaps))
 
+	var errs []error
 	for _, cmRef := range ephApp.Spec.ConfigMaps {
-		if err := r.copyConfigMap(ctx, cmRef, targetNamespace, ephApp); err != nil {
-			return fmt.Errorf("failed to copy configmap %s: %w", cmRef.Name, err)
+		if err := r.copyConfigMap(ctx, target, cmRef, targetNamespace, ephApp); err != nil {
+			recordInjectionError(ephApp, configMapStatus(cmRef), err)
+			errs = append(errs, fmt.Errorf("failed to copy configmap %s: %w", configMapTargetName(cmRef), err))
 		}
 	}
 
-	return nil
+	return utilerrors.NewAggregate(errs)
 }
 
 // copyConfigMap copies a single configmap from source or creates from inline data
+// Inline data and rendered templates are merged over the data of the source
 func (r *EphemeralApplicationReconciler) copyConfigMap(
 	ctx context.Context,
+	target client.Client,
 	cmRef ephemeralv1alpha1.ConfigMapReference,
 	targetNamespace string,
 	ephApp *ephemeralv1alpha1.EphemeralApplication,
 ) error {
 	logger := log.FromContext(ctx)
 
-	var cmData map[string]string
+	targetName := configMapTargetName(cmRef)
+	cmData := make(map[string]string)
+	var binaryData map[string][]byte
+	var sourceVersion string
 
 	// Check if creating from inline data or copying from source
-	if len(cmRef.Data) > 0 {
-		// Use inline data
+	if cmRef.SourceNamespace == "" {
 		logger.Info("creating configmap from inline data",
-			"name", cmRef.Name,
+			"name", targetName,
 			"targetNamespace", targetNamespace)
-		cmData = cmRef.Data
 	} else {
 		// Copy from source namespace
 		sourceCM := &corev1.ConfigMap{}
@@ -68,10 +84,20 @@ func (r *EphemeralApplicationReconciler) copyConfigMap(
 		logger.Info("copying configmap",
 			"sourceNamespace", cmRef.SourceNamespace,
 			"sourceName", cmRef.Name,
-			"targetNamespace", targetNamespace)
+			"targetNamespace", targetNamespace,
+			"targetName", targetName)
 
-		cmData = sourceCM.Data
+		maps.Copy(cmData, sourceCM.Data)
+		binaryData = sourceCM.BinaryData
+		sourceVersion = sourceCM.ResourceVersion
 	}
+	maps.Copy(cmData, cmRef.Data)
+
+	rendered, err := renderTemplates(cmRef.Templates, newTemplateData(ephApp, targetNamespace, maps.Clone(cmData)))
+	if err != nil {
+		return fmt.Errorf("failed to render configmap %s: %w", targetName, err)
+	}
+	maps.Copy(cmData, rendered)
 
 	// Prepare labels
 	labels := map[string]string{
@@ -82,7 +108,7 @@ func (r *EphemeralApplicationReconciler) copyConfigMap(
 	annotations := map[string]string{}
 
 	// Add different labels for inline vs copied
-	if len(cmRef.Data) > 0 {
+	if cmRef.SourceNamespace == "" {
 		labels["ephemeral.argo.io/inline"] = "true"
 	} else {
 		labels["ephemeral.argo.io/copied-from"] = cmRef.SourceNamespace
@@ -94,56 +120,53 @@ func (r *EphemeralApplicationReconciler) copyConfigMap(
 	// Create the target configmap
 	targetCM := &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:        cmRef.Name,
+			Name:        targetName,
 			Namespace:   targetNamespace,
 			Labels:      labels,
 			Annotations: annotations,
 		},
-		Data: cmData,
+		Data:       cmData,
+		BinaryData: binaryData,
 	}
 
 	// Create or update
-	err := r.Create(ctx, targetCM)
-	if err != nil {
+	if err := target.Create(ctx, targetCM); err != nil {
 		if errors.IsAlreadyExists(err) {
 			// Update if already exists
-			logger.Info("configmap already exists, updating", "name", cmRef.Name)
+			logger.Info("configmap already exists, updating", "name", targetName)
 			existingCM := &corev1.ConfigMap{}
-			if err := r.Get(ctx, client.ObjectKey{
+			if err := target.Get(ctx, client.ObjectKey{
 				Namespace: targetNamespace,
-				Name:      cmRef.Name,
+				Name:      targetName,
 			}, existingCM); err != nil {
 				return err
 			}
 
 			existingCM.Data = cmData
+			existingCM.BinaryData = binaryData
 
-			if err := r.Update(ctx, existingCM); err != nil {
+			if err := target.Update(ctx, existingCM); err != nil {
 				return fmt.Errorf("failed to update configmap: %w", err)
 			}
-
-			return nil
+		} else {
+			return fmt.Errorf("failed to create configmap: %w", err)
 		}
-		return fmt.Errorf("failed to create configmap: %w", err)
 	}
 
+	// Data and binaryData keys are unique within a configmap so they are hashed together
+	hashed := make(map[string][]byte, len(cmData)+len(binaryData))
+	for key, value := range cmData {
+		hashed[key] = []byte(value)
+	}
+	maps.Copy(hashed, binaryData)
+	recordInjected(ephApp, synced(configMapStatus(cmRef), hashed, sourceVersion))
 	return nil
 }
 
-// buildCopiedConfigMapsList creates a human-readable list of copied configmaps
-func (r *EphemeralApplicationReconciler) buildCopiedConfigMapsList(configMaps []ephemeralv1alpha1.ConfigMapReference) []string {
-	if len(configMaps) == 0 {
-		return nil
+// configMapTargetName returns the name of the copy of a configmap in the ephemeral namespace
+func configMapTargetName(cmRef ephemeralv1alpha1.ConfigMapReference) string {
+	if cmRef.TargetName != "" {
+		return cmRef.TargetName
 	}
-
-	copiedList := make([]string, 0, len(configMaps))
-	for _, cm := range configMaps {
-		if len(cm.Data) > 0 {
-			copiedList = append(copiedList, fmt.Sprintf("%s (inline)", cm.Name))
-		} else {
-			copiedList = append(copiedList, fmt.Sprintf("%s/%s", cm.SourceNamespace, cm.Name))
-		}
-	}
-
-	return copiedList
+	return cmRef.Name
 }
@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	ephemeralv1alpha1 "github.com/jbarea/argo-ephemeral-operator/api/v1alpha1"
+)
+
+func TestCopyConfigMap_MergeOverSource(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = ephemeralv1alpha1.AddToScheme(scheme)
+
+	sourceCM := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-config", Namespace: "shared-config"},
+		Data: map[string]string{
+			"LOG_LEVEL": "info",
+			"REGION":    "eu-west-1",
+		},
+		BinaryData: map[string][]byte{"logo.png": {0x89, 0x50, 0x4e, 0x47}},
+	}
+
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sourceCM).Build()
+	reconciler := &EphemeralApplicationReconciler{Client: fakeClient, Scheme: scheme}
+
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "pr-42", Namespace: "default"},
+		Status:     ephemeralv1alpha1.EphemeralApplicationStatus{PreviewURL: "https://ephemeral-test.preview.example.com"},
+	}
+	cmRef := ephemeralv1alpha1.ConfigMapReference{
+		Name:            "app-config",
+		SourceNamespace: "shared-config",
+		TargetName:      "settings",
+		Data:            map[string]string{"LOG_LEVEL": "debug"},
+		Templates: map[string]string{
+			"PUBLIC_URL": "{{ .PreviewURL }}",
+			"SUMMARY":    "{{ .Name }} logs at {{ .Data.LOG_LEVEL }}",
+		},
+	}
+
+	ctx := context.Background()
+	if err := reconciler.copyConfigMap(ctx, fakeClient, cmRef, "ephemeral-test", ephApp); err != nil {
+		t.Fatalf("copyConfigMap failed: %v", err)
+	}
+
+	copied := &corev1.ConfigMap{}
+	if err := fakeClient.Get(ctx, client.ObjectKey{Namespace: "ephemeral-test", Name: "settings"}, copied); err != nil {
+		t.Fatalf("failed to get copied configmap: %v", err)
+	}
+
+	expected := map[string]string{
+		"LOG_LEVEL":  "debug",
+		"REGION":     "eu-west-1",
+		"PUBLIC_URL": "https://ephemeral-test.preview.example.com",
+		"SUMMARY":    "pr-42 logs at debug",
+	}
+	if len(copied.Data) != len(expected) {
+		t.Errorf("expected data %v, got %v", expected, copied.Data)
+	}
+	for key, value := range expected {
+		if copied.Data[key] != value {
+			t.Errorf("expected %s to be %q, got %q", key, value, copied.Data[key])
+		}
+	}
+	if len(copied.BinaryData["logo.png"]) != 4 {
+		t.Errorf("expected binaryData to be copied, got %v", copied.BinaryData)
+	}
+	if copied.Labels["ephemeral.argo.io/source-name"] != "app-config" {
+		t.Errorf("expected source-name label, got %v", copied.Labels)
+	}
+
+	// The source version is recorded under the target name
+	if injected := findInjected(ephApp, configMapKind, "settings"); injected == nil || injected.LastSyncedResourceVersion == "" {
+		t.Errorf("expected the source version to be recorded, got %v", ephApp.Status.InjectedResources)
+	}
+}
+
+func TestCopyConfigMap_Inline(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = ephemeralv1alpha1.AddToScheme(scheme)
+
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+	reconciler := &EphemeralApplicationReconciler{Client: fakeClient, Scheme: scheme}
+
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-app", Namespace: "default"},
+	}
+	cmRef := ephemeralv1alpha1.ConfigMapReference{
+		Name: "app-config",
+		Data: map[string]string{"LOG_LEVEL": "debug"},
+	}
+
+	ctx := context.Background()
+	if err := reconciler.copyConfigMap(ctx, fakeClient, cmRef, "ephemeral-test", ephApp); err != nil {
+		t.Fatalf("copyConfigMap failed: %v", err)
+	}
+
+	copied := &corev1.ConfigMap{}
+	if err := fakeClient.Get(ctx, client.ObjectKey{Namespace: "ephemeral-test", Name: "app-config"}, copied); err != nil {
+		t.Fatalf("failed to get configmap: %v", err)
+	}
+	if copied.Data["LOG_LEVEL"] != "debug" {
+		t.Errorf("expected inline data, got %v", copied.Data)
+	}
+	if copied.Labels["ephemeral.argo.io/inline"] != "true" {
+		t.Errorf("expected inline label, got %v", copied.Labels)
+	}
+	injected := findInjected(ephApp, configMapKind, "app-config")
+	if injected == nil || injected.SourceNamespace != "" || injected.LastSyncedResourceVersion != "" {
+		t.Errorf("expected inline configmaps to be recorded without a source, got %v", ephApp.Status.InjectedResources)
+	}
+}
@@ -0,0 +1,29 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	ephemeralv1alpha1 "github.com/jbarea/argo-ephemeral-operator/api/v1alpha1"
+	"github.com/jbarea/argo-ephemeral-operator/internal/cluster"
+)
+
+// targetCluster returns the cluster the namespace and the injected resources of an environment
+// live in, reconcilers without a cluster cache only deploy to their own cluster
+func (r *EphemeralApplicationReconciler) targetCluster(ctx context.Context, ephApp *ephemeralv1alpha1.EphemeralApplication) (cluster.Target, error) {
+	if r.Clusters != nil {
+		return r.Clusters.Get(ctx, ephApp.Spec.Destination, ephApp.Namespace)
+	}
+	if ephApp.Spec.Destination != nil {
+		return cluster.Target{}, fmt.Errorf("remote destinations are not supported by this reconciler")
+	}
+	return cluster.Target{Client: r.Client, Server: cluster.InClusterServer}, nil
+}
+
+// destinationServer returns the ArgoCD destination server of the applications of an environment
+func destinationServer(ephApp *ephemeralv1alpha1.EphemeralApplication) string {
+	if ephApp.Status.DestinationServer == "" {
+		return cluster.InClusterServer
+	}
+	return ephApp.Status.DestinationServer
+}
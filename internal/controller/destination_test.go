@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	ephemeralv1alpha1 "github.com/jbarea/argo-ephemeral-operator/api/v1alpha1"
+	"github.com/jbarea/argo-ephemeral-operator/internal/cluster"
+	"github.com/jbarea/argo-ephemeral-operator/internal/config"
+	"github.com/jbarea/argo-ephemeral-operator/internal/naming"
+)
+
+func TestReconcile_DeploysToRemoteCluster(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = networkingv1.AddToScheme(scheme)
+	_ = ephemeralv1alpha1.AddToScheme(scheme)
+
+	clusterSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cluster-staging",
+			Namespace: "argocd",
+			Labels:    map[string]string{"argocd.argoproj.io/secret-type": "cluster"},
+		},
+		Data: map[string][]byte{
+			"name":   []byte("staging"),
+			"server": []byte("https://staging.example.com"),
+			"config": []byte(`{"bearerToken":"token"}`),
+		},
+	}
+	sharedSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "shared"},
+		Data:       map[string][]byte{"password": []byte("secret")},
+	}
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "preview",
+			Namespace:  "default",
+			Finalizers: []string{finalizerName},
+		},
+		Spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+			RepoURL:     "https://github.com/example/app.git",
+			Path:        "manifests",
+			TTL:         &metav1.Duration{Duration: time.Hour},
+			Destination: &ephemeralv1alpha1.Destination{Name: "staging"},
+			Secrets:     []ephemeralv1alpha1.SecretReference{{Name: "db", SourceNamespace: "shared"}},
+		},
+	}
+
+	localClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(clusterSecret, sharedSecret, ephApp).
+		WithStatusSubresource(ephApp).
+		Build()
+	remoteClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+	clusters := cluster.NewCache(localClient, scheme, "argocd")
+	clusters.NewClient = func(*rest.Config) (client.Client, error) {
+		return remoteClient, nil
+	}
+
+	argoClient := newMockArgoClient()
+	reconciler := &EphemeralApplicationReconciler{
+		Client:        localClient,
+		Scheme:        scheme,
+		ArgoClient:    argoClient,
+		Config:        &config.Config{ArgoNamespace: "argocd", ReconcileInterval: time.Minute},
+		NameGenerator: naming.NewDefaultGenerator(),
+		Clusters:      clusters,
+	}
+
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ephApp)}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	updated := &ephemeralv1alpha1.EphemeralApplication{}
+	if err := localClient.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("failed to get EphemeralApplication: %v", err)
+	}
+	namespace := updated.Status.Namespace
+	if updated.Status.DestinationServer != "https://staging.example.com" {
+		t.Errorf("expected the staging server in status, got %q", updated.Status.DestinationServer)
+	}
+
+	if err := remoteClient.Get(ctx, client.ObjectKey{Name: namespace}, &corev1.Namespace{}); err != nil {
+		t.Errorf("expected namespace %s on the remote cluster: %v", namespace, err)
+	}
+	if err := localClient.Get(ctx, client.ObjectKey{Name: namespace}, &corev1.Namespace{}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected no namespace %s on the local cluster, got %v", namespace, err)
+	}
+	if err := remoteClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: "db"}, &corev1.Secret{}); err != nil {
+		t.Errorf("expected the secret to be copied to the remote cluster: %v", err)
+	}
+	if got := argoClient.apps["preview"].Spec.Destination.Server; got != "https://staging.example.com" {
+		t.Errorf("expected the ArgoCD application to target the staging server, got %s", got)
+	}
+
+	if err := localClient.Delete(ctx, updated); err != nil {
+		t.Fatalf("failed to delete EphemeralApplication: %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if err := remoteClient.Get(ctx, client.ObjectKey{Name: namespace}, &corev1.Namespace{}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected namespace %s to be deleted from the remote cluster, got %v", namespace, err)
+	}
+}
@@ -3,23 +3,29 @@ package controller
 import (
 	"context"
 	"fmt"
-	"strings"
 	"time"
 
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
-	"github.com/argoproj/argo-cd/v2/pkg/apiclient/application"
 	"github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
 	ephemeralv1alpha1 "github.com/jbarea/argo-ephemeral-operator/api/v1alpha1"
 	"github.com/jbarea/argo-ephemeral-operator/internal/argocd"
+	"github.com/jbarea/argo-ephemeral-operator/internal/cluster"
 	"github.com/jbarea/argo-ephemeral-operator/internal/config"
+	"github.com/jbarea/argo-ephemeral-operator/internal/naming"
+	"github.com/jbarea/argo-ephemeral-operator/internal/policy"
 )
 
 const (
@@ -32,19 +38,24 @@ type EphemeralApplicationReconciler struct {
 	Scheme        *runtime.Scheme
 	ArgoClient    argocd.Client
 	Config        *config.Config
-	NameGenerator NameGenerator
-}
-
-// NameGenerator generates unique namespace names
-type NameGenerator interface {
-	GenerateNamespace(prefix, suffix string) string
+	NameGenerator naming.Generator
+	// Clusters resolves the destination of the environments, only the local cluster is used when nil
+	Clusters *cluster.Cache
 }
 
 // +kubebuilder:rbac:groups=ephemeral.argo.io,resources=ephemeralapplications,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=ephemeral.argo.io,resources=ephemeralapplications/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=ephemeral.argo.io,resources=ephemeralapplications/finalizers,verbs=update
+// +kubebuilder:rbac:groups=ephemeral.argo.io,resources=ephemeralapplicationtemplates,verbs=get;list;watch
+// +kubebuilder:rbac:groups=ephemeral.argo.io,resources=ephemeralpolicies,verbs=get;list;watch
 // +kubebuilder:rbac:groups=argoproj.io,resources=applications,verbs=get;list;watch;create;update;patch;delete
-// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch;create;delete
+// +kubebuilder:rbac:groups=argoproj.io,resources=appprojects,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=secrets;configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=resourcequotas;limitranges,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=apps,resources=deployments;statefulsets,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create
 
 // Reconcile is the main reconciliation loop
 func (r *EphemeralApplicationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -73,16 +84,66 @@ func (r *EphemeralApplicationReconciler) Reconcile(ctx context.Context, req ctrl
 		}
 	}
 
+	// Surface whether calls to ArgoCD are currently suspended
+	if err := r.reconcileArgoAvailability(ctx, ephApp); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	policies, err := policy.List(ctx, r.Client)
+	if err != nil {
+		logger.Error(err, "unable to list ephemeral policies")
+		return ctrl.Result{}, err
+	}
+
+	// Record the effective expiry computed from expirationDate or ttl
+	if err := r.recordExpiry(ctx, ephApp, policies); err != nil {
+		return ctrl.Result{}, err
+	}
+
 	// Check if expired
 	if r.isExpired(ephApp) {
 		return r.handleExpiration(ctx, ephApp)
 	}
 
+	// Merge the referenced template, the merged spec is never written back
+	templateGeneration, err := r.resolveTemplate(ctx, ephApp)
+	if err != nil {
+		logger.Error(err, "failed to resolve template")
+		phase := ephApp.Status.Phase
+		if phase == "" {
+			phase = ephemeralv1alpha1.PhasePending
+		}
+		return r.updateStatusWithError(ctx, ephApp, phase, "Failed to resolve template", err)
+	}
+
+	// Propagate spec and template changes to already created ArgoCD Applications
+	if r.hasSpecChanged(ephApp, templateGeneration) {
+		return r.handleSpecChange(ctx, ephApp, policies, templateGeneration)
+	}
+
+	// Retry the secrets and configmaps that failed to be injected and copy again the
+	// continuously synced ones whose source changed
+	switch ephApp.Status.Phase {
+	case ephemeralv1alpha1.PhaseCreating, ephemeralv1alpha1.PhaseUpdating,
+		ephemeralv1alpha1.PhaseActive, ephemeralv1alpha1.PhaseHibernating:
+		if err := r.syncInjectedResources(ctx, ephApp); err != nil {
+			logger.Error(err, "failed to sync secrets and configmaps")
+		}
+	}
+
+	// Scale the workloads down or up following the hibernation schedule
+	switch ephApp.Status.Phase {
+	case ephemeralv1alpha1.PhaseActive, ephemeralv1alpha1.PhaseHibernating:
+		if result, handled, err := r.reconcileHibernation(ctx, ephApp); handled || err != nil {
+			return result, err
+		}
+	}
+
 	// Handle based on current phase
 	switch ephApp.Status.Phase {
 	case "", ephemeralv1alpha1.PhasePending:
-		return r.handlePendingPhase(ctx, ephApp)
-	case ephemeralv1alpha1.PhaseCreating:
+		return r.handlePendingPhase(ctx, ephApp, policies, templateGeneration)
+	case ephemeralv1alpha1.PhaseCreating, ephemeralv1alpha1.PhaseUpdating:
 		return r.handleCreatingPhase(ctx, ephApp)
 	case ephemeralv1alpha1.PhaseActive:
 		return r.handleActivePhase(ctx, ephApp)
@@ -94,130 +155,267 @@ func (r *EphemeralApplicationReconciler) Reconcile(ctx context.Context, req ctrl
 }
 
 // handlePendingPhase handles the pending phase
-func (r *EphemeralApplicationReconciler) handlePendingPhase(ctx context.Context, ephApp *ephemeralv1alpha1.EphemeralApplication) (ctrl.Result, error) {
+func (r *EphemeralApplicationReconciler) handlePendingPhase(
+	ctx context.Context,
+	ephApp *ephemeralv1alpha1.EphemeralApplication,
+	policies policy.Policies,
+	templateGeneration int64,
+) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 	logger.Info("handling pending phase")
 
-	// Generate namespace name
-	namespace := r.NameGenerator.GenerateNamespace(ephApp.Spec.NamespaceName, "")
-
-	// Create namespace
-	ns := &corev1.Namespace{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: namespace,
-			Labels: map[string]string{
-				"app.kubernetes.io/managed-by": "argo-ephemeral-operator",
-				"ephemeral.argo.io/owner":      ephApp.Name,
-			},
-		},
+	if err := ephApp.Spec.ValidateSource(ephApp.Name); err != nil {
+		return r.updateStatusWithError(ctx, ephApp, ephemeralv1alpha1.PhaseFailed, "Invalid application source", err)
+	}
+	if err := ephApp.Spec.ValidateResources(); err != nil {
+		return r.updateStatusWithError(ctx, ephApp, ephemeralv1alpha1.PhaseFailed, "Invalid secrets or configmaps", err)
+	}
+	if err := ephApp.Spec.ValidateHibernation(); err != nil {
+		return r.updateStatusWithError(ctx, ephApp, ephemeralv1alpha1.PhaseFailed, "Invalid hibernation schedule", err)
+	}
+	if err := ephApp.Spec.ValidateDestination(); err != nil {
+		return r.updateStatusWithError(ctx, ephApp, ephemeralv1alpha1.PhaseFailed, "Invalid destination", err)
+	}
+	if err := ephApp.Spec.ValidateHooks(); err != nil {
+		return r.updateStatusWithError(ctx, ephApp, ephemeralv1alpha1.PhaseFailed, "Invalid hooks", err)
+	}
+	if err := ephApp.Spec.ValidateExpiration(); err != nil {
+		return r.updateStatusWithError(ctx, ephApp, ephemeralv1alpha1.PhaseFailed, "Invalid expiration", err)
+	}
+	if err := policies.CheckSpec(ephApp); err != nil {
+		return r.updateStatusWithError(ctx, ephApp, ephemeralv1alpha1.PhaseFailed, "Policy violation", err)
 	}
 
-	if err := r.Create(ctx, ns); err != nil && !errors.IsAlreadyExists(err) {
+	// Wait until an environment is deleted when the quota is exhausted, the quota is not checked
+	// again while the environment runs its preSync hooks since it already holds its namespace
+	user := ephApp.Annotations[ephemeralv1alpha1.CreatedByAnnotation]
+	if ephApp.Status.Namespace == "" {
+		if err := policies.CheckQuota(ctx, r.Client, ephApp, user, false); err != nil {
+			logger.Info("quota exceeded, waiting", "reason", err.Error())
+			ephApp.Status.Phase = ephemeralv1alpha1.PhasePending
+			ephApp.Status.Message = fmt.Sprintf("Waiting for quota: %v", err)
+			r.setCondition(ephApp, "Ready", metav1.ConditionFalse, "QuotaExceeded", err.Error())
+			if err := r.updateStatus(ctx, ephApp); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{RequeueAfter: time.Minute}, nil
+		}
+	}
+
+	// Wait in pending instead of failing while ArgoCD is unavailable
+	if err := r.argoUnavailable(); err != nil {
+		logger.Info("ArgoCD is unavailable, waiting", "reason", err.Error())
+		return ctrl.Result{RequeueAfter: pollInterval}, nil
+	}
+
+	// Resolve the cluster the environment is deployed to
+	target, err := r.targetCluster(ctx, ephApp)
+	if err != nil {
+		logger.Error(err, "failed to resolve destination cluster")
+		return r.updateStatusWithError(ctx, ephApp, ephemeralv1alpha1.PhaseFailed, "Failed to resolve destination cluster", err)
+	}
+	ephApp.Status.DestinationServer = target.Server
+
+	// Generate namespace name, unless it was chosen before waiting for the preSync hooks
+	namespace := ephApp.Status.Namespace
+	if namespace == "" {
+		namespace = r.NameGenerator.GenerateNamespace(ephApp.Spec.NamespaceName, "")
+	}
+	ephApp.Status.PreviewURL = r.previewURL(namespace)
+
+	// Create namespace with its quota, limits and network isolation
+	if err := r.reconcileNamespace(ctx, target.Client, ephApp, namespace, policies.NamespaceOptions(ephApp.Spec.Namespace)); err != nil {
 		logger.Error(err, "failed to create namespace")
 		return r.updateStatusWithError(ctx, ephApp, ephemeralv1alpha1.PhaseFailed, "Failed to create namespace", err)
 	}
 
-	// Copy secrets to the ephemeral namespace
-	if err := r.copySecrets(ctx, ephApp, namespace); err != nil {
+	// Copy secrets and configmaps to the ephemeral namespace, the ones that fail are reported
+	// in status.injectedResources and retried without failing the environment
+	if err := r.copySecrets(ctx, target.Client, ephApp, namespace); err != nil {
 		logger.Error(err, "failed to copy secrets")
-		return r.updateStatusWithError(ctx, ephApp, ephemeralv1alpha1.PhaseFailed, "Failed to copy secrets", err)
 	}
-
-	// Copy configmaps to the ephemeral namespace
-	if err := r.copyConfigMaps(ctx, ephApp, namespace); err != nil {
+	if err := r.copyConfigMaps(ctx, target.Client, ephApp, namespace); err != nil {
 		logger.Error(err, "failed to copy configmaps")
-		return r.updateStatusWithError(ctx, ephApp, ephemeralv1alpha1.PhaseFailed, "Failed to copy configmaps", err)
-	}
-
-	// Build ignore differences for injected resources
-	ignoreDiffs := argocd.BuildIgnoreDifferences(ephApp)
-
-	// Build and create ArgoCD Application
-	argoApp, err := r.ArgoClient.CreateApplication(ctx, &application.ApplicationCreateRequest{
-		Application: &v1alpha1.Application{
-			ObjectMeta: metav1.ObjectMeta{
-				Name: ephApp.Name,
-			},
-			Spec: v1alpha1.ApplicationSpec{
-				Project: "default",
-				Source: &v1alpha1.ApplicationSource{
-					RepoURL:        ephApp.Spec.RepoURL,
-					Path:           ephApp.Spec.Path,
-					TargetRevision: ephApp.Spec.TargetRevision,
-				},
-				Destination: v1alpha1.ApplicationDestination{
-					Namespace: namespace,
-					Server:    "https://kubernetes.default.svc",
-				},
-				SyncPolicy: &v1alpha1.SyncPolicy{
-					Automated: &v1alpha1.SyncPolicyAutomated{
-						Prune:    true,
-						SelfHeal: true,
-					},
-					SyncOptions: v1alpha1.SyncOptions{
-						"RespectIgnoreDifferences=true",
-					},
-				},
-				IgnoreDifferences: ignoreDiffs,
-			},
-		},
-	})
+	}
+	r.setInjectionCondition(ephApp)
 
-	if err != nil {
+	// Restrict the applications to the ephemeral namespace with a dedicated project
+	if err := r.reconcileAppProject(ctx, ephApp, namespace, policies); err != nil {
+		logger.Error(err, "failed to create ArgoCD project")
+		ephApp.Status.Namespace = namespace
+		return r.updateStatusWithError(ctx, ephApp, ephemeralv1alpha1.PhaseFailed, "Failed to create ArgoCD project", err)
+	}
+
+	// Run the preSync hooks before ArgoCD deploys anything, the environment stays pending until they succeed
+	ephApp.Status.Phase = ephemeralv1alpha1.PhasePending
+	ephApp.Status.Namespace = namespace
+	if result, waiting, err := r.reconcileHooks(ctx, ephApp, ephemeralv1alpha1.HookPreSync); waiting || err != nil {
+		return result, err
+	}
+
+	// Build and create one ArgoCD Application per component
+	if err := r.reconcileComponentApplications(ctx, ephApp, namespace); err != nil {
 		logger.Error(err, "failed to create ArgoCD application")
+		ephApp.Status.Namespace = namespace
 		return r.updateStatusWithError(ctx, ephApp, ephemeralv1alpha1.PhaseFailed, "Failed to create ArgoCD application", err)
 	}
 
 	ephApp.Status.Phase = ephemeralv1alpha1.PhaseCreating
+	ephApp.Status.ObservedGeneration = ephApp.Generation
+	ephApp.Status.TemplateGeneration = templateGeneration
 	ephApp.Status.Namespace = namespace
-	ephApp.Status.ArgoApplicationName = argoApp.Name
 	ephApp.Status.Message = "ArgoCD application created successfully"
-	ephApp.Status.CopiedSecrets = r.buildCopiedSecretsList(ephApp.Spec.Secrets)
-	ephApp.Status.CopiedConfigMaps = r.buildCopiedConfigMapsList(ephApp.Spec.ConfigMaps)
 	r.setCondition(ephApp, "Ready", metav1.ConditionFalse, "Creating", "Creating ephemeral environment")
 
-	if err := r.Status().Update(ctx, ephApp); err != nil {
+	if err := r.updateStatus(ctx, ephApp); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: r.syncRequeueInterval()}, nil
+}
+
+// handleSpecChange updates the ArgoCD Application after the EphemeralApplication spec or its template has changed
+func (r *EphemeralApplicationReconciler) handleSpecChange(
+	ctx context.Context,
+	ephApp *ephemeralv1alpha1.EphemeralApplication,
+	policies policy.Policies,
+	templateGeneration int64,
+) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	logger.Info("spec changed, updating ArgoCD application",
+		"generation", ephApp.Generation,
+		"observedGeneration", ephApp.Status.ObservedGeneration)
+
+	// Record the generations even if the rollout fails, a new edit will trigger another attempt
+	ephApp.Status.ObservedGeneration = ephApp.Generation
+	ephApp.Status.TemplateGeneration = templateGeneration
+
+	if err := ephApp.Spec.ValidateSource(ephApp.Name); err != nil {
+		return r.updateStatusWithError(ctx, ephApp, ephemeralv1alpha1.PhaseFailed, "Invalid application source", err)
+	}
+	if err := ephApp.Spec.ValidateResources(); err != nil {
+		return r.updateStatusWithError(ctx, ephApp, ephemeralv1alpha1.PhaseFailed, "Invalid secrets or configmaps", err)
+	}
+	if err := ephApp.Spec.ValidateHibernation(); err != nil {
+		return r.updateStatusWithError(ctx, ephApp, ephemeralv1alpha1.PhaseFailed, "Invalid hibernation schedule", err)
+	}
+	if err := ephApp.Spec.ValidateDestination(); err != nil {
+		return r.updateStatusWithError(ctx, ephApp, ephemeralv1alpha1.PhaseFailed, "Invalid destination", err)
+	}
+	if err := ephApp.Spec.ValidateHooks(); err != nil {
+		return r.updateStatusWithError(ctx, ephApp, ephemeralv1alpha1.PhaseFailed, "Invalid hooks", err)
+	}
+	if err := policies.CheckSpec(ephApp); err != nil {
+		return r.updateStatusWithError(ctx, ephApp, ephemeralv1alpha1.PhaseFailed, "Policy violation", err)
+	}
+
+	namespace := ephApp.Status.Namespace
+	target, err := r.targetCluster(ctx, ephApp)
+	if err != nil {
+		logger.Error(err, "failed to resolve destination cluster")
+		return r.updateStatusWithError(ctx, ephApp, ephemeralv1alpha1.PhaseFailed, "Failed to resolve destination cluster", err)
+	}
+
+	// Apply the namespace options, they may have changed
+	if err := r.reconcileNamespace(ctx, target.Client, ephApp, namespace, policies.NamespaceOptions(ephApp.Spec.Namespace)); err != nil {
+		logger.Error(err, "failed to update namespace")
+		return r.updateStatusWithError(ctx, ephApp, ephemeralv1alpha1.PhaseFailed, "Failed to update namespace", err)
+	}
+
+	// Refresh injected resources, the list of secrets or configmaps may have changed
+	if err := r.copySecrets(ctx, target.Client, ephApp, namespace); err != nil {
+		logger.Error(err, "failed to copy secrets")
+	}
+	if err := r.copyConfigMaps(ctx, target.Client, ephApp, namespace); err != nil {
+		logger.Error(err, "failed to copy configmaps")
+	}
+
+	// Delete the secrets and configmaps removed from the spec
+	if err := r.pruneInjectedResources(ctx, target.Client, ephApp, namespace); err != nil {
+		logger.Error(err, "failed to delete removed secrets and configmaps")
+		return r.updateStatusWithError(ctx, ephApp, ephemeralv1alpha1.PhaseFailed, "Failed to delete removed secrets and configmaps", err)
+	}
+
+	// The repositories allowed by the project follow the components
+	if err := r.reconcileAppProject(ctx, ephApp, namespace, policies); err != nil {
+		if argocd.IsUnavailable(err) {
+			logger.Info("ArgoCD is unavailable, retrying", "reason", err.Error())
+			return ctrl.Result{RequeueAfter: pollInterval}, nil
+		}
+		logger.Error(err, "failed to update ArgoCD project")
+		return r.updateStatusWithError(ctx, ephApp, ephemeralv1alpha1.PhaseFailed, "Failed to update ArgoCD project", err)
+	}
+
+	// Update existing applications, create added components and delete removed ones
+	if err := r.reconcileComponentApplications(ctx, ephApp, namespace); err != nil {
+		// The generation is not recorded so the rollout is attempted again
+		if argocd.IsUnavailable(err) {
+			logger.Info("ArgoCD is unavailable, retrying", "reason", err.Error())
+			return ctrl.Result{RequeueAfter: pollInterval}, nil
+		}
+		logger.Error(err, "failed to update ArgoCD application")
+		return r.updateStatusWithError(ctx, ephApp, ephemeralv1alpha1.PhaseFailed, "Failed to update ArgoCD application", err)
+	}
+
+	ephApp.Status.Phase = ephemeralv1alpha1.PhaseUpdating
+	ephApp.Status.Message = "ArgoCD application updated, waiting for sync"
+	r.setInjectionCondition(ephApp)
+	r.setCondition(ephApp, "Ready", metav1.ConditionFalse, "Updating", "Rolling out spec changes")
+
+	if err := r.updateStatus(ctx, ephApp); err != nil {
 		return ctrl.Result{}, err
 	}
 
-	return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+	return ctrl.Result{RequeueAfter: r.syncRequeueInterval()}, nil
 }
 
-// handleCreatingPhase handles the creating phase
+// handleCreatingPhase handles the creating and updating phases
 func (r *EphemeralApplicationReconciler) handleCreatingPhase(ctx context.Context, ephApp *ephemeralv1alpha1.EphemeralApplication) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 	logger.Info("handling creating phase")
 
-	// Check if ArgoCD Application exists and is synced
-	appQuery := application.ApplicationQuery{
-		Name:         &ephApp.Status.ArgoApplicationName,
-		AppNamespace: &r.Config.ArgoNamespace,
-	}
-	argoApp, err := r.ArgoClient.GetApplication(ctx, appQuery)
+	// Check if every ArgoCD Application exists and is synced
+	synced, healthy, err := r.refreshComponentStatus(ctx, ephApp)
 	if err != nil {
-		if errors.IsNotFound(err) {
+		if argocd.IsNotFound(err) {
 			return r.updateStatusWithError(ctx, ephApp, ephemeralv1alpha1.PhaseFailed, "ArgoCD application not found", err)
 		}
+		if argocd.IsUnavailable(err) {
+			logger.Info("ArgoCD is unavailable, retrying", "reason", err.Error())
+			return ctrl.Result{RequeueAfter: pollInterval}, nil
+		}
 		return ctrl.Result{}, err
 	}
 
 	// Check sync status
-	if argoApp.Status.Sync.Status == "Synced" && argoApp.Status.Health.Status == "Healthy" {
+	if synced && healthy {
+		// The environment only becomes active once its postReady hooks succeeded
+		if result, waiting, err := r.reconcileHooks(ctx, ephApp, ephemeralv1alpha1.HookPostReady); waiting || err != nil {
+			return result, err
+		}
+
 		ephApp.Status.Phase = ephemeralv1alpha1.PhaseActive
 		ephApp.Status.Message = "Ephemeral environment is active"
 		now := metav1.Now()
 		ephApp.Status.LastSyncTime = &now
 		r.setCondition(ephApp, "Ready", metav1.ConditionTrue, "Active", "Ephemeral environment is active and healthy")
 
-		if err := r.Status().Update(ctx, ephApp); err != nil {
+		if err := r.updateStatus(ctx, ephApp); err != nil {
 			return ctrl.Result{}, err
 		}
 
 		return ctrl.Result{RequeueAfter: r.Config.ReconcileInterval}, nil
 	}
 
+	// Record per-component progress
+	if len(ephApp.Status.Components) > 0 {
+		if err := r.updateStatus(ctx, ephApp); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
 	// Still creating, requeue
-	return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+	return ctrl.Result{RequeueAfter: r.syncRequeueInterval()}, nil
 }
 
 // handleActivePhase handles the active phase
@@ -225,30 +423,32 @@ func (r *EphemeralApplicationReconciler) handleActivePhase(ctx context.Context,
 	logger := log.FromContext(ctx)
 	logger.Info("handling active phase")
 
-	// Verify ArgoCD Application still exists and is healthy
-	appQuery := application.ApplicationQuery{
-		Name:         &ephApp.Status.ArgoApplicationName,
-		AppNamespace: &r.Config.ArgoNamespace,
-	}
-	argoApp, err := r.ArgoClient.GetApplication(ctx, appQuery)
+	// Verify every ArgoCD Application still exists and is healthy
+	synced, _, err := r.refreshComponentStatus(ctx, ephApp)
 	if err != nil {
-		if errors.IsNotFound(err) {
+		if argocd.IsNotFound(err) {
 			return r.updateStatusWithError(ctx, ephApp, ephemeralv1alpha1.PhaseFailed, "ArgoCD application disappeared", err)
 		}
+		if argocd.IsUnavailable(err) {
+			logger.Info("ArgoCD is unavailable, retrying", "reason", err.Error())
+			return ctrl.Result{RequeueAfter: pollInterval}, nil
+		}
 		return ctrl.Result{}, err
 	}
 
 	// Update sync time if synced
-	if argoApp.Status.Sync.Status == "Synced" {
+	if synced {
 		now := metav1.Now()
 		ephApp.Status.LastSyncTime = &now
-		if err := r.Status().Update(ctx, ephApp); err != nil {
+	}
+	if synced || len(ephApp.Status.Components) > 0 {
+		if err := r.updateStatus(ctx, ephApp); err != nil {
 			return ctrl.Result{}, err
 		}
 	}
 
-	// Requeue for next check
-	return ctrl.Result{RequeueAfter: r.Config.ReconcileInterval}, nil
+	// Requeue for next check, or when the environment has to go to sleep
+	return ctrl.Result{RequeueAfter: r.requeueUntil(ephApp.Status.NextHibernationTransition)}, nil
 }
 
 // handleFailedPhase handles the failed phase
@@ -260,13 +460,13 @@ func (r *EphemeralApplicationReconciler) handleFailedPhase(ctx context.Context,
 // handleExpiration handles expired applications
 func (r *EphemeralApplicationReconciler) handleExpiration(ctx context.Context, ephApp *ephemeralv1alpha1.EphemeralApplication) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
-	logger.Info("handling expiration", "expirationDate", ephApp.Spec.ExpirationDate)
+	logger.Info("handling expiration", "expiresAt", ephApp.Status.ExpiresAt)
 
 	ephApp.Status.Phase = ephemeralv1alpha1.PhaseExpiring
 	ephApp.Status.Message = "Ephemeral environment has expired and is being deleted"
 	r.setCondition(ephApp, "Ready", metav1.ConditionFalse, "Expiring", "Environment has expired")
 
-	if err := r.Status().Update(ctx, ephApp); err != nil {
+	if err := r.updateStatus(ctx, ephApp); err != nil {
 		return ctrl.Result{}, err
 	}
 
@@ -283,27 +483,44 @@ func (r *EphemeralApplicationReconciler) handleDeletion(ctx context.Context, eph
 	logger := log.FromContext(ctx)
 
 	if controllerutil.ContainsFinalizer(ephApp, finalizerName) {
-		// Delete ArgoCD Application
-		if ephApp.Status.ArgoApplicationName != "" {
-			logger.Info("deleting ArgoCD application", "name", ephApp.Status.ArgoApplicationName)
-			if err := r.ArgoClient.DeleteApplication(ctx, ephApp.Status.ArgoApplicationName, r.Config.ArgoNamespace); err != nil {
-				// FIXME: ArgoCD is return a 403 PermissionDenied error when the application does not exist, but we should improve this error handling.
-				if !errors.IsNotFound(err) && !strings.Contains(err.Error(), "PermissionDenied") {
+		// Run the preDelete hooks while the applications are still deployed
+		if result, waiting, err := r.runPreDeleteHooks(ctx, ephApp); waiting || err != nil {
+			return result, err
+		}
+
+		// Delete ArgoCD Applications
+		for _, name := range argoApplicationNames(ephApp) {
+			logger.Info("deleting ArgoCD application", "name", name)
+			if err := r.ArgoClient.DeleteApplication(ctx, name, r.Config.ArgoNamespace); err != nil {
+				// ArgoCD answers PermissionDenied instead of NotFound for applications that do not exist
+				if !argocd.IsNotFound(err) && !argocd.IsPermissionDenied(err) {
 					logger.Error(err, "failed to delete ArgoCD application")
 					return ctrl.Result{}, err
 				}
 			}
 		}
 
-		// Delete namespace
-		if ephApp.Status.Namespace != "" {
+		// Delete the project once no application references it
+		if err := r.deleteAppProject(ctx, ephApp); err != nil {
+			logger.Error(err, "failed to delete ArgoCD project")
+			return ctrl.Result{}, err
+		}
+
+		// Delete namespace, it is left behind when the destination cluster can no longer be
+		// reached so the EphemeralApplication does not get stuck
+		target, err := r.targetCluster(ctx, ephApp)
+		if err != nil {
+			logger.Error(err, "failed to resolve destination cluster, the namespace is not deleted",
+				"namespace", ephApp.Status.Namespace)
+		}
+		if ephApp.Status.Namespace != "" && err == nil {
 			logger.Info("deleting namespace", "namespace", ephApp.Status.Namespace)
 			ns := &corev1.Namespace{
 				ObjectMeta: metav1.ObjectMeta{
 					Name: ephApp.Status.Namespace,
 				},
 			}
-			if err := r.Delete(ctx, ns); err != nil {
+			if err := target.Client.Delete(ctx, ns); err != nil {
 				if !errors.IsNotFound(err) {
 					logger.Error(err, "failed to delete namespace")
 					return ctrl.Result{}, err
@@ -321,9 +538,36 @@ func (r *EphemeralApplicationReconciler) handleDeletion(ctx context.Context, eph
 	return ctrl.Result{}, nil
 }
 
+// hasSpecChanged reports whether the spec or the template changed after the ArgoCD Applications were created
+func (r *EphemeralApplicationReconciler) hasSpecChanged(ephApp *ephemeralv1alpha1.EphemeralApplication, templateGeneration int64) bool {
+	switch ephApp.Status.Phase {
+	case ephemeralv1alpha1.PhaseCreating, ephemeralv1alpha1.PhaseUpdating, ephemeralv1alpha1.PhaseActive, ephemeralv1alpha1.PhaseFailed:
+		return len(argoApplicationNames(ephApp)) > 0 &&
+			(ephApp.Generation != ephApp.Status.ObservedGeneration || ephApp.Status.TemplateGeneration != templateGeneration)
+	default:
+		return false
+	}
+}
+
+// buildApplicationSpec builds the desired ArgoCD Application spec for a component of the EphemeralApplication
+func (r *EphemeralApplicationReconciler) buildApplicationSpec(ephApp *ephemeralv1alpha1.EphemeralApplication, component ephemeralv1alpha1.Component, namespace string) v1alpha1.ApplicationSpec {
+	return v1alpha1.ApplicationSpec{
+		Project: applicationProject(ephApp),
+		Source:  argocd.BuildApplicationSource(component),
+		Destination: v1alpha1.ApplicationDestination{
+			Namespace: namespace,
+			Server:    destinationServer(ephApp),
+		},
+		SyncPolicy: argocd.BuildSyncPolicy(ephApp),
+		// Ignore differences on injected resources
+		IgnoreDifferences: argocd.BuildIgnoreDifferences(ephApp),
+	}
+}
+
 // isExpired checks if the application has expired
 func (r *EphemeralApplicationReconciler) isExpired(ephApp *ephemeralv1alpha1.EphemeralApplication) bool {
-	return time.Now().After(ephApp.Spec.ExpirationDate.Time)
+	expiresAt := ephApp.Status.ExpiresAt
+	return expiresAt != nil && time.Now().After(expiresAt.Time)
 }
 
 // updateStatusWithError updates the status with an error
@@ -338,7 +582,7 @@ func (r *EphemeralApplicationReconciler) updateStatusWithError(
 	ephApp.Status.Message = fmt.Sprintf("%s: %v", message, err)
 	r.setCondition(ephApp, "Ready", metav1.ConditionFalse, "Error", message)
 
-	if updateErr := r.Status().Update(ctx, ephApp); updateErr != nil {
+	if updateErr := r.updateStatus(ctx, ephApp); updateErr != nil {
 		return ctrl.Result{}, updateErr
 	}
 
@@ -377,7 +621,58 @@ func (r *EphemeralApplicationReconciler) setCondition(
 
 // SetupWithManager sets up the controller with the Manager
 func (r *EphemeralApplicationReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	// Index the continuously synced sources to find the applications copying a changed object
+	indexer := mgr.GetFieldIndexer()
+	if err := indexer.IndexField(context.Background(), &ephemeralv1alpha1.EphemeralApplication{},
+		continuousSourceIndex, indexApplicationSources); err != nil {
+		return err
+	}
+	if err := indexer.IndexField(context.Background(), &ephemeralv1alpha1.EphemeralApplicationTemplate{},
+		continuousSourceIndex, indexTemplateSources); err != nil {
+		return err
+	}
+
+	b := ctrl.NewControllerManagedBy(mgr).
 		For(&ephemeralv1alpha1.EphemeralApplication{}).
-		Complete(r)
+		Watches(
+			&ephemeralv1alpha1.EphemeralApplicationTemplate{},
+			handler.EnqueueRequestsFromMapFunc(r.findApplicationsForTemplate),
+		).
+		// Only the metadata of the secrets and configmaps of the cluster is cached, their
+		// content is read from the API server
+		Watches(
+			&corev1.Secret{},
+			handler.EnqueueRequestsFromMapFunc(r.findApplicationsForSource(secretKind)),
+			builder.OnlyMetadata,
+		).
+		Watches(
+			&corev1.ConfigMap{},
+			handler.EnqueueRequestsFromMapFunc(r.findApplicationsForSource(configMapKind)),
+			builder.OnlyMetadata,
+		).
+		Watches(
+			&batchv1.Job{},
+			handler.EnqueueRequestsFromMapFunc(r.findApplicationForOwner),
+			builder.WithPredicates(predicate.NewPredicateFuncs(isHookJob)),
+		)
+
+	// React to sync and health changes of the ArgoCD Applications instead of polling them
+	if r.Config.WatchArgoApplications {
+		b = b.Watches(
+			NewArgoApplicationObject(),
+			handler.EnqueueRequestsFromMapFunc(r.findApplicationForOwner),
+			builder.WithPredicates(predicate.NewPredicateFuncs(r.inArgoNamespace)),
+		)
+	}
+
+	// Update the ArgoUnavailable condition of every application when the circuit breaker flips
+	events, err := r.availabilityEvents(mgr)
+	if err != nil {
+		return err
+	}
+	if events != nil {
+		b = b.WatchesRawSource(source.Channel(events, handler.EnqueueRequestsFromMapFunc(r.findAllApplications)))
+	}
+
+	return b.Complete(r)
 }
@@ -1,82 +1,666 @@
 package controller
 
 import (
+	"context"
+	"fmt"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/argoproj/argo-cd/v2/pkg/apiclient/application"
+	"github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	"github.com/argoproj/gitops-engine/pkg/health"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	ephemeralv1alpha1 "github.com/jbarea/argo-ephemeral-operator/api/v1alpha1"
+	"github.com/jbarea/argo-ephemeral-operator/internal/argocd"
+	"github.com/jbarea/argo-ephemeral-operator/internal/config"
+	"github.com/jbarea/argo-ephemeral-operator/internal/naming"
 )
 
-func TestDefaultNameGenerator_GenerateNamespace(t *testing.T) {
-	tests := []struct {
-		name          string
-		namespaceName string
-		wantLen       int
-		validate      func(t *testing.T, result string)
-	}{
-		{
-			name:          "custom namespace name",
-			namespaceName: "my-custom-namespace",
-			wantLen:       63,
-			validate: func(t *testing.T, result string) {
-				if result != "my-custom-namespace" {
-					t.Errorf("expected 'my-custom-namespace', got '%s'", result)
-				}
+// mockArgoClient is an in-memory implementation of argocd.Client
+type mockArgoClient struct {
+	apps     map[string]*v1alpha1.Application
+	projects map[string]*v1alpha1.AppProject
+	// err is returned by every request when set
+	err error
+	// unavailable is reported as the circuit breaker state when set
+	unavailable error
+}
+
+func newMockArgoClient(apps ...*v1alpha1.Application) *mockArgoClient {
+	m := &mockArgoClient{
+		apps:     map[string]*v1alpha1.Application{},
+		projects: map[string]*v1alpha1.AppProject{},
+	}
+	for _, app := range apps {
+		m.apps[app.Name] = app
+	}
+	return m
+}
+
+func (m *mockArgoClient) DoRequestWithRetry(requestFunc func(appClient application.ApplicationServiceClient) error) error {
+	return nil
+}
+
+func (m *mockArgoClient) CreateApplication(ctx context.Context, newApp *application.ApplicationCreateRequest) (*v1alpha1.Application, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	m.apps[newApp.Application.Name] = newApp.Application
+	return newApp.Application, nil
+}
+
+func (m *mockArgoClient) UpdateApplication(ctx context.Context, updateReq *application.ApplicationUpdateRequest) (*v1alpha1.Application, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	if _, ok := m.apps[updateReq.Application.Name]; !ok {
+		return nil, fmt.Errorf("%w: application %s", argocd.ErrNotFound, updateReq.Application.Name)
+	}
+	m.apps[updateReq.Application.Name] = updateReq.Application
+	return updateReq.Application, nil
+}
+
+func (m *mockArgoClient) GetApplication(ctx context.Context, query application.ApplicationQuery) (*v1alpha1.Application, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	app, ok := m.apps[*query.Name]
+	if !ok {
+		return nil, fmt.Errorf("%w: application %s", argocd.ErrNotFound, *query.Name)
+	}
+	return app.DeepCopy(), nil
+}
+
+func (m *mockArgoClient) GetApplications(ctx context.Context) (*v1alpha1.ApplicationList, error) {
+	list := &v1alpha1.ApplicationList{}
+	for _, app := range m.apps {
+		list.Items = append(list.Items, *app)
+	}
+	return list, nil
+}
+
+func (m *mockArgoClient) Unavailable() error {
+	return m.unavailable
+}
+
+func (m *mockArgoClient) Notify(ch chan<- struct{}) {}
+
+func (m *mockArgoClient) DeleteApplication(ctx context.Context, name string, namespace string) error {
+	if m.err != nil {
+		return m.err
+	}
+	if _, ok := m.apps[name]; !ok {
+		return fmt.Errorf("%w: application %s", argocd.ErrNotFound, name)
+	}
+	delete(m.apps, name)
+	return nil
+}
+
+func (m *mockArgoClient) CreateProject(ctx context.Context, appProject *v1alpha1.AppProject) (*v1alpha1.AppProject, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	m.projects[appProject.Name] = appProject
+	return appProject, nil
+}
+
+func (m *mockArgoClient) UpdateProject(ctx context.Context, appProject *v1alpha1.AppProject) (*v1alpha1.AppProject, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	if _, ok := m.projects[appProject.Name]; !ok {
+		return nil, fmt.Errorf("%w: project %s", argocd.ErrNotFound, appProject.Name)
+	}
+	m.projects[appProject.Name] = appProject
+	return appProject, nil
+}
+
+func (m *mockArgoClient) GetProject(ctx context.Context, name string) (*v1alpha1.AppProject, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	appProject, ok := m.projects[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: project %s", argocd.ErrNotFound, name)
+	}
+	return appProject.DeepCopy(), nil
+}
+
+func (m *mockArgoClient) DeleteProject(ctx context.Context, name string) error {
+	if m.err != nil {
+		return m.err
+	}
+	delete(m.projects, name)
+	return nil
+}
+
+func TestReconcile_PropagatesSpecChanges(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = networkingv1.AddToScheme(scheme)
+	_ = ephemeralv1alpha1.AddToScheme(scheme)
+
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test-app",
+			Namespace:  "default",
+			Generation: 2,
+			Finalizers: []string{finalizerName},
+		},
+		Spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+			RepoURL:        "https://github.com/example/app.git",
+			Path:           "manifests",
+			TargetRevision: "new-commit",
+			ExpirationDate: &metav1.Time{Time: time.Now().Add(time.Hour)},
+		},
+		Status: ephemeralv1alpha1.EphemeralApplicationStatus{
+			Phase:               ephemeralv1alpha1.PhaseActive,
+			Namespace:           "ephemeral-test",
+			ArgoApplicationName: "test-app",
+			ObservedGeneration:  1,
+		},
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(ephApp).
+		WithStatusSubresource(ephApp).
+		Build()
+
+	argoClient := newMockArgoClient(&v1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-app"},
+		Spec: v1alpha1.ApplicationSpec{
+			Source: &v1alpha1.ApplicationSource{
+				RepoURL:        "https://github.com/example/app.git",
+				Path:           "manifests",
+				TargetRevision: "old-commit",
+			},
+		},
+	})
+
+	reconciler := &EphemeralApplicationReconciler{
+		Client:     fakeClient,
+		Scheme:     scheme,
+		ArgoClient: argoClient,
+		Config:     &config.Config{ArgoNamespace: "argocd", ReconcileInterval: time.Minute},
+	}
+
+	ctx := context.Background()
+	_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ephApp)})
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	if got := argoClient.apps["test-app"].Spec.Source.TargetRevision; got != "new-commit" {
+		t.Errorf("expected ArgoCD application targetRevision 'new-commit', got '%s'", got)
+	}
+
+	updated := &ephemeralv1alpha1.EphemeralApplication{}
+	if err := fakeClient.Get(ctx, client.ObjectKeyFromObject(ephApp), updated); err != nil {
+		t.Fatalf("failed to get EphemeralApplication: %v", err)
+	}
+	if updated.Status.Phase != ephemeralv1alpha1.PhaseUpdating {
+		t.Errorf("expected phase Updating, got %s", updated.Status.Phase)
+	}
+	if updated.Status.ObservedGeneration != updated.Generation {
+		t.Errorf("expected observedGeneration %d, got %d", updated.Generation, updated.Status.ObservedGeneration)
+	}
+}
+
+func TestReconcile_ComponentsActiveWhenAllReady(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = networkingv1.AddToScheme(scheme)
+	_ = ephemeralv1alpha1.AddToScheme(scheme)
+
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "preview",
+			Namespace:  "default",
+			Generation: 1,
+			Finalizers: []string{finalizerName},
+		},
+		Spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+			Components: []ephemeralv1alpha1.Component{
+				{Name: "frontend", RepoURL: "https://github.com/example/frontend.git", Path: "manifests"},
+				{Name: "backend", RepoURL: "https://github.com/example/backend.git", Path: "manifests"},
 			},
+			ExpirationDate: &metav1.Time{Time: time.Now().Add(time.Hour)},
 		},
-		{
-			name:          "auto-generated namespace",
-			namespaceName: "",
-			wantLen:       63,
-			validate: func(t *testing.T, result string) {
-				if !strings.HasPrefix(result, "ephemeral-") {
-					t.Errorf("expected prefix 'ephemeral-', got '%s'", result)
-				}
-				if len(result) != 17 { // "ephemeral-" (10) + 7 random chars
-					t.Errorf("expected length 17, got %d", len(result))
-				}
+		Status: ephemeralv1alpha1.EphemeralApplicationStatus{
+			Phase:              ephemeralv1alpha1.PhaseCreating,
+			Namespace:          "ephemeral-test",
+			ObservedGeneration: 1,
+			Components: []ephemeralv1alpha1.ComponentStatus{
+				{Name: "frontend", ArgoApplicationName: "preview-frontend"},
+				{Name: "backend", ArgoApplicationName: "preview-backend"},
 			},
 		},
-		{
-			name:          "long custom name",
-			namespaceName: "this-is-a-very-long-namespace-name-that-exceeds-kubernetes-limits",
-			wantLen:       63,
-			validate: func(t *testing.T, result string) {
-				if len(result) > 63 {
-					t.Errorf("result length %d exceeds 63 characters", len(result))
-				}
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(ephApp).
+		WithStatusSubresource(ephApp).
+		Build()
+
+	argoApp := func(name string, status health.HealthStatusCode) *v1alpha1.Application {
+		return &v1alpha1.Application{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: v1alpha1.ApplicationStatus{
+				Sync:   v1alpha1.SyncStatus{Status: "Synced"},
+				Health: v1alpha1.HealthStatus{Status: status},
+			},
+		}
+	}
+	argoClient := newMockArgoClient(
+		argoApp("preview-frontend", "Healthy"),
+		argoApp("preview-backend", "Progressing"),
+	)
+
+	reconciler := &EphemeralApplicationReconciler{
+		Client:     fakeClient,
+		Scheme:     scheme,
+		ArgoClient: argoClient,
+		Config:     &config.Config{ArgoNamespace: "argocd", ReconcileInterval: time.Minute},
+	}
+
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ephApp)}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	updated := &ephemeralv1alpha1.EphemeralApplication{}
+	if err := fakeClient.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("failed to get EphemeralApplication: %v", err)
+	}
+	if updated.Status.Phase != ephemeralv1alpha1.PhaseCreating {
+		t.Errorf("expected phase Creating while a component is progressing, got %s", updated.Status.Phase)
+	}
+	if got := updated.Status.Components[1].HealthStatus; got != "Progressing" {
+		t.Errorf("expected backend health 'Progressing', got '%s'", got)
+	}
+
+	argoClient.apps["preview-backend"].Status.Health.Status = "Healthy"
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	if err := fakeClient.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("failed to get EphemeralApplication: %v", err)
+	}
+	if updated.Status.Phase != ephemeralv1alpha1.PhaseActive {
+		t.Errorf("expected phase Active, got %s", updated.Status.Phase)
+	}
+}
+
+func TestReconcile_RemovedComponentIsDeleted(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = networkingv1.AddToScheme(scheme)
+	_ = ephemeralv1alpha1.AddToScheme(scheme)
+
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "preview",
+			Namespace:  "default",
+			Generation: 2,
+			Finalizers: []string{finalizerName},
+		},
+		Spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+			Components: []ephemeralv1alpha1.Component{
+				{Name: "frontend", RepoURL: "https://github.com/example/frontend.git", Path: "manifests"},
+				{Name: "database", RepoURL: "https://charts.example.com", Helm: &ephemeralv1alpha1.HelmSource{Chart: "postgresql"}},
 			},
+			ExpirationDate: &metav1.Time{Time: time.Now().Add(time.Hour)},
 		},
-		{
-			name:          "name with underscores",
-			namespaceName: "my_custom_namespace",
-			wantLen:       63,
-			validate: func(t *testing.T, result string) {
-				if result != "my-custom-namespace" {
-					t.Errorf("expected 'my-custom-namespace', got '%s'", result)
-				}
+		Status: ephemeralv1alpha1.EphemeralApplicationStatus{
+			Phase:              ephemeralv1alpha1.PhaseActive,
+			Namespace:          "ephemeral-test",
+			ObservedGeneration: 1,
+			Components: []ephemeralv1alpha1.ComponentStatus{
+				{Name: "frontend", ArgoApplicationName: "preview-frontend"},
+				{Name: "backend", ArgoApplicationName: "preview-backend"},
+				// Already deleted outside the operator
+				{Name: "worker", ArgoApplicationName: "preview-worker"},
 			},
 		},
 	}
 
-	gen := NewDefaultNameGenerator()
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(ephApp).
+		WithStatusSubresource(ephApp).
+		Build()
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got := gen.GenerateNamespace(tt.namespaceName, "")
+	argoClient := newMockArgoClient(
+		&v1alpha1.Application{ObjectMeta: metav1.ObjectMeta{Name: "preview-frontend"}},
+		&v1alpha1.Application{ObjectMeta: metav1.ObjectMeta{Name: "preview-backend"}},
+	)
 
-			if len(got) > tt.wantLen {
-				t.Errorf("GenerateNamespace() length = %v, want <= %v", len(got), tt.wantLen)
-			}
+	reconciler := &EphemeralApplicationReconciler{
+		Client:     fakeClient,
+		Scheme:     scheme,
+		ArgoClient: argoClient,
+		Config:     &config.Config{ArgoNamespace: "argocd", ReconcileInterval: time.Minute},
+	}
+
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ephApp)}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	if _, ok := argoClient.apps["preview-backend"]; ok {
+		t.Error("expected ArgoCD application of removed component to be deleted")
+	}
+	database, ok := argoClient.apps["preview-database"]
+	if !ok {
+		t.Fatal("expected ArgoCD application of added component to be created")
+	}
+	if database.Spec.Source.Chart != "postgresql" {
+		t.Errorf("expected chart 'postgresql', got '%s'", database.Spec.Source.Chart)
+	}
 
-			if tt.validate != nil {
-				tt.validate(t, got)
-			}
-		})
+	updated := &ephemeralv1alpha1.EphemeralApplication{}
+	if err := fakeClient.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("failed to get EphemeralApplication: %v", err)
+	}
+	var names []string
+	for _, component := range updated.Status.Components {
+		names = append(names, component.ArgoApplicationName)
+	}
+	if strings.Join(names, ",") != "preview-frontend,preview-database" {
+		t.Errorf("unexpected component applications: %v", names)
 	}
 }
 
-// Mock implementations for testing would go here
-// Example:
-// type mockArgoClient struct{}
-// func (m *mockArgoClient) CreateApplication(ctx context.Context, app *argocdv1alpha1.Application) error {
-//     return nil
-// }
+func TestReconcile_ResolvesTemplate(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = networkingv1.AddToScheme(scheme)
+	_ = ephemeralv1alpha1.AddToScheme(scheme)
+
+	template := &ephemeralv1alpha1.EphemeralApplicationTemplate{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "backend",
+			Namespace:  "default",
+			Generation: 1,
+		},
+		Spec: ephemeralv1alpha1.EphemeralApplicationTemplateSpec{
+			RepoURL:        "https://github.com/example/backend.git",
+			Path:           "manifests",
+			TargetRevision: "main",
+		},
+	}
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "preview",
+			Namespace:  "default",
+			Generation: 1,
+			Finalizers: []string{finalizerName},
+		},
+		Spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+			TemplateRef:    &ephemeralv1alpha1.TemplateReference{Name: "backend"},
+			TargetRevision: "pr-42",
+			ExpirationDate: &metav1.Time{Time: time.Now().Add(time.Hour)},
+		},
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(template, ephApp).
+		WithStatusSubresource(ephApp).
+		Build()
+
+	argoClient := newMockArgoClient()
+	reconciler := &EphemeralApplicationReconciler{
+		Client:        fakeClient,
+		Scheme:        scheme,
+		ArgoClient:    argoClient,
+		Config:        &config.Config{ArgoNamespace: "argocd", ReconcileInterval: time.Minute},
+		NameGenerator: naming.NewDefaultGenerator(),
+	}
+
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ephApp)}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	argoApp, ok := argoClient.apps["preview"]
+	if !ok {
+		t.Fatal("expected ArgoCD application to be created")
+	}
+	source := argoApp.Spec.Source
+	if source.RepoURL != "https://github.com/example/backend.git" || source.Path != "manifests" || source.TargetRevision != "pr-42" {
+		t.Errorf("unexpected source: %+v", source)
+	}
+
+	updated := &ephemeralv1alpha1.EphemeralApplication{}
+	if err := fakeClient.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("failed to get EphemeralApplication: %v", err)
+	}
+	if updated.Status.TemplateGeneration != 1 {
+		t.Errorf("expected templateGeneration 1, got %d", updated.Status.TemplateGeneration)
+	}
+	if updated.Spec.RepoURL != "" {
+		t.Errorf("expected merged spec not to be persisted, got repoURL '%s'", updated.Spec.RepoURL)
+	}
+
+	// A template change is rolled out to the ArgoCD application
+	template.Spec.Path = "deploy/preview"
+	template.Generation = 2
+	if err := fakeClient.Update(ctx, template); err != nil {
+		t.Fatalf("failed to update template: %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	if got := argoClient.apps["preview"].Spec.Source.Path; got != "deploy/preview" {
+		t.Errorf("expected path 'deploy/preview', got '%s'", got)
+	}
+	if err := fakeClient.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("failed to get EphemeralApplication: %v", err)
+	}
+	if updated.Status.TemplateGeneration != 2 || updated.Status.Phase != ephemeralv1alpha1.PhaseUpdating {
+		t.Errorf("expected templateGeneration 2 and phase Updating, got %d and %s",
+			updated.Status.TemplateGeneration, updated.Status.Phase)
+	}
+}
+
+func TestReconcile_ExpiresAfterTTL(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = networkingv1.AddToScheme(scheme)
+	_ = ephemeralv1alpha1.AddToScheme(scheme)
+
+	created := metav1.NewTime(time.Now().Add(-2 * time.Hour).Truncate(time.Second))
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "preview",
+			Namespace:         "default",
+			CreationTimestamp: created,
+			Finalizers:        []string{finalizerName},
+		},
+		Spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+			RepoURL: "https://github.com/example/app.git",
+			Path:    "manifests",
+			TTL:     &metav1.Duration{Duration: time.Hour},
+		},
+		Status: ephemeralv1alpha1.EphemeralApplicationStatus{
+			Phase: ephemeralv1alpha1.PhaseActive,
+		},
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(ephApp).
+		WithStatusSubresource(ephApp).
+		Build()
+
+	reconciler := &EphemeralApplicationReconciler{
+		Client:     fakeClient,
+		Scheme:     scheme,
+		ArgoClient: newMockArgoClient(),
+		Config:     &config.Config{ArgoNamespace: "argocd", ReconcileInterval: time.Minute},
+	}
+
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ephApp)}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	updated := &ephemeralv1alpha1.EphemeralApplication{}
+	if err := fakeClient.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("failed to get EphemeralApplication: %v", err)
+	}
+	if updated.Status.ExpiresAt == nil || !updated.Status.ExpiresAt.Time.Equal(created.Add(time.Hour)) {
+		t.Errorf("expected expiresAt %v, got %v", created.Add(time.Hour), updated.Status.ExpiresAt)
+	}
+	if updated.Status.Phase != ephemeralv1alpha1.PhaseExpiring {
+		t.Errorf("expected phase Expiring, got %s", updated.Status.Phase)
+	}
+	if updated.DeletionTimestamp.IsZero() {
+		t.Error("expected EphemeralApplication to be deleted")
+	}
+}
+
+func TestReconcile_WaitsForQuota(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = networkingv1.AddToScheme(scheme)
+	_ = ephemeralv1alpha1.AddToScheme(scheme)
+
+	maxEnvironments := int32(1)
+	quota := &ephemeralv1alpha1.EphemeralPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec:       ephemeralv1alpha1.EphemeralPolicySpec{MaxEnvironmentsPerNamespace: &maxEnvironments},
+	}
+	running := &ephemeralv1alpha1.EphemeralApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "running", Namespace: "default"},
+		Status:     ephemeralv1alpha1.EphemeralApplicationStatus{Phase: ephemeralv1alpha1.PhaseActive},
+	}
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "waiting",
+			Namespace:  "default",
+			Finalizers: []string{finalizerName},
+		},
+		Spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+			RepoURL: "https://github.com/example/app.git",
+			Path:    "manifests",
+			TTL:     &metav1.Duration{Duration: time.Hour},
+		},
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(quota, running, ephApp).
+		WithStatusSubresource(ephApp).
+		Build()
+
+	argoClient := newMockArgoClient()
+	reconciler := &EphemeralApplicationReconciler{
+		Client:        fakeClient,
+		Scheme:        scheme,
+		ArgoClient:    argoClient,
+		Config:        &config.Config{ArgoNamespace: "argocd", ReconcileInterval: time.Minute},
+		NameGenerator: naming.NewDefaultGenerator(),
+	}
+
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ephApp)}
+	result, err := reconciler.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if result.RequeueAfter == 0 {
+		t.Error("expected a requeue while waiting for quota")
+	}
+	if len(argoClient.apps) != 0 {
+		t.Errorf("expected no ArgoCD application, got %d", len(argoClient.apps))
+	}
+
+	updated := &ephemeralv1alpha1.EphemeralApplication{}
+	if err := fakeClient.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("failed to get EphemeralApplication: %v", err)
+	}
+	if updated.Status.Phase != ephemeralv1alpha1.PhasePending {
+		t.Errorf("expected phase Pending, got %s", updated.Status.Phase)
+	}
+	if !strings.HasPrefix(updated.Status.Message, "Waiting for quota") {
+		t.Errorf("expected a quota message, got %q", updated.Status.Message)
+	}
+}
+
+func TestReconcile_ArgoUnavailableKeepsPhase(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = networkingv1.AddToScheme(scheme)
+	_ = ephemeralv1alpha1.AddToScheme(scheme)
+
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test-app",
+			Namespace:  "default",
+			Generation: 1,
+			Finalizers: []string{finalizerName},
+		},
+		Spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+			RepoURL:        "https://github.com/example/app.git",
+			Path:           "manifests",
+			ExpirationDate: &metav1.Time{Time: time.Now().Add(time.Hour)},
+		},
+		Status: ephemeralv1alpha1.EphemeralApplicationStatus{
+			Phase:               ephemeralv1alpha1.PhaseActive,
+			Namespace:           "ephemeral-test",
+			ArgoApplicationName: "test-app",
+			ObservedGeneration:  1,
+		},
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(ephApp).
+		WithStatusSubresource(ephApp).
+		Build()
+
+	argoClient := newMockArgoClient()
+	argoClient.err = fmt.Errorf("%w: connection refused", argocd.ErrUnavailable)
+
+	reconciler := &EphemeralApplicationReconciler{
+		Client:     fakeClient,
+		Scheme:     scheme,
+		ArgoClient: argoClient,
+		Config:     &config.Config{ArgoNamespace: "argocd", ReconcileInterval: time.Minute},
+	}
+
+	ctx := context.Background()
+	result, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ephApp)})
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if result.RequeueAfter != pollInterval {
+		t.Errorf("expected requeue after %s, got %s", pollInterval, result.RequeueAfter)
+	}
+
+	updated := &ephemeralv1alpha1.EphemeralApplication{}
+	if err := fakeClient.Get(ctx, client.ObjectKeyFromObject(ephApp), updated); err != nil {
+		t.Fatalf("failed to get EphemeralApplication: %v", err)
+	}
+	if updated.Status.Phase != ephemeralv1alpha1.PhaseActive {
+		t.Errorf("expected phase to stay Active while ArgoCD is down, got %s", updated.Status.Phase)
+	}
+}
@@ -0,0 +1,220 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/argoproj/argo-cd/v2/pkg/apiclient/application"
+	ephemeralv1alpha1 "github.com/jbarea/argo-ephemeral-operator/api/v1alpha1"
+)
+
+// hibernatedReplicasAnnotation records the replicas of a workload before it was scaled to zero
+const hibernatedReplicasAnnotation = "ephemeral.argo.io/hibernated-replicas"
+
+// reconcileHibernation puts the environment to sleep or wakes it up following the hibernation
+// schedule, it reports whether the request was handled and the phase handlers must be skipped
+func (r *EphemeralApplicationReconciler) reconcileHibernation(ctx context.Context, ephApp *ephemeralv1alpha1.EphemeralApplication) (ctrl.Result, bool, error) {
+	logger := log.FromContext(ctx)
+
+	hibernate := false
+	var next *metav1.Time
+	if ephApp.Spec.Hibernation != nil {
+		var nextTransition time.Time
+		var err error
+		hibernate, nextTransition, err = ephApp.Spec.Hibernation.Hibernating(time.Now(), ephApp.WakeRequestedAt())
+		if err != nil {
+			result, err := r.updateStatusWithError(ctx, ephApp, ephemeralv1alpha1.PhaseFailed, "Invalid hibernation schedule", err)
+			return result, true, err
+		}
+		next = &metav1.Time{Time: nextTransition}
+	}
+
+	hibernating := ephApp.Status.Phase == ephemeralv1alpha1.PhaseHibernating
+	switch {
+	case hibernate && !hibernating:
+		logger.Info("hibernating ephemeral environment", "namespace", ephApp.Status.Namespace, "wakeAt", next)
+		if err := r.setSelfHeal(ctx, ephApp, false); err != nil {
+			return ctrl.Result{}, true, err
+		}
+		if err := r.scaleWorkloads(ctx, ephApp, true); err != nil {
+			return ctrl.Result{}, true, err
+		}
+
+		now := metav1.Now()
+		ephApp.Status.Phase = ephemeralv1alpha1.PhaseHibernating
+		ephApp.Status.HibernatedAt = &now
+		ephApp.Status.Message = "Ephemeral environment is hibernating"
+		r.setCondition(ephApp, "Ready", metav1.ConditionFalse, "Hibernating", "Workloads are scaled to zero")
+
+	case !hibernate && hibernating:
+		logger.Info("waking up ephemeral environment", "namespace", ephApp.Status.Namespace)
+		if err := r.scaleWorkloads(ctx, ephApp, false); err != nil {
+			return ctrl.Result{}, true, err
+		}
+		if err := r.setSelfHeal(ctx, ephApp, true); err != nil {
+			return ctrl.Result{}, true, err
+		}
+
+		// Wait for the workloads to become healthy again
+		ephApp.Status.Phase = ephemeralv1alpha1.PhaseUpdating
+		ephApp.Status.HibernatedAt = nil
+		ephApp.Status.Message = "Waking up from hibernation, waiting for sync"
+		r.setCondition(ephApp, "Ready", metav1.ConditionFalse, "WakingUp", "Restoring workloads")
+
+	case hibernating:
+		// Still asleep, only the next transition may have moved
+		if next.Equal(ephApp.Status.NextHibernationTransition) {
+			return ctrl.Result{RequeueAfter: r.requeueUntil(next)}, true, nil
+		}
+
+	default:
+		if !next.Equal(ephApp.Status.NextHibernationTransition) {
+			ephApp.Status.NextHibernationTransition = next
+			if err := r.updateStatus(ctx, ephApp); err != nil {
+				return ctrl.Result{}, true, err
+			}
+		}
+		return ctrl.Result{}, false, nil
+	}
+
+	ephApp.Status.NextHibernationTransition = next
+	if err := r.updateStatus(ctx, ephApp); err != nil {
+		return ctrl.Result{}, true, err
+	}
+
+	if !hibernate {
+		return ctrl.Result{RequeueAfter: r.syncRequeueInterval()}, true, nil
+	}
+	return ctrl.Result{RequeueAfter: r.requeueUntil(next)}, true, nil
+}
+
+// requeueUntil returns the delay until the given time, at most the reconcile interval
+func (r *EphemeralApplicationReconciler) requeueUntil(at *metav1.Time) time.Duration {
+	if at == nil {
+		return r.Config.ReconcileInterval
+	}
+	delay := time.Until(at.Time)
+	if delay > r.Config.ReconcileInterval {
+		return r.Config.ReconcileInterval
+	}
+	if delay < time.Second {
+		return time.Second
+	}
+	return delay
+}
+
+// scaleWorkloads scales the Deployments and StatefulSets of the environment to zero, recording
+// their replicas in an annotation, or restores the recorded replicas
+func (r *EphemeralApplicationReconciler) scaleWorkloads(ctx context.Context, ephApp *ephemeralv1alpha1.EphemeralApplication, down bool) error {
+	target, err := r.targetCluster(ctx, ephApp)
+	if err != nil {
+		return fmt.Errorf("failed to resolve destination cluster: %w", err)
+	}
+	namespace := ephApp.Status.Namespace
+
+	deployments := &appsv1.DeploymentList{}
+	if err := target.Client.List(ctx, deployments, client.InNamespace(namespace)); err != nil {
+		return fmt.Errorf("failed to list deployments: %w", err)
+	}
+	for i := range deployments.Items {
+		deployment := &deployments.Items[i]
+		if err := scaleWorkload(ctx, target.Client, deployment, &deployment.Spec.Replicas, down); err != nil {
+			return fmt.Errorf("failed to scale deployment %s: %w", deployment.Name, err)
+		}
+	}
+
+	statefulSets := &appsv1.StatefulSetList{}
+	if err := target.Client.List(ctx, statefulSets, client.InNamespace(namespace)); err != nil {
+		return fmt.Errorf("failed to list statefulsets: %w", err)
+	}
+	for i := range statefulSets.Items {
+		statefulSet := &statefulSets.Items[i]
+		if err := scaleWorkload(ctx, target.Client, statefulSet, &statefulSet.Spec.Replicas, down); err != nil {
+			return fmt.Errorf("failed to scale statefulset %s: %w", statefulSet.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// scaleWorkload scales a single workload, replicas points to the replicas field of its spec
+func scaleWorkload(ctx context.Context, c client.Client, obj client.Object, replicas **int32, down bool) error {
+	annotations := obj.GetAnnotations()
+	recorded, isHibernated := annotations[hibernatedReplicasAnnotation]
+
+	if down {
+		// Workloads already at zero are left untouched so they stay at zero on wake
+		if isHibernated || (*replicas != nil && **replicas == 0) {
+			return nil
+		}
+		current := int32(1)
+		if *replicas != nil {
+			current = **replicas
+		}
+		if annotations == nil {
+			annotations = make(map[string]string)
+		}
+		annotations[hibernatedReplicasAnnotation] = strconv.Itoa(int(current))
+		obj.SetAnnotations(annotations)
+		zero := int32(0)
+		*replicas = &zero
+		return c.Update(ctx, obj)
+	}
+
+	if !isHibernated {
+		return nil
+	}
+	restored, err := strconv.ParseInt(recorded, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid %s annotation %q: %w", hibernatedReplicasAnnotation, recorded, err)
+	}
+	delete(annotations, hibernatedReplicasAnnotation)
+	obj.SetAnnotations(annotations)
+	count := int32(restored)
+	*replicas = &count
+	return c.Update(ctx, obj)
+}
+
+// setSelfHeal disables self-heal on the ArgoCD Applications so scaled down workloads are not
+// reverted, or restores the sync policy of the EphemeralApplication
+func (r *EphemeralApplicationReconciler) setSelfHeal(ctx context.Context, ephApp *ephemeralv1alpha1.EphemeralApplication, enabled bool) error {
+	namespace := ephApp.Status.Namespace
+	for _, component := range ephApp.Spec.ResolvedComponents() {
+		name := componentApplicationName(ephApp, component)
+		if enabled {
+			if err := r.updateComponentApplication(ctx, ephApp, component, name, namespace); err != nil {
+				return err
+			}
+			continue
+		}
+
+		appQuery := application.ApplicationQuery{
+			Name:         &name,
+			AppNamespace: &r.Config.ArgoNamespace,
+		}
+		argoApp, err := r.ArgoClient.GetApplication(ctx, appQuery)
+		if err != nil {
+			return fmt.Errorf("failed to get ArgoCD application %s: %w", name, err)
+		}
+		automated := argoApp.Spec.SyncPolicy != nil && argoApp.Spec.SyncPolicy.Automated != nil
+		if !automated || !argoApp.Spec.SyncPolicy.Automated.SelfHeal {
+			continue
+		}
+
+		argoApp.Spec.SyncPolicy.Automated.SelfHeal = false
+		if _, err := r.ArgoClient.UpdateApplication(ctx, &application.ApplicationUpdateRequest{
+			Application: argoApp,
+		}); err != nil {
+			return fmt.Errorf("failed to disable self-heal on ArgoCD application %s: %w", name, err)
+		}
+	}
+	return nil
+}
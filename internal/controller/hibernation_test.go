@@ -0,0 +1,258 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	ephemeralv1alpha1 "github.com/jbarea/argo-ephemeral-operator/api/v1alpha1"
+	"github.com/jbarea/argo-ephemeral-operator/internal/config"
+)
+
+func TestReconcile_HibernatesAndWakes(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = networkingv1.AddToScheme(scheme)
+	_ = appsv1.AddToScheme(scheme)
+	_ = ephemeralv1alpha1.AddToScheme(scheme)
+
+	// Went to sleep an hour ago and wakes up in an hour
+	now := time.Now().UTC()
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "preview",
+			Namespace:  "default",
+			Generation: 1,
+			Finalizers: []string{finalizerName},
+		},
+		Spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+			RepoURL:        "https://github.com/example/app.git",
+			Path:           "manifests",
+			ExpirationDate: &metav1.Time{Time: now.Add(24 * time.Hour)},
+			SyncPolicy: &ephemeralv1alpha1.SyncPolicy{
+				Automated: &ephemeralv1alpha1.AutomatedSyncPolicy{SelfHeal: true},
+			},
+			Hibernation: &ephemeralv1alpha1.HibernationSpec{
+				SleepSchedule: fmt.Sprintf("0 %d * * *", now.Add(-time.Hour).Hour()),
+				WakeSchedule:  fmt.Sprintf("0 %d * * *", now.Add(time.Hour).Hour()),
+			},
+		},
+		Status: ephemeralv1alpha1.EphemeralApplicationStatus{
+			Phase:               ephemeralv1alpha1.PhaseActive,
+			Namespace:           "ephemeral-test",
+			ArgoApplicationName: "preview",
+			ObservedGeneration:  1,
+		},
+	}
+
+	replicas := int32(3)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "ephemeral-test"},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(ephApp, deployment).
+		WithStatusSubresource(ephApp).
+		Build()
+
+	argoClient := newMockArgoClient(&v1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "preview"},
+		Spec: v1alpha1.ApplicationSpec{
+			SyncPolicy: &v1alpha1.SyncPolicy{
+				Automated: &v1alpha1.SyncPolicyAutomated{SelfHeal: true},
+			},
+		},
+	})
+
+	reconciler := &EphemeralApplicationReconciler{
+		Client:     fakeClient,
+		Scheme:     scheme,
+		ArgoClient: argoClient,
+		Config:     &config.Config{ArgoNamespace: "argocd", ReconcileInterval: time.Minute},
+	}
+
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ephApp)}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	updated := &ephemeralv1alpha1.EphemeralApplication{}
+	if err := fakeClient.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("failed to get EphemeralApplication: %v", err)
+	}
+	if updated.Status.Phase != ephemeralv1alpha1.PhaseHibernating {
+		t.Fatalf("expected phase Hibernating, got %s", updated.Status.Phase)
+	}
+	if argoClient.apps["preview"].Spec.SyncPolicy.Automated.SelfHeal {
+		t.Error("expected self-heal to be disabled while hibernating")
+	}
+
+	scaled := &appsv1.Deployment{}
+	if err := fakeClient.Get(ctx, client.ObjectKeyFromObject(deployment), scaled); err != nil {
+		t.Fatalf("failed to get deployment: %v", err)
+	}
+	if *scaled.Spec.Replicas != 0 {
+		t.Errorf("expected deployment scaled to zero, got %d replicas", *scaled.Spec.Replicas)
+	}
+	if got := scaled.Annotations[hibernatedReplicasAnnotation]; got != "3" {
+		t.Errorf("expected recorded replicas '3', got '%s'", got)
+	}
+
+	// Wake up manually
+	updated.Annotations = map[string]string{
+		ephemeralv1alpha1.WakeRequestedAnnotation: now.Format(time.RFC3339),
+	}
+	if err := fakeClient.Update(ctx, updated); err != nil {
+		t.Fatalf("failed to update EphemeralApplication: %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	if err := fakeClient.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("failed to get EphemeralApplication: %v", err)
+	}
+	if updated.Status.Phase != ephemeralv1alpha1.PhaseUpdating {
+		t.Errorf("expected phase Updating after waking up, got %s", updated.Status.Phase)
+	}
+	if !argoClient.apps["preview"].Spec.SyncPolicy.Automated.SelfHeal {
+		t.Error("expected self-heal to be restored")
+	}
+	if err := fakeClient.Get(ctx, client.ObjectKeyFromObject(deployment), scaled); err != nil {
+		t.Fatalf("failed to get deployment: %v", err)
+	}
+	if *scaled.Spec.Replicas != 3 {
+		t.Errorf("expected 3 replicas restored, got %d", *scaled.Spec.Replicas)
+	}
+	if _, ok := scaled.Annotations[hibernatedReplicasAnnotation]; ok {
+		t.Error("expected the recorded replicas annotation to be removed")
+	}
+}
+
+func TestReconcile_RollsOutTemplateChangesAfterWaking(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = networkingv1.AddToScheme(scheme)
+	_ = appsv1.AddToScheme(scheme)
+	_ = ephemeralv1alpha1.AddToScheme(scheme)
+
+	// The template was edited while the environment sleeps until an hour from now
+	now := time.Now().UTC()
+	template := &ephemeralv1alpha1.EphemeralApplicationTemplate{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "backend",
+			Namespace:  "default",
+			Generation: 2,
+		},
+		Spec: ephemeralv1alpha1.EphemeralApplicationTemplateSpec{
+			RepoURL: "https://github.com/example/backend.git",
+			Path:    "deploy/preview",
+		},
+	}
+	hibernatedAt := metav1.NewTime(now.Add(-time.Hour))
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "preview",
+			Namespace:  "default",
+			Generation: 1,
+			Finalizers: []string{finalizerName},
+		},
+		Spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+			TemplateRef:    &ephemeralv1alpha1.TemplateReference{Name: "backend"},
+			ExpirationDate: &metav1.Time{Time: now.Add(24 * time.Hour)},
+			Hibernation: &ephemeralv1alpha1.HibernationSpec{
+				SleepSchedule: fmt.Sprintf("0 %d * * *", now.Add(-time.Hour).Hour()),
+				WakeSchedule:  fmt.Sprintf("0 %d * * *", now.Add(time.Hour).Hour()),
+			},
+		},
+		Status: ephemeralv1alpha1.EphemeralApplicationStatus{
+			Phase:               ephemeralv1alpha1.PhaseHibernating,
+			Namespace:           "ephemeral-test",
+			ArgoApplicationName: "preview",
+			ObservedGeneration:  1,
+			TemplateGeneration:  1,
+			HibernatedAt:        &hibernatedAt,
+		},
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(template, ephApp).
+		WithStatusSubresource(ephApp).
+		Build()
+
+	argoClient := newMockArgoClient(&v1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "preview"},
+		Spec: v1alpha1.ApplicationSpec{
+			Source: &v1alpha1.ApplicationSource{
+				RepoURL: "https://github.com/example/backend.git",
+				Path:    "manifests",
+			},
+		},
+	})
+
+	reconciler := &EphemeralApplicationReconciler{
+		Client:     fakeClient,
+		Scheme:     scheme,
+		ArgoClient: argoClient,
+		Config:     &config.Config{ArgoNamespace: "argocd", ReconcileInterval: time.Minute},
+	}
+
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ephApp)}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	updated := &ephemeralv1alpha1.EphemeralApplication{}
+	if err := fakeClient.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("failed to get EphemeralApplication: %v", err)
+	}
+	if updated.Status.Phase != ephemeralv1alpha1.PhaseHibernating {
+		t.Fatalf("expected phase Hibernating, got %s", updated.Status.Phase)
+	}
+	if updated.Status.TemplateGeneration != 1 {
+		t.Errorf("expected templateGeneration 1 while hibernating, got %d", updated.Status.TemplateGeneration)
+	}
+	if got := argoClient.apps["preview"].Spec.Source.Path; got != "manifests" {
+		t.Errorf("expected the template change not to be rolled out while hibernating, got path '%s'", got)
+	}
+
+	// Wake up manually, the template change is rolled out afterwards
+	updated.Annotations = map[string]string{
+		ephemeralv1alpha1.WakeRequestedAnnotation: now.Format(time.RFC3339),
+	}
+	if err := fakeClient.Update(ctx, updated); err != nil {
+		t.Fatalf("failed to update EphemeralApplication: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := reconciler.Reconcile(ctx, req); err != nil {
+			t.Fatalf("Reconcile failed: %v", err)
+		}
+	}
+
+	if got := argoClient.apps["preview"].Spec.Source.Path; got != "deploy/preview" {
+		t.Errorf("expected path 'deploy/preview' after waking up, got '%s'", got)
+	}
+	if err := fakeClient.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("failed to get EphemeralApplication: %v", err)
+	}
+	if updated.Status.TemplateGeneration != 2 || updated.Status.Phase != ephemeralv1alpha1.PhaseUpdating {
+		t.Errorf("expected templateGeneration 2 and phase Updating, got %d and %s",
+			updated.Status.TemplateGeneration, updated.Status.Phase)
+	}
+}
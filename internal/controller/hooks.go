@@ -0,0 +1,294 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	ephemeralv1alpha1 "github.com/jbarea/argo-ephemeral-operator/api/v1alpha1"
+)
+
+const (
+	// hookStageLabel marks the Jobs created for the hooks with the stage they run at
+	hookStageLabel = "ephemeral.argo.io/hook"
+
+	// defaultHookTimeout is the activeDeadlineSeconds of the hook Jobs that do not set one, a
+	// stuck hook fails once it passes instead of blocking the environment or its deletion
+	defaultHookTimeout = 30 * time.Minute
+)
+
+// HookJobSelector selects the Jobs running hooks, they are the only Jobs the operator caches
+func HookJobSelector() labels.Selector {
+	exists, _ := labels.NewRequirement(hookStageLabel, selection.Exists, nil)
+	return labels.NewSelector().Add(*exists)
+}
+
+// runHooks runs the hooks of a stage one after the other and records their status, a hook
+// that succeeded or failed is never run again. It returns the first hook that has not
+// succeeded yet, nil once all of them did
+func (r *EphemeralApplicationReconciler) runHooks(
+	ctx context.Context,
+	c client.Client,
+	ephApp *ephemeralv1alpha1.EphemeralApplication,
+	namespace string,
+	stage ephemeralv1alpha1.HookStage,
+) (*ephemeralv1alpha1.HookStatus, error) {
+	logger := log.FromContext(ctx)
+	hooks := ephApp.Spec.Hooks.Stage(stage)
+	pruneHookStatus(ephApp, stage, hooks)
+
+	for _, hook := range hooks {
+		status := findHook(ephApp, stage, hook.Name)
+		if status != nil && status.Phase != ephemeralv1alpha1.HookRunning {
+			if status.Phase == ephemeralv1alpha1.HookSucceeded {
+				continue
+			}
+			return status.DeepCopy(), nil
+		}
+
+		job := &batchv1.Job{}
+		key := client.ObjectKey{Namespace: namespace, Name: stage.JobName(hook.Name)}
+		if err := c.Get(ctx, key, job); err != nil {
+			if !errors.IsNotFound(err) {
+				return nil, fmt.Errorf("failed to get job %s: %w", key.Name, err)
+			}
+
+			logger.Info("starting hook", "stage", stage, "job", key.Name)
+			if err := c.Create(ctx, buildHookJob(ephApp, hook, stage, namespace)); err != nil && !errors.IsAlreadyExists(err) {
+				return nil, fmt.Errorf("failed to create job %s: %w", key.Name, err)
+			}
+			now := metav1.Now()
+			started := ephemeralv1alpha1.HookStatus{
+				Stage:     stage,
+				Name:      hook.Name,
+				JobName:   key.Name,
+				Phase:     ephemeralv1alpha1.HookRunning,
+				StartedAt: &now,
+			}
+			recordHook(ephApp, started)
+			return &started, nil
+		}
+
+		observed := hookStatus(job, stage, hook.Name)
+		if status != nil && observed.StartedAt == nil {
+			observed.StartedAt = status.StartedAt
+		}
+		recordHook(ephApp, observed)
+		if observed.Phase != ephemeralv1alpha1.HookSucceeded {
+			if observed.Phase == ephemeralv1alpha1.HookFailed {
+				logger.Info("hook failed", "stage", stage, "job", key.Name, "reason", observed.Message)
+			}
+			return &observed, nil
+		}
+	}
+	return nil, nil
+}
+
+// buildHookJob builds the Job running a hook, it carries the owner labels of the environment
+// so its changes are mapped back to the EphemeralApplication. Jobs without a deadline get the
+// default one, a Job failed for exceeding it is reported like any other failed hook
+func buildHookJob(
+	ephApp *ephemeralv1alpha1.EphemeralApplication,
+	hook ephemeralv1alpha1.Hook,
+	stage ephemeralv1alpha1.HookStage,
+	namespace string,
+) *batchv1.Job {
+	labels := applicationLabels(ephApp)
+	labels[hookStageLabel] = string(stage)
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      stage.JobName(hook.Name),
+			Namespace: namespace,
+			Labels:    labels,
+		},
+		Spec: *hook.Spec.DeepCopy(),
+	}
+
+	if job.Spec.Template.Spec.RestartPolicy == "" {
+		job.Spec.Template.Spec.RestartPolicy = corev1.RestartPolicyNever
+	}
+	if job.Spec.ActiveDeadlineSeconds == nil {
+		deadline := int64(defaultHookTimeout.Seconds())
+		job.Spec.ActiveDeadlineSeconds = &deadline
+	}
+	return job
+}
+
+// hookStatus reads the status of a hook from the conditions of its Job
+func hookStatus(job *batchv1.Job, stage ephemeralv1alpha1.HookStage, name string) ephemeralv1alpha1.HookStatus {
+	status := ephemeralv1alpha1.HookStatus{
+		Stage:     stage,
+		Name:      name,
+		JobName:   job.Name,
+		Phase:     ephemeralv1alpha1.HookRunning,
+		StartedAt: job.Status.StartTime,
+	}
+
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			status.Phase = ephemeralv1alpha1.HookSucceeded
+		case batchv1.JobFailed:
+			status.Phase = ephemeralv1alpha1.HookFailed
+			status.Message = condition.Message
+		default:
+			continue
+		}
+		completedAt := condition.LastTransitionTime
+		status.CompletedAt = &completedAt
+		break
+	}
+	return status
+}
+
+// findHook returns the status of a hook, nil when it was never started
+func findHook(ephApp *ephemeralv1alpha1.EphemeralApplication, stage ephemeralv1alpha1.HookStage, name string) *ephemeralv1alpha1.HookStatus {
+	for i := range ephApp.Status.Hooks {
+		hook := &ephApp.Status.Hooks[i]
+		if hook.Stage == stage && hook.Name == name {
+			return hook
+		}
+	}
+	return nil
+}
+
+// recordHook records the status of a hook, replacing the previous one
+func recordHook(ephApp *ephemeralv1alpha1.EphemeralApplication, status ephemeralv1alpha1.HookStatus) {
+	if existing := findHook(ephApp, status.Stage, status.Name); existing != nil {
+		*existing = status
+		return
+	}
+	ephApp.Status.Hooks = append(ephApp.Status.Hooks, status)
+}
+
+// pruneHookStatus forgets the hooks of a stage that are no longer in the spec
+func pruneHookStatus(ephApp *ephemeralv1alpha1.EphemeralApplication, stage ephemeralv1alpha1.HookStage, hooks []ephemeralv1alpha1.Hook) {
+	ephApp.Status.Hooks = slices.DeleteFunc(ephApp.Status.Hooks, func(status ephemeralv1alpha1.HookStatus) bool {
+		return status.Stage == stage && !slices.ContainsFunc(hooks, func(hook ephemeralv1alpha1.Hook) bool {
+			return hook.Name == status.Name
+		})
+	})
+	if len(ephApp.Status.Hooks) == 0 {
+		ephApp.Status.Hooks = nil
+	}
+}
+
+// hookFailure returns the error failing the environment when a hook failed, nil otherwise
+func hookFailure(status *ephemeralv1alpha1.HookStatus) error {
+	if status == nil || status.Phase != ephemeralv1alpha1.HookFailed {
+		return nil
+	}
+	return fmt.Errorf("job %s failed: %s", status.JobName, status.Message)
+}
+
+// reconcileHooks runs the hooks of a stage in the destination cluster, it reports whether the
+// environment has to wait for them and the phase handlers must stop. A failed hook fails the environment
+func (r *EphemeralApplicationReconciler) reconcileHooks(
+	ctx context.Context,
+	ephApp *ephemeralv1alpha1.EphemeralApplication,
+	stage ephemeralv1alpha1.HookStage,
+) (ctrl.Result, bool, error) {
+	if len(ephApp.Spec.Hooks.Stage(stage)) == 0 {
+		pruneHookStatus(ephApp, stage, nil)
+		return ctrl.Result{}, false, nil
+	}
+
+	target, err := r.targetCluster(ctx, ephApp)
+	if err != nil {
+		result, err := r.updateStatusWithError(ctx, ephApp, ephemeralv1alpha1.PhaseFailed, "Failed to resolve destination cluster", err)
+		return result, true, err
+	}
+
+	waiting, err := r.runHooks(ctx, target.Client, ephApp, ephApp.Status.Namespace, stage)
+	if err != nil {
+		result, err := r.updateStatusWithError(ctx, ephApp, ephemeralv1alpha1.PhaseFailed, fmt.Sprintf("Failed to run %s hooks", stage), err)
+		return result, true, err
+	}
+	if err := hookFailure(waiting); err != nil {
+		result, err := r.updateStatusWithError(ctx, ephApp, ephemeralv1alpha1.PhaseFailed, fmt.Sprintf("%s hook failed", stage), err)
+		return result, true, err
+	}
+	if waiting != nil {
+		result, err := r.waitForHook(ctx, ephApp, waiting)
+		return result, true, err
+	}
+	return ctrl.Result{}, false, nil
+}
+
+// runPreDeleteHooks runs the preDelete hooks of a deployed environment, it reports whether the
+// deletion has to wait for them. A hook that fails or can not be started does not block the deletion
+func (r *EphemeralApplicationReconciler) runPreDeleteHooks(ctx context.Context, ephApp *ephemeralv1alpha1.EphemeralApplication) (ctrl.Result, bool, error) {
+	logger := log.FromContext(ctx)
+
+	hooks := ephApp.Spec.Hooks.Stage(ephemeralv1alpha1.HookPreDelete)
+	if len(hooks) == 0 || ephApp.Status.Namespace == "" || len(argoApplicationNames(ephApp)) == 0 {
+		return ctrl.Result{}, false, nil
+	}
+
+	target, err := r.targetCluster(ctx, ephApp)
+	if err != nil {
+		logger.Error(err, "failed to resolve destination cluster, the preDelete hooks are skipped")
+		return ctrl.Result{}, false, nil
+	}
+
+	waiting, err := r.runHooks(ctx, target.Client, ephApp, ephApp.Status.Namespace, ephemeralv1alpha1.HookPreDelete)
+	if err != nil {
+		// The namespace may already be gone or terminating
+		if errors.IsNotFound(err) || errors.IsForbidden(err) {
+			logger.Error(err, "failed to run preDelete hooks, deleting the environment anyway")
+			return ctrl.Result{}, false, nil
+		}
+		return ctrl.Result{}, true, err
+	}
+	if err := hookFailure(waiting); err != nil {
+		logger.Error(err, "preDelete hook failed, deleting the environment anyway")
+		return ctrl.Result{}, false, nil
+	}
+	if waiting != nil {
+		result, err := r.waitForHook(ctx, ephApp, waiting)
+		return result, true, err
+	}
+	return ctrl.Result{}, false, nil
+}
+
+// waitForHook records that the environment waits for a hook and checks it again later, the
+// status is only written when it changed since every update triggers another reconcile
+func (r *EphemeralApplicationReconciler) waitForHook(
+	ctx context.Context,
+	ephApp *ephemeralv1alpha1.EphemeralApplication,
+	hook *ephemeralv1alpha1.HookStatus,
+) (ctrl.Result, error) {
+	message := fmt.Sprintf("Waiting for %s hook %s", hook.Stage, hook.Name)
+	ephApp.Status.Message = message
+	if existing := meta.FindStatusCondition(ephApp.Status.Conditions, "Ready"); existing == nil ||
+		existing.Reason != "RunningHooks" || existing.Message != message {
+		r.setCondition(ephApp, "Ready", metav1.ConditionFalse, "RunningHooks", message)
+	}
+
+	if err := r.updateStatus(ctx, ephApp); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: pollInterval}, nil
+}
+
+// isHookJob filters the watched Jobs to the ones running hooks
+func isHookJob(obj client.Object) bool {
+	_, ok := obj.GetLabels()[hookStageLabel]
+	return ok
+}
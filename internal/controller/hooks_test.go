@@ -0,0 +1,280 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	ephemeralv1alpha1 "github.com/jbarea/argo-ephemeral-operator/api/v1alpha1"
+	"github.com/jbarea/argo-ephemeral-operator/internal/config"
+	"github.com/jbarea/argo-ephemeral-operator/internal/naming"
+)
+
+func hookJobSpec() batchv1.JobSpec {
+	return batchv1.JobSpec{
+		Template: corev1.PodTemplateSpec{
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "hook", Image: "busybox"}},
+			},
+		},
+	}
+}
+
+// finishJob marks a hook Job as completed or failed like the Job controller would
+func finishJob(t *testing.T, c client.Client, namespace, name string, conditionType batchv1.JobConditionType) {
+	t.Helper()
+	job := &batchv1.Job{}
+	if err := c.Get(context.Background(), client.ObjectKey{Namespace: namespace, Name: name}, job); err != nil {
+		t.Fatalf("failed to get job %s: %v", name, err)
+	}
+	job.Status.Conditions = append(job.Status.Conditions, batchv1.JobCondition{
+		Type:               conditionType,
+		Status:             corev1.ConditionTrue,
+		Message:            "Job has reached the specified backoff limit",
+		LastTransitionTime: metav1.Now(),
+	})
+	if err := c.Status().Update(context.Background(), job); err != nil {
+		t.Fatalf("failed to update job %s: %v", name, err)
+	}
+}
+
+func TestReconcile_RunsHooks(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = batchv1.AddToScheme(scheme)
+	_ = networkingv1.AddToScheme(scheme)
+	_ = ephemeralv1alpha1.AddToScheme(scheme)
+
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "preview",
+			Namespace:  "default",
+			Finalizers: []string{finalizerName},
+		},
+		Spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+			RepoURL:       "https://github.com/example/app.git",
+			Path:          "manifests",
+			TTL:           &metav1.Duration{Duration: time.Hour},
+			NamespaceName: "ephemeral-preview",
+			Hooks: &ephemeralv1alpha1.Hooks{
+				PreSync: []ephemeralv1alpha1.Hook{
+					{Name: "migrate", Spec: hookJobSpec()},
+					{Name: "seed", Spec: hookJobSpec()},
+				},
+				PostReady: []ephemeralv1alpha1.Hook{{Name: "smoke", Spec: hookJobSpec()}},
+				PreDelete: []ephemeralv1alpha1.Hook{{Name: "dump", Spec: hookJobSpec()}},
+			},
+		},
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(ephApp).
+		WithStatusSubresource(ephApp, &batchv1.Job{}).
+		Build()
+
+	argoClient := newMockArgoClient()
+	reconciler := &EphemeralApplicationReconciler{
+		Client:        fakeClient,
+		Scheme:        scheme,
+		ArgoClient:    argoClient,
+		Config:        &config.Config{ArgoNamespace: "argocd", ReconcileInterval: time.Minute},
+		NameGenerator: naming.NewDefaultGenerator(),
+	}
+
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ephApp)}
+	reconcile := func() *ephemeralv1alpha1.EphemeralApplication {
+		t.Helper()
+		if _, err := reconciler.Reconcile(ctx, req); err != nil {
+			t.Fatalf("Reconcile failed: %v", err)
+		}
+		updated := &ephemeralv1alpha1.EphemeralApplication{}
+		if err := fakeClient.Get(ctx, req.NamespacedName, updated); err != nil {
+			if errors.IsNotFound(err) {
+				return nil
+			}
+			t.Fatalf("failed to get EphemeralApplication: %v", err)
+		}
+		return updated
+	}
+	namespace := "ephemeral-preview"
+
+	// The preSync hooks run one after the other before the application is created
+	updated := reconcile()
+	if updated.Status.Phase != ephemeralv1alpha1.PhasePending || updated.Status.Namespace != namespace {
+		t.Fatalf("expected to wait in Pending with namespace %s, got %s %q", namespace, updated.Status.Phase, updated.Status.Namespace)
+	}
+	if _, ok := argoClient.apps["preview"]; ok {
+		t.Fatal("expected the ArgoCD application to wait for the preSync hooks")
+	}
+	job := &batchv1.Job{}
+	if err := fakeClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: "presync-migrate"}, job); err != nil {
+		t.Fatalf("expected the migrate job to be created: %v", err)
+	}
+	if job.Labels[hookStageLabel] != "PreSync" || job.Labels[ownerLabel] != "preview" {
+		t.Errorf("expected hook and owner labels, got %v", job.Labels)
+	}
+	if job.Spec.Template.Spec.RestartPolicy != corev1.RestartPolicyNever {
+		t.Errorf("expected restart policy Never, got %s", job.Spec.Template.Spec.RestartPolicy)
+	}
+	if job.Spec.ActiveDeadlineSeconds == nil || *job.Spec.ActiveDeadlineSeconds != 1800 {
+		t.Errorf("expected the default deadline of 1800 seconds, got %v", job.Spec.ActiveDeadlineSeconds)
+	}
+	if err := fakeClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: "presync-seed"}, &batchv1.Job{}); !errors.IsNotFound(err) {
+		t.Errorf("expected the seed job to wait for the migrate job, got %v", err)
+	}
+
+	finishJob(t, fakeClient, namespace, "presync-migrate", batchv1.JobComplete)
+	updated = reconcile()
+	if hook := findHook(updated, ephemeralv1alpha1.HookPreSync, "migrate"); hook == nil || hook.Phase != ephemeralv1alpha1.HookSucceeded {
+		t.Errorf("expected the migrate hook to succeed, got %v", hook)
+	}
+	if updated.Status.Phase != ephemeralv1alpha1.PhasePending {
+		t.Fatalf("expected to wait for the seed job, got %s", updated.Status.Phase)
+	}
+
+	finishJob(t, fakeClient, namespace, "presync-seed", batchv1.JobComplete)
+	updated = reconcile()
+	if updated.Status.Phase != ephemeralv1alpha1.PhaseCreating {
+		t.Fatalf("expected phase Creating once the preSync hooks succeeded, got %s: %s", updated.Status.Phase, updated.Status.Message)
+	}
+	argoApp, ok := argoClient.apps["preview"]
+	if !ok {
+		t.Fatal("expected the ArgoCD application to be created")
+	}
+
+	// The environment only becomes active once the postReady hooks succeeded
+	argoApp.Status = v1alpha1.ApplicationStatus{
+		Sync:   v1alpha1.SyncStatus{Status: "Synced"},
+		Health: v1alpha1.HealthStatus{Status: "Healthy"},
+	}
+	updated = reconcile()
+	if updated.Status.Phase != ephemeralv1alpha1.PhaseCreating {
+		t.Fatalf("expected to wait for the postReady hooks, got %s", updated.Status.Phase)
+	}
+	if hook := findHook(updated, ephemeralv1alpha1.HookPostReady, "smoke"); hook == nil || hook.Phase != ephemeralv1alpha1.HookRunning {
+		t.Errorf("expected the smoke hook to be running, got %v", hook)
+	}
+
+	finishJob(t, fakeClient, namespace, "postready-smoke", batchv1.JobComplete)
+	updated = reconcile()
+	if updated.Status.Phase != ephemeralv1alpha1.PhaseActive {
+		t.Fatalf("expected phase Active, got %s: %s", updated.Status.Phase, updated.Status.Message)
+	}
+
+	// The deletion waits for the preDelete hooks while the application is still deployed
+	if err := fakeClient.Delete(ctx, updated); err != nil {
+		t.Fatalf("failed to delete EphemeralApplication: %v", err)
+	}
+	updated = reconcile()
+	if updated == nil {
+		t.Fatal("expected the deletion to wait for the preDelete hooks")
+	}
+	if _, ok := argoClient.apps["preview"]; !ok {
+		t.Error("expected the ArgoCD application to be kept while the preDelete hooks run")
+	}
+
+	// A failed preDelete hook does not block the deletion
+	finishJob(t, fakeClient, namespace, "predelete-dump", batchv1.JobFailed)
+	if updated = reconcile(); updated != nil {
+		t.Errorf("expected the EphemeralApplication to be deleted, finalizers %v", updated.Finalizers)
+	}
+	if _, ok := argoClient.apps["preview"]; ok {
+		t.Error("expected the ArgoCD application to be deleted")
+	}
+}
+
+func TestReconcile_FailedPreSyncHookFailsEnvironment(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = batchv1.AddToScheme(scheme)
+	_ = networkingv1.AddToScheme(scheme)
+	_ = ephemeralv1alpha1.AddToScheme(scheme)
+
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "preview",
+			Namespace:  "default",
+			Finalizers: []string{finalizerName},
+		},
+		Spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+			RepoURL:       "https://github.com/example/app.git",
+			Path:          "manifests",
+			TTL:           &metav1.Duration{Duration: time.Hour},
+			NamespaceName: "ephemeral-preview",
+			Hooks: &ephemeralv1alpha1.Hooks{
+				PreSync: []ephemeralv1alpha1.Hook{{Name: "migrate", Spec: hookJobSpec()}},
+			},
+		},
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(ephApp).
+		WithStatusSubresource(ephApp, &batchv1.Job{}).
+		Build()
+
+	argoClient := newMockArgoClient()
+	reconciler := &EphemeralApplicationReconciler{
+		Client:        fakeClient,
+		Scheme:        scheme,
+		ArgoClient:    argoClient,
+		Config:        &config.Config{ArgoNamespace: "argocd", ReconcileInterval: time.Minute},
+		NameGenerator: naming.NewDefaultGenerator(),
+	}
+
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ephApp)}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	finishJob(t, fakeClient, "ephemeral-preview", "presync-migrate", batchv1.JobFailed)
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	updated := &ephemeralv1alpha1.EphemeralApplication{}
+	if err := fakeClient.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("failed to get EphemeralApplication: %v", err)
+	}
+	if updated.Status.Phase != ephemeralv1alpha1.PhaseFailed {
+		t.Fatalf("expected phase Failed, got %s", updated.Status.Phase)
+	}
+	hook := findHook(updated, ephemeralv1alpha1.HookPreSync, "migrate")
+	if hook == nil || hook.Phase != ephemeralv1alpha1.HookFailed || hook.Message == "" || hook.CompletedAt == nil {
+		t.Errorf("expected the failure to be recorded, got %v", hook)
+	}
+	if len(argoClient.apps) != 0 {
+		t.Errorf("expected no ArgoCD application, got %v", argoClient.apps)
+	}
+}
+
+func TestBuildHookJob_KeepsDeadline(t *testing.T) {
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "preview", Namespace: "default"},
+	}
+	spec := hookJobSpec()
+	deadline := int64(300)
+	spec.ActiveDeadlineSeconds = &deadline
+
+	job := buildHookJob(ephApp, ephemeralv1alpha1.Hook{Name: "dump", Spec: spec}, ephemeralv1alpha1.HookPreDelete, "ephemeral-preview")
+	if job.Spec.ActiveDeadlineSeconds == nil || *job.Spec.ActiveDeadlineSeconds != 300 {
+		t.Errorf("expected the deadline of the hook to be kept, got %v", job.Spec.ActiveDeadlineSeconds)
+	}
+	if !HookJobSelector().Matches(labels.Set(job.Labels)) {
+		t.Errorf("expected the job to be selected by the hook job selector, labels %v", job.Labels)
+	}
+}
@@ -0,0 +1,189 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	ephemeralv1alpha1 "github.com/jbarea/argo-ephemeral-operator/api/v1alpha1"
+)
+
+// pruneInjectedResources deletes the secrets and configmaps injected in the ephemeral namespace
+// that are no longer in the spec, the injected objects are found by their owner label
+func (r *EphemeralApplicationReconciler) pruneInjectedResources(
+	ctx context.Context,
+	target client.Client,
+	ephApp *ephemeralv1alpha1.EphemeralApplication,
+	namespace string,
+) error {
+	logger := log.FromContext(ctx)
+	owned := []client.ListOption{
+		client.InNamespace(namespace),
+		client.MatchingLabels{"app.kubernetes.io/managed-by": "argo-ephemeral-operator", ownerLabel: ephApp.Name},
+	}
+
+	secrets := make(map[string]bool, len(ephApp.Spec.Secrets))
+	for _, secretRef := range ephApp.Spec.Secrets {
+		secrets[secretTargetName(secretRef)] = true
+	}
+	secretList := &corev1.SecretList{}
+	if err := target.List(ctx, secretList, owned...); err != nil {
+		return fmt.Errorf("failed to list injected secrets: %w", err)
+	}
+	for i := range secretList.Items {
+		secret := &secretList.Items[i]
+		if secrets[secret.Name] {
+			continue
+		}
+		logger.Info("deleting secret removed from the spec", "namespace", namespace, "name", secret.Name)
+		if err := target.Delete(ctx, secret); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete secret %s: %w", secret.Name, err)
+		}
+	}
+
+	configMaps := make(map[string]bool, len(ephApp.Spec.ConfigMaps))
+	for _, cmRef := range ephApp.Spec.ConfigMaps {
+		configMaps[configMapTargetName(cmRef)] = true
+	}
+	cmList := &corev1.ConfigMapList{}
+	if err := target.List(ctx, cmList, owned...); err != nil {
+		return fmt.Errorf("failed to list injected configmaps: %w", err)
+	}
+	for i := range cmList.Items {
+		cm := &cmList.Items[i]
+		if configMaps[cm.Name] {
+			continue
+		}
+		logger.Info("deleting configmap removed from the spec", "namespace", namespace, "name", cm.Name)
+		if err := target.Delete(ctx, cm); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete configmap %s: %w", cm.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// findInjected returns the status of an injected object, nil when it was never injected
+func findInjected(ephApp *ephemeralv1alpha1.EphemeralApplication, kind, name string) *ephemeralv1alpha1.InjectedResourceStatus {
+	for i := range ephApp.Status.InjectedResources {
+		injected := &ephApp.Status.InjectedResources[i]
+		if injected.Kind == kind && injected.Name == name {
+			return injected
+		}
+	}
+	return nil
+}
+
+// recordInjected records the status of an injected object, replacing the previous one
+// Writing the same data again keeps the previous sync time so the status does not churn
+func recordInjected(ephApp *ephemeralv1alpha1.EphemeralApplication, status ephemeralv1alpha1.InjectedResourceStatus) {
+	if existing := findInjected(ephApp, status.Kind, status.Name); existing != nil {
+		if existing.Error == "" && status.Error == "" && existing.Hash == status.Hash &&
+			existing.LastSyncedResourceVersion == status.LastSyncedResourceVersion {
+			status.LastSyncedTime = existing.LastSyncedTime
+		}
+		*existing = status
+		return
+	}
+	ephApp.Status.InjectedResources = append(ephApp.Status.InjectedResources, status)
+}
+
+// recordInjectionError records why an object could not be injected, the hash and versions of
+// the last successful copy are kept since that copy is still in the namespace
+func recordInjectionError(ephApp *ephemeralv1alpha1.EphemeralApplication, status ephemeralv1alpha1.InjectedResourceStatus, err error) {
+	if existing := findInjected(ephApp, status.Kind, status.Name); existing != nil {
+		status.Hash = existing.Hash
+		status.LastSyncedTime = existing.LastSyncedTime
+		status.LastSyncedResourceVersion = existing.LastSyncedResourceVersion
+	}
+	status.Error = err.Error()
+	recordInjected(ephApp, status)
+}
+
+// pruneInjectedStatus forgets the objects of a kind that are no longer in the spec
+func pruneInjectedStatus(ephApp *ephemeralv1alpha1.EphemeralApplication, kind string, names map[string]bool) {
+	ephApp.Status.InjectedResources = slices.DeleteFunc(ephApp.Status.InjectedResources, func(injected ephemeralv1alpha1.InjectedResourceStatus) bool {
+		return injected.Kind == kind && !names[injected.Name]
+	})
+	if len(ephApp.Status.InjectedResources) == 0 {
+		ephApp.Status.InjectedResources = nil
+	}
+}
+
+// secretStatus returns the status identifying the copy of a secret, without its sync details
+func secretStatus(secretRef ephemeralv1alpha1.SecretReference) ephemeralv1alpha1.InjectedResourceStatus {
+	status := ephemeralv1alpha1.InjectedResourceStatus{Kind: secretKind, Name: secretTargetName(secretRef)}
+	if copiedFromSource(secretRef) {
+		status.SourceNamespace, status.SourceName = secretRef.SourceNamespace, secretRef.Name
+	}
+	return status
+}
+
+// configMapStatus returns the status identifying the copy of a configmap, without its sync details
+func configMapStatus(cmRef ephemeralv1alpha1.ConfigMapReference) ephemeralv1alpha1.InjectedResourceStatus {
+	status := ephemeralv1alpha1.InjectedResourceStatus{Kind: configMapKind, Name: configMapTargetName(cmRef)}
+	if cmRef.SourceNamespace != "" {
+		status.SourceNamespace, status.SourceName = cmRef.SourceNamespace, cmRef.Name
+	}
+	return status
+}
+
+// synced completes the status of an object that was just written
+func synced(status ephemeralv1alpha1.InjectedResourceStatus, data map[string][]byte, sourceVersion string) ephemeralv1alpha1.InjectedResourceStatus {
+	now := metav1.Now()
+	status.Hash = dataHash(data)
+	status.LastSyncedTime = &now
+	status.LastSyncedResourceVersion = sourceVersion
+	return status
+}
+
+// dataHash returns the SHA-256 of the keys and values of an injected object
+func dataHash(data map[string][]byte) string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	hash := sha256.New()
+	for _, key := range keys {
+		hash.Write([]byte(key))
+		hash.Write([]byte{0})
+		hash.Write(data[key])
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// setInjectionCondition summarizes the injected objects in the ResourcesInjected condition,
+// the condition is left untouched when it already says the same
+func (r *EphemeralApplicationReconciler) setInjectionCondition(ephApp *ephemeralv1alpha1.EphemeralApplication) {
+	var failed []string
+	for _, injected := range ephApp.Status.InjectedResources {
+		if injected.Error != "" {
+			failed = append(failed, injected.Kind+" "+injected.Name)
+		}
+	}
+
+	status, reason, message := metav1.ConditionTrue, "Injected", "All secrets and configmaps are injected"
+	if len(failed) > 0 {
+		status, reason = metav1.ConditionFalse, "InjectionFailed"
+		message = "Failed to inject " + strings.Join(failed, ", ")
+	}
+
+	existing := meta.FindStatusCondition(ephApp.Status.Conditions, "ResourcesInjected")
+	if existing != nil && existing.Status == status && existing.Reason == reason && existing.Message == message {
+		return
+	}
+	r.setCondition(ephApp, "ResourcesInjected", status, reason, message)
+}
@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	ephemeralv1alpha1 "github.com/jbarea/argo-ephemeral-operator/api/v1alpha1"
+)
+
+func TestPruneInjectedResources(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = ephemeralv1alpha1.AddToScheme(scheme)
+
+	injected := func(name, owner string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "ephemeral-test",
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "argo-ephemeral-operator",
+				ownerLabel:                     owner,
+			},
+		}
+	}
+	objects := []client.Object{
+		&corev1.Secret{ObjectMeta: injected("db", "test-app")},
+		&corev1.Secret{ObjectMeta: injected("removed-secret", "test-app")},
+		&corev1.Secret{ObjectMeta: injected("other-owner", "other-app")},
+		// Secrets deployed by the application itself are not touched
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "app-secret", Namespace: "ephemeral-test"}},
+		&corev1.ConfigMap{ObjectMeta: injected("settings", "test-app")},
+		&corev1.ConfigMap{ObjectMeta: injected("removed-config", "test-app")},
+	}
+
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+	reconciler := &EphemeralApplicationReconciler{Client: fakeClient, Scheme: scheme}
+
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-app", Namespace: "default"},
+		Spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+			Secrets: []ephemeralv1alpha1.SecretReference{
+				{Name: "db-credentials", SourceNamespace: "shared", TargetName: "db"},
+			},
+			ConfigMaps: []ephemeralv1alpha1.ConfigMapReference{
+				{Name: "app-config", SourceNamespace: "shared", TargetName: "settings"},
+			},
+		},
+	}
+
+	ctx := context.Background()
+	if err := reconciler.pruneInjectedResources(ctx, fakeClient, ephApp, "ephemeral-test"); err != nil {
+		t.Fatalf("pruneInjectedResources failed: %v", err)
+	}
+
+	for _, name := range []string{"db", "other-owner", "app-secret"} {
+		if err := fakeClient.Get(ctx, client.ObjectKey{Namespace: "ephemeral-test", Name: name}, &corev1.Secret{}); err != nil {
+			t.Errorf("expected secret %s to be kept: %v", name, err)
+		}
+	}
+	if err := fakeClient.Get(ctx, client.ObjectKey{Namespace: "ephemeral-test", Name: "removed-secret"}, &corev1.Secret{}); !errors.IsNotFound(err) {
+		t.Errorf("expected removed-secret to be deleted, got %v", err)
+	}
+
+	if err := fakeClient.Get(ctx, client.ObjectKey{Namespace: "ephemeral-test", Name: "settings"}, &corev1.ConfigMap{}); err != nil {
+		t.Errorf("expected configmap settings to be kept: %v", err)
+	}
+	if err := fakeClient.Get(ctx, client.ObjectKey{Namespace: "ephemeral-test", Name: "removed-config"}, &corev1.ConfigMap{}); !errors.IsNotFound(err) {
+		t.Errorf("expected removed-config to be deleted, got %v", err)
+	}
+}
+
+func TestDataHash(t *testing.T) {
+	data := map[string][]byte{"user": []byte("admin"), "password": []byte("secret")}
+	reordered := map[string][]byte{"password": []byte("secret"), "user": []byte("admin")}
+	if dataHash(data) != dataHash(reordered) {
+		t.Error("expected the hash not to depend on the key order")
+	}
+
+	// Moving bytes between a key and its value changes the hash
+	shifted := map[string][]byte{"user": []byte("admin"), "passwords": []byte("ecret")}
+	if dataHash(data) == dataHash(shifted) {
+		t.Error("expected different data to have a different hash")
+	}
+}
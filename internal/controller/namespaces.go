@@ -0,0 +1,147 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	ephemeralv1alpha1 "github.com/jbarea/argo-ephemeral-operator/api/v1alpha1"
+)
+
+const (
+	// Names of the guardrails created in the ephemeral namespace
+	resourceQuotaName = "ephemeral-quota"
+	limitRangeName    = "ephemeral-limits"
+	networkPolicyName = "ephemeral-isolation"
+
+	// defaultIngressNamespace is allowed through the NetworkPolicy when no namespace is set
+	defaultIngressNamespace = "ingress-nginx"
+)
+
+// reconcileNamespace creates the ephemeral namespace or updates its metadata, then creates,
+// updates or deletes its ResourceQuota, LimitRange and NetworkPolicy following the options
+// A namespace that already exists without the owner labels of the environment is never adopted
+func (r *EphemeralApplicationReconciler) reconcileNamespace(
+	ctx context.Context,
+	target client.Client,
+	ephApp *ephemeralv1alpha1.EphemeralApplication,
+	namespace string,
+	options ephemeralv1alpha1.NamespaceOptions,
+) error {
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}
+	if _, err := controllerutil.CreateOrUpdate(ctx, target, ns, func() error {
+		if ns.ResourceVersion != "" && !ownedBy(ns.Labels, ephApp) {
+			return fmt.Errorf("namespace %s already exists and is not owned by this environment", namespace)
+		}
+		ns.Labels = mergeMetadata(ns.Labels, options.Labels)
+		ns.Annotations = mergeMetadata(ns.Annotations, options.Annotations)
+		// The operator labels can not be overridden by the options
+		ns.Labels["app.kubernetes.io/managed-by"] = "argo-ephemeral-operator"
+		ns.Labels[ownerLabel] = ephApp.Name
+		ns.Labels[ownerNamespaceLabel] = ephApp.Namespace
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to create namespace: %w", err)
+	}
+
+	quota := &corev1.ResourceQuota{ObjectMeta: metav1.ObjectMeta{Name: resourceQuotaName, Namespace: namespace}}
+	if err := reconcileGuardrail(ctx, target, ephApp, quota, len(options.ResourceQuota) > 0, func() {
+		quota.Spec.Hard = options.ResourceQuota
+	}); err != nil {
+		return fmt.Errorf("failed to reconcile resource quota: %w", err)
+	}
+
+	limits := &corev1.LimitRange{ObjectMeta: metav1.ObjectMeta{Name: limitRangeName, Namespace: namespace}}
+	if err := reconcileGuardrail(ctx, target, ephApp, limits, options.LimitRange != nil, func() {
+		limits.Spec.Limits = []corev1.LimitRangeItem{{
+			Type:           corev1.LimitTypeContainer,
+			Default:        options.LimitRange.Default,
+			DefaultRequest: options.LimitRange.DefaultRequest,
+			Max:            options.LimitRange.Max,
+		}}
+	}); err != nil {
+		return fmt.Errorf("failed to reconcile limit range: %w", err)
+	}
+
+	isolation := &networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: networkPolicyName, Namespace: namespace}}
+	if err := reconcileGuardrail(ctx, target, ephApp, isolation, options.NetworkPolicy != nil, func() {
+		isolation.Spec = buildNetworkPolicySpec(options.NetworkPolicy)
+	}); err != nil {
+		return fmt.Errorf("failed to reconcile network policy: %w", err)
+	}
+
+	return nil
+}
+
+// reconcileGuardrail creates or updates obj with mutate when enabled, and deletes it otherwise
+func reconcileGuardrail(
+	ctx context.Context,
+	target client.Client,
+	ephApp *ephemeralv1alpha1.EphemeralApplication,
+	obj client.Object,
+	enabled bool,
+	mutate func(),
+) error {
+	if !enabled {
+		if err := target.Delete(ctx, obj); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+
+	_, err := controllerutil.CreateOrUpdate(ctx, target, obj, func() error {
+		labels := obj.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels["app.kubernetes.io/managed-by"] = "argo-ephemeral-operator"
+		labels[ownerLabel] = ephApp.Name
+		obj.SetLabels(labels)
+		mutate()
+		return nil
+	})
+	return err
+}
+
+// buildNetworkPolicySpec denies the ingress traffic to every pod of the namespace except from
+// the pods of the same namespace and from the allowed namespaces, egress is not restricted
+func buildNetworkPolicySpec(isolation *ephemeralv1alpha1.NetworkIsolation) networkingv1.NetworkPolicySpec {
+	allowed := isolation.AllowedNamespaces
+	if len(allowed) == 0 {
+		allowed = []string{defaultIngressNamespace}
+	}
+
+	return networkingv1.NetworkPolicySpec{
+		PodSelector: metav1.LabelSelector{},
+		PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+		Ingress: []networkingv1.NetworkPolicyIngressRule{{
+			From: []networkingv1.NetworkPolicyPeer{
+				{PodSelector: &metav1.LabelSelector{}},
+				{NamespaceSelector: &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{{
+						Key:      corev1.LabelMetadataName,
+						Operator: metav1.LabelSelectorOpIn,
+						Values:   allowed,
+					}},
+				}},
+			},
+		}},
+	}
+}
+
+// mergeMetadata returns existing with the values of desired
+func mergeMetadata(existing, desired map[string]string) map[string]string {
+	if existing == nil {
+		existing = make(map[string]string, len(desired))
+	}
+	for k, v := range desired {
+		existing[k] = v
+	}
+	return existing
+}
@@ -0,0 +1,134 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	ephemeralv1alpha1 "github.com/jbarea/argo-ephemeral-operator/api/v1alpha1"
+)
+
+func TestReconcileNamespace_Guardrails(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = networkingv1.AddToScheme(scheme)
+	_ = ephemeralv1alpha1.AddToScheme(scheme)
+
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+	reconciler := &EphemeralApplicationReconciler{Client: fakeClient, Scheme: scheme}
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "preview", Namespace: "default"},
+	}
+
+	options := ephemeralv1alpha1.NamespaceOptions{
+		Labels: map[string]string{
+			"pod-security.kubernetes.io/enforce": "restricted",
+			ownerLabel:                           "someone-else",
+		},
+		Annotations:   map[string]string{"team": "payments"},
+		ResourceQuota: corev1.ResourceList{corev1.ResourceLimitsMemory: resource.MustParse("4Gi")},
+		LimitRange: &ephemeralv1alpha1.ContainerLimits{
+			Default: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")},
+		},
+		NetworkPolicy: &ephemeralv1alpha1.NetworkIsolation{},
+	}
+
+	ctx := context.Background()
+	if err := reconciler.reconcileNamespace(ctx, fakeClient, ephApp, "ephemeral-test", options); err != nil {
+		t.Fatalf("reconcileNamespace failed: %v", err)
+	}
+
+	ns := &corev1.Namespace{}
+	if err := fakeClient.Get(ctx, client.ObjectKey{Name: "ephemeral-test"}, ns); err != nil {
+		t.Fatalf("failed to get namespace: %v", err)
+	}
+	if ns.Labels["pod-security.kubernetes.io/enforce"] != "restricted" || ns.Annotations["team"] != "payments" {
+		t.Errorf("expected the labels and annotations of the options, got %v %v", ns.Labels, ns.Annotations)
+	}
+	if ns.Labels[ownerLabel] != "preview" {
+		t.Errorf("expected the owner label not to be overridden, got %s", ns.Labels[ownerLabel])
+	}
+
+	quota := &corev1.ResourceQuota{}
+	if err := fakeClient.Get(ctx, client.ObjectKey{Namespace: "ephemeral-test", Name: resourceQuotaName}, quota); err != nil {
+		t.Fatalf("expected a resource quota: %v", err)
+	}
+	if memory := quota.Spec.Hard[corev1.ResourceLimitsMemory]; memory.String() != "4Gi" {
+		t.Errorf("expected a 4Gi memory quota, got %s", memory.String())
+	}
+
+	policy := &networkingv1.NetworkPolicy{}
+	if err := fakeClient.Get(ctx, client.ObjectKey{Namespace: "ephemeral-test", Name: networkPolicyName}, policy); err != nil {
+		t.Fatalf("expected a network policy: %v", err)
+	}
+	from := policy.Spec.Ingress[0].From
+	if len(from) != 2 || from[1].NamespaceSelector.MatchExpressions[0].Values[0] != defaultIngressNamespace {
+		t.Errorf("expected ingress from the namespace and the ingress controller, got %+v", from)
+	}
+
+	// Removing the options removes the guardrails
+	options.LimitRange = nil
+	options.NetworkPolicy = nil
+	if err := reconciler.reconcileNamespace(ctx, fakeClient, ephApp, "ephemeral-test", options); err != nil {
+		t.Fatalf("reconcileNamespace failed: %v", err)
+	}
+	err := fakeClient.Get(ctx, client.ObjectKey{Namespace: "ephemeral-test", Name: limitRangeName}, &corev1.LimitRange{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected the limit range to be deleted, got %v", err)
+	}
+	err = fakeClient.Get(ctx, client.ObjectKey{Namespace: "ephemeral-test", Name: networkPolicyName}, &networkingv1.NetworkPolicy{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected the network policy to be deleted, got %v", err)
+	}
+}
+
+func TestReconcileNamespace_DoesNotAdoptExistingNamespace(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = networkingv1.AddToScheme(scheme)
+	_ = ephemeralv1alpha1.AddToScheme(scheme)
+
+	existing := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:   "kube-system",
+		Labels: map[string]string{"kubernetes.io/metadata.name": "kube-system"},
+	}}
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(existing).Build()
+	reconciler := &EphemeralApplicationReconciler{Client: fakeClient, Scheme: scheme}
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "preview", Namespace: "default"},
+	}
+
+	ctx := context.Background()
+	options := ephemeralv1alpha1.NamespaceOptions{NetworkPolicy: &ephemeralv1alpha1.NetworkIsolation{}}
+	if err := reconciler.reconcileNamespace(ctx, fakeClient, ephApp, "kube-system", options); err == nil {
+		t.Fatal("expected an existing namespace without owner labels to be rejected")
+	}
+
+	ns := &corev1.Namespace{}
+	if err := fakeClient.Get(ctx, client.ObjectKey{Name: "kube-system"}, ns); err != nil {
+		t.Fatalf("failed to get namespace: %v", err)
+	}
+	if _, ok := ns.Labels[ownerLabel]; ok {
+		t.Errorf("expected the namespace not to be labeled, got %v", ns.Labels)
+	}
+	err := fakeClient.Get(ctx, client.ObjectKey{Namespace: "kube-system", Name: networkPolicyName}, &networkingv1.NetworkPolicy{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected no network policy in the namespace, got %v", err)
+	}
+
+	// The namespace of another environment is not adopted either
+	existing.Labels[ownerLabel] = "other"
+	existing.Labels[ownerNamespaceLabel] = "default"
+	otherClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(existing).Build()
+	if err := reconciler.reconcileNamespace(ctx, otherClient, ephApp, "kube-system", options); err == nil {
+		t.Error("expected the namespace of another environment to be rejected")
+	}
+}
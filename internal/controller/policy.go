@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"context"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	ephemeralv1alpha1 "github.com/jbarea/argo-ephemeral-operator/api/v1alpha1"
+	"github.com/jbarea/argo-ephemeral-operator/internal/policy"
+)
+
+// recordExpiry computes the effective expiry from expirationDate or ttl and persists it when it changes
+// The expiry is capped to the maximum lifetime of the policies, and extensions beyond
+// the allowed number are ignored
+func (r *EphemeralApplicationReconciler) recordExpiry(ctx context.Context, ephApp *ephemeralv1alpha1.EphemeralApplication, policies policy.Policies) error {
+	logger := log.FromContext(ctx)
+
+	expiresAt := ephApp.ExpiresAt()
+	previous := ephApp.Status.ExpiresAt
+
+	if maxExpiry := policies.MaxExpiry(ephApp); maxExpiry != nil && expiresAt != nil && expiresAt.After(maxExpiry.Time) {
+		logger.Info("capping expiry to the maximum lifetime allowed by policy", "requested", expiresAt, "maxExpiry", maxExpiry)
+		expiresAt = maxExpiry
+	}
+
+	extensions := ephApp.Status.Extensions
+	if previous != nil && expiresAt != nil && expiresAt.After(previous.Time) {
+		if err := policies.CheckExtensions(extensions + 1); err != nil {
+			logger.Info("ignoring expiry extension", "requested", expiresAt, "reason", err.Error())
+			expiresAt = previous
+		} else {
+			extensions++
+		}
+	}
+
+	if expiresAt.Equal(previous) && extensions == ephApp.Status.Extensions {
+		return nil
+	}
+
+	ephApp.Status.ExpiresAt = expiresAt
+	ephApp.Status.Extensions = extensions
+	return r.updateStatus(ctx, ephApp)
+}
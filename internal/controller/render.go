@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	ephemeralv1alpha1 "github.com/jbarea/argo-ephemeral-operator/api/v1alpha1"
+)
+
+// templateData holds the facts about an environment that secret and configmap templates can reference
+type templateData struct {
+	// Name of the EphemeralApplication
+	Name string
+	// Namespace is the ephemeral namespace
+	Namespace string
+	// PreviewURL is the URL the environment is exposed at, empty without a preview domain
+	PreviewURL string
+	// Data holds the copied or inline values the templates are merged over
+	Data map[string]string
+}
+
+// newTemplateData returns the template data of an environment deployed to namespace
+func newTemplateData(ephApp *ephemeralv1alpha1.EphemeralApplication, namespace string, data map[string]string) templateData {
+	return templateData{
+		Name:       ephApp.Name,
+		Namespace:  namespace,
+		PreviewURL: ephApp.Status.PreviewURL,
+		Data:       data,
+	}
+}
+
+// renderTemplates renders every template into the value of its key
+// Referencing a missing value is an error rather than rendering "<no value>"
+func renderTemplates(templates map[string]string, data templateData) (map[string]string, error) {
+	rendered := make(map[string]string, len(templates))
+	for key, text := range templates {
+		tmpl, err := template.New(key).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template %s: %w", key, err)
+		}
+		var value strings.Builder
+		if err := tmpl.Execute(&value, data); err != nil {
+			return nil, fmt.Errorf("failed to render template %s: %w", key, err)
+		}
+		rendered[key] = value.String()
+	}
+	return rendered, nil
+}
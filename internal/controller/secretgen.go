@@ -0,0 +1,261 @@
+package controller
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/url"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	ephemeralv1alpha1 "github.com/jbarea/argo-ephemeral-operator/api/v1alpha1"
+)
+
+const (
+	defaultPasswordLength      = 32
+	defaultPasswordCharset     = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	defaultRSABits             = 2048
+	defaultCertificateValidity = 365 * 24 * time.Hour
+)
+
+// generateSecret creates a secret with generated values in the ephemeral namespace
+// The values are generated once, an existing secret only gets the keys it is missing
+func (r *EphemeralApplicationReconciler) generateSecret(
+	ctx context.Context,
+	target client.Client,
+	secretRef ephemeralv1alpha1.SecretReference,
+	targetNamespace string,
+	ephApp *ephemeralv1alpha1.EphemeralApplication,
+) error {
+	logger := log.FromContext(ctx)
+	targetName := secretTargetName(secretRef)
+
+	secret := &corev1.Secret{}
+	err := target.Get(ctx, client.ObjectKey{Namespace: targetNamespace, Name: targetName}, secret)
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to get secret: %w", err)
+	}
+	exists := err == nil
+
+	if secret.Data == nil {
+		secret.Data = make(map[string][]byte)
+	}
+	generated, err := generateSecretData(secretRef.Generate, secret.Data, previewHost(ephApp), time.Now())
+	if err != nil {
+		return err
+	}
+	if exists && !generated {
+		recordGenerated(ephApp, secretRef, secret.Data)
+		return nil
+	}
+
+	if exists {
+		logger.Info("generating missing secret keys", "targetNamespace", targetNamespace, "targetName", targetName)
+		if err := target.Update(ctx, secret); err != nil {
+			return fmt.Errorf("failed to update secret: %w", err)
+		}
+		recordGenerated(ephApp, secretRef, secret.Data)
+		return nil
+	}
+
+	logger.Info("generating secret", "targetNamespace", targetNamespace, "targetName", targetName)
+	secret.ObjectMeta = metav1.ObjectMeta{
+		Name:      targetName,
+		Namespace: targetNamespace,
+		Labels: map[string]string{
+			"app.kubernetes.io/managed-by": "argo-ephemeral-operator",
+			"ephemeral.argo.io/owner":      ephApp.Name,
+			"ephemeral.argo.io/generated":  "true",
+		},
+	}
+	secret.Type = corev1.SecretTypeOpaque
+	if secretRef.Generate.TLS != nil {
+		secret.Type = corev1.SecretTypeTLS
+	}
+	if err := target.Create(ctx, secret); err != nil {
+		return fmt.Errorf("failed to create secret: %w", err)
+	}
+	recordGenerated(ephApp, secretRef, secret.Data)
+	return nil
+}
+
+// recordGenerated records a generated secret, it has no source version
+func recordGenerated(ephApp *ephemeralv1alpha1.EphemeralApplication, secretRef ephemeralv1alpha1.SecretReference, data map[string][]byte) {
+	recordInjected(ephApp, synced(secretStatus(secretRef), data, ""))
+}
+
+// generateSecretData generates the values of gen missing from data and reports whether any was added
+func generateSecretData(gen *ephemeralv1alpha1.SecretGenerator, data map[string][]byte, host string, now time.Time) (bool, error) {
+	generated := false
+
+	for _, password := range gen.Passwords {
+		if _, ok := data[password.Key]; ok {
+			continue
+		}
+		value, err := randomPassword(int(password.Length), password.Charset)
+		if err != nil {
+			return false, fmt.Errorf("failed to generate password %s: %w", password.Key, err)
+		}
+		data[password.Key] = []byte(value)
+		generated = true
+	}
+
+	if gen.KeyPair != nil && (data["privateKey"] == nil || data["publicKey"] == nil) {
+		private, public, err := generateKeyPair(gen.KeyPair)
+		if err != nil {
+			return false, fmt.Errorf("failed to generate key pair: %w", err)
+		}
+		data["privateKey"], data["publicKey"] = private, public
+		generated = true
+	}
+
+	if gen.TLS != nil && (data[corev1.TLSCertKey] == nil || data[corev1.TLSPrivateKeyKey] == nil) {
+		cert, key, err := generateCertificate(gen.TLS, host, now)
+		if err != nil {
+			return false, fmt.Errorf("failed to generate certificate: %w", err)
+		}
+		// The certificate is self-signed so it is its own CA
+		data[corev1.TLSCertKey], data[corev1.TLSPrivateKeyKey], data["ca.crt"] = cert, key, cert
+		generated = true
+	}
+
+	return generated, nil
+}
+
+// randomPassword returns a password of length characters picked from charset
+func randomPassword(length int, charset string) (string, error) {
+	if length == 0 {
+		length = defaultPasswordLength
+	}
+	if charset == "" {
+		charset = defaultPasswordCharset
+	}
+
+	chars := []rune(charset)
+	max := big.NewInt(int64(len(chars)))
+	password := make([]rune, length)
+	for i := range password {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		password[i] = chars[n.Int64()]
+	}
+	return string(password), nil
+}
+
+// generateKeyPair returns a PEM encoded private key and its public key
+func generateKeyPair(spec *ephemeralv1alpha1.KeyPairGenerator) ([]byte, []byte, error) {
+	var private crypto.Signer
+	switch spec.Algorithm {
+	case ephemeralv1alpha1.KeyAlgorithmEd25519:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, nil, err
+		}
+		private = key
+	default:
+		bits := int(spec.Bits)
+		if bits == 0 {
+			bits = defaultRSABits
+		}
+		key, err := rsa.GenerateKey(rand.Reader, bits)
+		if err != nil {
+			return nil, nil, err
+		}
+		private = key
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, nil, err
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(private.Public())
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), nil
+}
+
+// generateCertificate returns a PEM encoded self-signed certificate and its private key,
+// issued for the DNS names of spec or the preview host of the environment
+func generateCertificate(spec *ephemeralv1alpha1.TLSGenerator, host string, now time.Time) ([]byte, []byte, error) {
+	dnsNames := spec.DNSNames
+	if len(dnsNames) == 0 {
+		if host == "" {
+			return nil, nil, fmt.Errorf("tls.dnsNames is required when the environment has no preview hostname")
+		}
+		dnsNames = []string{host}
+	}
+	validity := defaultCertificateValidity
+	if spec.Validity != nil {
+		validity = spec.Validity.Duration
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:              dnsNames,
+		NotBefore:             now,
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), nil
+}
+
+// previewURL returns the URL an environment deployed to namespace is exposed at,
+// empty when the operator has no preview domain
+func (r *EphemeralApplicationReconciler) previewURL(namespace string) string {
+	if r.Config.PreviewDomain == "" {
+		return ""
+	}
+	return "https://" + namespace + "." + r.Config.PreviewDomain
+}
+
+// previewHost returns the hostname of the preview URL of an environment
+func previewHost(ephApp *ephemeralv1alpha1.EphemeralApplication) string {
+	if ephApp.Status.PreviewURL == "" {
+		return ""
+	}
+	u, err := url.Parse(ephApp.Status.PreviewURL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
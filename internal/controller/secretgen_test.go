@@ -0,0 +1,161 @@
+package controller
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	ephemeralv1alpha1 "github.com/jbarea/argo-ephemeral-operator/api/v1alpha1"
+)
+
+func TestGenerateSecretData_Passwords(t *testing.T) {
+	gen := &ephemeralv1alpha1.SecretGenerator{
+		Passwords: []ephemeralv1alpha1.PasswordGenerator{
+			{Key: "password"},
+			{Key: "pin", Length: 8, Charset: "0123456789"},
+			{Key: "existing"},
+		},
+	}
+	data := map[string][]byte{"existing": []byte("kept")}
+
+	generated, err := generateSecretData(gen, data, "", time.Now())
+	if err != nil {
+		t.Fatalf("generateSecretData failed: %v", err)
+	}
+	if !generated {
+		t.Error("expected missing passwords to be generated")
+	}
+	if len(data["password"]) != defaultPasswordLength {
+		t.Errorf("expected a password of %d characters, got %q", defaultPasswordLength, data["password"])
+	}
+	if len(data["pin"]) != 8 || strings.Trim(string(data["pin"]), "0123456789") != "" {
+		t.Errorf("expected a numeric pin of 8 digits, got %q", data["pin"])
+	}
+	if string(data["existing"]) != "kept" {
+		t.Errorf("expected existing value to be kept, got %q", data["existing"])
+	}
+
+	// Nothing is generated again once every key exists
+	generated, err = generateSecretData(gen, data, "", time.Now())
+	if err != nil {
+		t.Fatalf("generateSecretData failed: %v", err)
+	}
+	if generated {
+		t.Error("expected no value to be generated again")
+	}
+}
+
+func TestGenerateSecretData_KeyPair(t *testing.T) {
+	gen := &ephemeralv1alpha1.SecretGenerator{
+		KeyPair: &ephemeralv1alpha1.KeyPairGenerator{Algorithm: ephemeralv1alpha1.KeyAlgorithmEd25519},
+	}
+	data := map[string][]byte{}
+
+	if _, err := generateSecretData(gen, data, "", time.Now()); err != nil {
+		t.Fatalf("generateSecretData failed: %v", err)
+	}
+
+	block, _ := pem.Decode(data["privateKey"])
+	if block == nil {
+		t.Fatal("expected a PEM private key")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		t.Fatalf("failed to parse private key: %v", err)
+	}
+	if _, ok := key.(ed25519.PrivateKey); !ok {
+		t.Errorf("expected an ed25519 key, got %T", key)
+	}
+	if block, _ := pem.Decode(data["publicKey"]); block == nil || block.Type != "PUBLIC KEY" {
+		t.Error("expected a PEM public key")
+	}
+}
+
+func TestGenerateSecretData_TLS(t *testing.T) {
+	now := time.Now()
+	gen := &ephemeralv1alpha1.SecretGenerator{
+		TLS: &ephemeralv1alpha1.TLSGenerator{Validity: &metav1.Duration{Duration: 24 * time.Hour}},
+	}
+	data := map[string][]byte{}
+
+	if _, err := generateSecretData(gen, data, "ephemeral-abc.preview.example.com", now); err != nil {
+		t.Fatalf("generateSecretData failed: %v", err)
+	}
+
+	block, _ := pem.Decode(data[corev1.TLSCertKey])
+	if block == nil {
+		t.Fatal("expected a PEM certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	if err := cert.VerifyHostname("ephemeral-abc.preview.example.com"); err != nil {
+		t.Errorf("expected the certificate to be issued for the preview host: %v", err)
+	}
+	if !cert.NotAfter.Equal(now.Add(24 * time.Hour).Truncate(time.Second)) {
+		t.Errorf("expected the certificate to expire in a day, got %v", cert.NotAfter)
+	}
+	if string(data["ca.crt"]) != string(data[corev1.TLSCertKey]) {
+		t.Error("expected ca.crt to be the self-signed certificate")
+	}
+
+	// Without DNS names nor preview host there is nothing to issue the certificate for
+	if _, err := generateSecretData(gen, map[string][]byte{}, "", now); err == nil {
+		t.Error("expected an error without DNS names")
+	}
+}
+
+func TestGenerateSecret_StoredOnce(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = ephemeralv1alpha1.AddToScheme(scheme)
+
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+	reconciler := &EphemeralApplicationReconciler{Client: fakeClient, Scheme: scheme}
+
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-app", Namespace: "default"},
+	}
+	secretRef := ephemeralv1alpha1.SecretReference{
+		Name: "db-credentials",
+		Generate: &ephemeralv1alpha1.SecretGenerator{
+			Passwords: []ephemeralv1alpha1.PasswordGenerator{{Key: "password"}},
+		},
+	}
+
+	ctx := context.Background()
+	if err := reconciler.copySecret(ctx, fakeClient, secretRef, "ephemeral-test", ephApp); err != nil {
+		t.Fatalf("copySecret failed: %v", err)
+	}
+	first := &corev1.Secret{}
+	key := client.ObjectKey{Namespace: "ephemeral-test", Name: "db-credentials"}
+	if err := fakeClient.Get(ctx, key, first); err != nil {
+		t.Fatalf("failed to get generated secret: %v", err)
+	}
+	if first.Labels["ephemeral.argo.io/generated"] != "true" {
+		t.Errorf("expected generated label, got %v", first.Labels)
+	}
+
+	// Reconciling again keeps the generated password
+	if err := reconciler.copySecret(ctx, fakeClient, secretRef, "ephemeral-test", ephApp); err != nil {
+		t.Fatalf("copySecret failed: %v", err)
+	}
+	second := &corev1.Secret{}
+	if err := fakeClient.Get(ctx, key, second); err != nil {
+		t.Fatalf("failed to get generated secret: %v", err)
+	}
+	if string(second.Data["password"]) != string(first.Data["password"]) {
+		t.Error("expected the generated password to be kept")
+	}
+}
@@ -3,10 +3,12 @@ package controller
 import (
 	"context"
 	"fmt"
+	"slices"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
@@ -14,41 +16,56 @@ import (
 )
 
 // copySecrets copies secrets from source namespaces to the target ephemeral namespace
+// The secrets are read from the local cluster and written with the target client. Every secret
+// is attempted, the ones that fail are recorded in the status and their errors returned together
 func (r *EphemeralApplicationReconciler) copySecrets(
 	ctx context.Context,
+	target client.Client,
 	ephApp *ephemeralv1alpha1.EphemeralApplication,
 	targetNamespace string,
 ) error {
 	logger := log.FromContext(ctx)
 
+	names := make(map[string]bool, len(ephApp.Spec.Secrets))
+	for _, secretRef := range ephApp.Spec.Secrets {
+		names[secretTargetName(secretRef)] = true
+	}
+	pruneInjectedStatus(ephApp, secretKind, names)
+
 	if len(ephApp.Spec.Secrets) == 0 {
 		return nil
 	}
 
 	logger.Info("copying secrets to ephemeral namespace", "count", len(ephApp.Spec.Secrets))
 
+	var errs []error
 	for _, secretRef := range ephApp.Spec.Secrets {
-		if err := r.copySecret(ctx, secretRef, targetNamespace, ephApp); err != nil {
-			return fmt.Errorf("failed to copy secret %s from %s: %w",
-				secretRef.Name, secretRef.SourceNamespace, err)
+		if err := r.copySecret(ctx, target, secretRef, targetNamespace, ephApp); err != nil {
+			recordInjectionError(ephApp, secretStatus(secretRef), err)
+			errs = append(errs, fmt.Errorf("failed to copy secret %s: %w", secretTargetName(secretRef), err))
 		}
 	}
 
-	return nil
+	return utilerrors.NewAggregate(errs)
 }
 
 // copySecret copies a single secret from source to target namespace
 func (r *EphemeralApplicationReconciler) copySecret(
 	ctx context.Context,
+	target client.Client,
 	secretRef ephemeralv1alpha1.SecretReference,
 	targetNamespace string,
 	ephApp *ephemeralv1alpha1.EphemeralApplication,
 ) error {
 	logger := log.FromContext(ctx)
 
+	if secretRef.Generate != nil {
+		return r.generateSecret(ctx, target, secretRef, targetNamespace, ephApp)
+	}
+
 	// Get the source secret or use the values if provided
 	sourceSecret := &corev1.Secret{}
-	if len(secretRef.Values) == 0 {
+	if copiedFromSource(secretRef) {
 		err := r.Get(ctx, client.ObjectKey{
 			Namespace: secretRef.SourceNamespace,
 			Name:      secretRef.Name,
@@ -64,12 +81,15 @@ func (r *EphemeralApplicationReconciler) copySecret(
 		sourceSecret.Type = corev1.SecretTypeOpaque
 	}
 
-	// Determine target secret name
-	targetName := secretRef.Name
-	if secretRef.TargetName != "" {
-		targetName = secretRef.TargetName
+	// Select, rename and render the keys of the copy
+	data, err := secretData(secretRef, sourceSecret.Data, ephApp, targetNamespace)
+	if err != nil {
+		return err
 	}
 
+	// Determine target secret name
+	targetName := secretTargetName(secretRef)
+
 	logger.Info("copying secret",
 		"sourceNamespace", secretRef.SourceNamespace,
 		"sourceName", secretRef.Name,
@@ -85,7 +105,7 @@ func (r *EphemeralApplicationReconciler) copySecret(
 	annotations := map[string]string{}
 
 	// Add different labels for inline vs copied secrets
-	if len(secretRef.Values) > 0 {
+	if !copiedFromSource(secretRef) {
 		labels["ephemeral.argo.io/inline"] = "true"
 	} else {
 		labels["ephemeral.argo.io/copied-from"] = secretRef.SourceNamespace
@@ -102,52 +122,73 @@ func (r *EphemeralApplicationReconciler) copySecret(
 			Annotations: annotations,
 		},
 		Type: sourceSecret.Type,
-		Data: sourceSecret.Data,
+		Data: data,
 	}
 
 	// Create or update the secret
-	err := r.Create(ctx, targetSecret)
-	if err != nil {
+	if err := target.Create(ctx, targetSecret); err != nil {
 		if errors.IsAlreadyExists(err) {
 			// Update if already exists
 			logger.Info("secret already exists, updating", "name", targetName)
 			existingSecret := &corev1.Secret{}
-			if err := r.Get(ctx, client.ObjectKey{
+			if err := target.Get(ctx, client.ObjectKey{
 				Namespace: targetNamespace,
 				Name:      targetName,
 			}, existingSecret); err != nil {
 				return err
 			}
 
-			existingSecret.Data = sourceSecret.Data
+			existingSecret.Data = data
 			existingSecret.Type = sourceSecret.Type
 
-			if err := r.Update(ctx, existingSecret); err != nil {
+			if err := target.Update(ctx, existingSecret); err != nil {
 				return fmt.Errorf("failed to update secret: %w", err)
 			}
-
-			return nil
+		} else {
+			return fmt.Errorf("failed to create secret: %w", err)
 		}
-		return fmt.Errorf("failed to create secret: %w", err)
 	}
 
+	recordInjected(ephApp, synced(secretStatus(secretRef), data, sourceSecret.ResourceVersion))
 	return nil
 }
 
-// buildCopiedSecretsList creates a human-readable list of copied secrets
-func (r *EphemeralApplicationReconciler) buildCopiedSecretsList(secrets []ephemeralv1alpha1.SecretReference) []string {
-	if len(secrets) == 0 {
-		return nil
+// secretData returns the data of the copy of a secret: the selected source keys under
+// their new names, with the rendered templates merged over them
+func secretData(
+	secretRef ephemeralv1alpha1.SecretReference,
+	source map[string][]byte,
+	ephApp *ephemeralv1alpha1.EphemeralApplication,
+	targetNamespace string,
+) (map[string][]byte, error) {
+	for _, key := range secretRef.Keys {
+		if _, ok := source[key]; !ok {
+			return nil, fmt.Errorf("key %s not found in source secret %s", key, secretRef.Name)
+		}
 	}
 
-	copiedList := make([]string, 0, len(secrets))
-	for _, secret := range secrets {
-		targetName := secret.Name
-		if secret.TargetName != "" {
-			targetName = secret.TargetName
+	data := make(map[string][]byte, len(source)+len(secretRef.Templates))
+	values := make(map[string]string, len(source))
+	for key, value := range source {
+		if len(secretRef.Keys) > 0 && !slices.Contains(secretRef.Keys, key) {
+			continue
 		}
-		copiedList = append(copiedList, fmt.Sprintf("%s/%s -> %s", secret.SourceNamespace, secret.Name, targetName))
+		if slices.Contains(secretRef.ExcludeKeys, key) {
+			continue
+		}
+		if renamed, ok := secretRef.RenameKeys[key]; ok {
+			key = renamed
+		}
+		data[key] = value
+		values[key] = string(value)
 	}
 
-	return copiedList
+	rendered, err := renderTemplates(secretRef.Templates, newTemplateData(ephApp, targetNamespace, values))
+	if err != nil {
+		return nil, fmt.Errorf("failed to render secret %s: %w", secretTargetName(secretRef), err)
+	}
+	for key, value := range rendered {
+		data[key] = []byte(value)
+	}
+	return data, nil
 }
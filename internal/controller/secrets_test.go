@@ -55,7 +55,7 @@ func TestCopySecret_FromSource(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	err := reconciler.copySecret(ctx, secretRef, "ephemeral-test", ephApp)
+	err := reconciler.copySecret(ctx, fakeClient, secretRef, "ephemeral-test", ephApp)
 	if err != nil {
 		t.Fatalf("copySecret failed: %v", err)
 	}
@@ -115,7 +115,7 @@ func TestCopySecret_InlineValues(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	err := reconciler.copySecret(ctx, secretRef, "ephemeral-test", ephApp)
+	err := reconciler.copySecret(ctx, fakeClient, secretRef, "ephemeral-test", ephApp)
 	if err != nil {
 		t.Fatalf("copySecret with inline values failed: %v", err)
 	}
@@ -144,85 +144,63 @@ func TestCopySecret_InlineValues(t *testing.T) {
 	}
 }
 
-func TestBuildCopiedSecretsList(t *testing.T) {
-	tests := []struct {
-		name     string
-		secrets  []ephemeralv1alpha1.SecretReference
-		expected []string
-	}{
-		{
-			name:     "empty secrets",
-			secrets:  []ephemeralv1alpha1.SecretReference{},
-			expected: nil,
-		},
-		{
-			name: "copied secret",
-			secrets: []ephemeralv1alpha1.SecretReference{
-				{
-					Name:            "db-creds",
-					SourceNamespace: "databases",
-				},
-			},
-			expected: []string{"databases/db-creds -> db-creds"},
-		},
-		{
-			name: "inline secret",
-			secrets: []ephemeralv1alpha1.SecretReference{
-				{
-					Name: "api-keys",
-					Values: map[string]string{
-						"key": "value",
-					},
-				},
-			},
-			expected: []string{"/api-keys -> api-keys"},
-		},
-		{
-			name: "copied with rename",
-			secrets: []ephemeralv1alpha1.SecretReference{
-				{
-					Name:            "postgres",
-					SourceNamespace: "databases",
-					TargetName:      "pg-creds",
-				},
+func TestCopySecrets_ReportsFailuresPerSecret(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = ephemeralv1alpha1.AddToScheme(scheme)
+
+	sourceSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "postgres", Namespace: "databases"},
+		Data:       map[string][]byte{"password": []byte("secret")},
+	}
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sourceSecret).Build()
+	reconciler := &EphemeralApplicationReconciler{Client: fakeClient, Scheme: scheme}
+
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-app", Namespace: "default"},
+		Spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+			Secrets: []ephemeralv1alpha1.SecretReference{
+				{Name: "missing", SourceNamespace: "databases"},
+				{Name: "postgres", SourceNamespace: "databases", TargetName: "pg-creds"},
 			},
-			expected: []string{"databases/postgres -> pg-creds"},
 		},
-		{
-			name: "mixed secrets",
-			secrets: []ephemeralv1alpha1.SecretReference{
-				{
-					Name:            "postgres",
-					SourceNamespace: "databases",
-				},
-				{
-					Name: "test-data",
-					Values: map[string]string{
-						"data": "test",
-					},
-				},
+		Status: ephemeralv1alpha1.EphemeralApplicationStatus{
+			InjectedResources: []ephemeralv1alpha1.InjectedResourceStatus{
+				{Kind: secretKind, Name: "removed"},
+				{Kind: configMapKind, Name: "app-config"},
 			},
-			expected: []string{"databases/postgres -> postgres", "/test-data -> test-data"},
 		},
 	}
 
-	reconciler := &EphemeralApplicationReconciler{}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got := reconciler.buildCopiedSecretsList(tt.secrets)
+	ctx := context.Background()
+	if err := reconciler.copySecrets(ctx, fakeClient, ephApp, "ephemeral-test"); err == nil {
+		t.Fatal("expected the missing secret to be reported")
+	}
 
-			if len(got) != len(tt.expected) {
-				t.Errorf("expected %d items, got %d", len(tt.expected), len(got))
-				return
-			}
+	// The secret after the failing one is still copied
+	copied := &corev1.Secret{}
+	if err := fakeClient.Get(ctx, client.ObjectKey{Namespace: "ephemeral-test", Name: "pg-creds"}, copied); err != nil {
+		t.Fatalf("expected pg-creds to be copied: %v", err)
+	}
 
-			for i, expected := range tt.expected {
-				if got[i] != expected {
-					t.Errorf("item %d: expected '%s', got '%s'", i, expected, got[i])
-				}
-			}
-		})
+	if failed := findInjected(ephApp, secretKind, "missing"); failed == nil || failed.Error == "" {
+		t.Errorf("expected the missing secret to have an error, got %v", failed)
+	}
+	injected := findInjected(ephApp, secretKind, "pg-creds")
+	if injected == nil {
+		t.Fatal("expected pg-creds to be recorded")
+	}
+	if injected.SourceNamespace != "databases" || injected.SourceName != "postgres" {
+		t.Errorf("expected source databases/postgres, got %s/%s", injected.SourceNamespace, injected.SourceName)
+	}
+	if injected.Hash != dataHash(copied.Data) || injected.LastSyncedTime == nil || injected.Error != "" {
+		t.Errorf("expected the copy to be recorded, got %v", injected)
+	}
+	if findInjected(ephApp, secretKind, "removed") != nil {
+		t.Error("expected secrets removed from the spec to be forgotten")
+	}
+	if findInjected(ephApp, configMapKind, "app-config") == nil {
+		t.Error("expected configmaps to be left untouched")
 	}
 }
 
@@ -251,8 +229,133 @@ func TestCopySecrets_EmptyList(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	err := reconciler.copySecrets(ctx, ephApp, "ephemeral-test")
+	err := reconciler.copySecrets(ctx, fakeClient, ephApp, "ephemeral-test")
 	if err != nil {
 		t.Fatalf("copySecrets with empty list should not fail: %v", err)
 	}
 }
+
+func TestCopySecret_SelectRenameAndTemplateKeys(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = ephemeralv1alpha1.AddToScheme(scheme)
+
+	sourceSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "db-credentials", Namespace: "shared-secrets"},
+		Data: map[string][]byte{
+			"username":   []byte("admin"),
+			"password":   []byte("secret123"),
+			"admin-cert": []byte("cert"),
+		},
+	}
+
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sourceSecret).Build()
+	reconciler := &EphemeralApplicationReconciler{Client: fakeClient, Scheme: scheme}
+
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "pr-42", Namespace: "default"},
+		Status:     ephemeralv1alpha1.EphemeralApplicationStatus{PreviewURL: "https://ephemeral-test.preview.example.com"},
+	}
+	secretRef := ephemeralv1alpha1.SecretReference{
+		Name:            "db-credentials",
+		SourceNamespace: "shared-secrets",
+		ExcludeKeys:     []string{"admin-cert"},
+		RenameKeys:      map[string]string{"username": "DB_USER", "password": "DB_PASSWORD"},
+		Templates: map[string]string{
+			"DB_NAME":      "app_{{ .Namespace }}",
+			"DATABASE_URL": "postgres://{{ .Data.DB_USER }}@db/app_{{ .Namespace }}",
+			"CALLBACK_URL": "{{ .PreviewURL }}/callback",
+			"ENVIRONMENT":  "{{ .Name }}",
+		},
+	}
+
+	ctx := context.Background()
+	if err := reconciler.copySecret(ctx, fakeClient, secretRef, "ephemeral-test", ephApp); err != nil {
+		t.Fatalf("copySecret failed: %v", err)
+	}
+
+	copied := &corev1.Secret{}
+	if err := fakeClient.Get(ctx, client.ObjectKey{Namespace: "ephemeral-test", Name: "db-credentials"}, copied); err != nil {
+		t.Fatalf("failed to get copied secret: %v", err)
+	}
+
+	expected := map[string]string{
+		"DB_USER":      "admin",
+		"DB_PASSWORD":  "secret123",
+		"DB_NAME":      "app_ephemeral-test",
+		"DATABASE_URL": "postgres://admin@db/app_ephemeral-test",
+		"CALLBACK_URL": "https://ephemeral-test.preview.example.com/callback",
+		"ENVIRONMENT":  "pr-42",
+	}
+	if len(copied.Data) != len(expected) {
+		t.Errorf("expected keys %v, got %v", expected, copied.Data)
+	}
+	for key, value := range expected {
+		if string(copied.Data[key]) != value {
+			t.Errorf("expected %s to be %q, got %q", key, value, copied.Data[key])
+		}
+	}
+}
+
+func TestCopySecret_MissingSelectedKey(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = ephemeralv1alpha1.AddToScheme(scheme)
+
+	sourceSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "db-credentials", Namespace: "shared-secrets"},
+		Data:       map[string][]byte{"username": []byte("admin")},
+	}
+
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sourceSecret).Build()
+	reconciler := &EphemeralApplicationReconciler{Client: fakeClient, Scheme: scheme}
+
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-app", Namespace: "default"},
+	}
+	secretRef := ephemeralv1alpha1.SecretReference{
+		Name:            "db-credentials",
+		SourceNamespace: "shared-secrets",
+		Keys:            []string{"username", "password"},
+	}
+
+	if err := reconciler.copySecret(context.Background(), fakeClient, secretRef, "ephemeral-test", ephApp); err == nil {
+		t.Error("expected an error for a selected key missing from the source")
+	}
+}
+
+func TestCopySecret_InlineTemplates(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = ephemeralv1alpha1.AddToScheme(scheme)
+
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+	reconciler := &EphemeralApplicationReconciler{Client: fakeClient, Scheme: scheme}
+
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-app", Namespace: "default"},
+	}
+	secretRef := ephemeralv1alpha1.SecretReference{
+		Name:      "app-config",
+		Values:    map[string]string{"user": "app"},
+		Templates: map[string]string{"dsn": "{{ .Data.user }}@{{ .Namespace }}", "missing": "{{ .Data.nope }}"},
+	}
+
+	// Referencing a missing value fails instead of rendering an empty value
+	ctx := context.Background()
+	if err := reconciler.copySecret(ctx, fakeClient, secretRef, "ephemeral-test", ephApp); err == nil {
+		t.Fatal("expected an error for a template referencing a missing value")
+	}
+
+	delete(secretRef.Templates, "missing")
+	if err := reconciler.copySecret(ctx, fakeClient, secretRef, "ephemeral-test", ephApp); err != nil {
+		t.Fatalf("copySecret failed: %v", err)
+	}
+	copied := &corev1.Secret{}
+	if err := fakeClient.Get(ctx, client.ObjectKey{Namespace: "ephemeral-test", Name: "app-config"}, copied); err != nil {
+		t.Fatalf("failed to get secret: %v", err)
+	}
+	if string(copied.Data["dsn"]) != "app@ephemeral-test" || string(copied.Data["user"]) != "app" {
+		t.Errorf("expected inline and rendered values, got %v", copied.Data)
+	}
+}
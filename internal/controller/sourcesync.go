@@ -0,0 +1,193 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	ephemeralv1alpha1 "github.com/jbarea/argo-ephemeral-operator/api/v1alpha1"
+)
+
+const (
+	// continuousSourceIndex indexes EphemeralApplications and templates by the secrets and
+	// configmaps they copy continuously, as "Kind/namespace/name"
+	continuousSourceIndex = "spec.continuousSources"
+
+	secretKind    = "Secret"
+	configMapKind = "ConfigMap"
+)
+
+// sourceKey returns the index key of a copied object
+func sourceKey(kind, namespace, name string) string {
+	return kind + "/" + namespace + "/" + name
+}
+
+// continuousSources returns the index keys of the secrets and configmaps copied continuously
+func continuousSources(secrets []ephemeralv1alpha1.SecretReference, configMaps []ephemeralv1alpha1.ConfigMapReference) []string {
+	var keys []string
+	for _, secret := range secrets {
+		if secret.SyncMode == ephemeralv1alpha1.SyncModeContinuous && copiedFromSource(secret) {
+			keys = append(keys, sourceKey(secretKind, secret.SourceNamespace, secret.Name))
+		}
+	}
+	for _, cm := range configMaps {
+		if cm.SyncMode == ephemeralv1alpha1.SyncModeContinuous && cm.SourceNamespace != "" {
+			keys = append(keys, sourceKey(configMapKind, cm.SourceNamespace, cm.Name))
+		}
+	}
+	return keys
+}
+
+// indexApplicationSources is the continuousSourceIndex function of EphemeralApplications
+func indexApplicationSources(obj client.Object) []string {
+	ephApp := obj.(*ephemeralv1alpha1.EphemeralApplication)
+	return continuousSources(ephApp.Spec.Secrets, ephApp.Spec.ConfigMaps)
+}
+
+// indexTemplateSources is the continuousSourceIndex function of EphemeralApplicationTemplates
+func indexTemplateSources(obj client.Object) []string {
+	template := obj.(*ephemeralv1alpha1.EphemeralApplicationTemplate)
+	return continuousSources(template.Spec.Secrets, template.Spec.ConfigMaps)
+}
+
+// findApplicationsForSource returns a map function from a secret or configmap of the given
+// kind to the EphemeralApplications copying it continuously, directly or through their template
+func (r *EphemeralApplicationReconciler) findApplicationsForSource(kind string) handler.MapFunc {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		logger := log.FromContext(ctx)
+		key := client.MatchingFields{continuousSourceIndex: sourceKey(kind, obj.GetNamespace(), obj.GetName())}
+
+		list := &ephemeralv1alpha1.EphemeralApplicationList{}
+		if err := r.List(ctx, list, key); err != nil {
+			logger.Error(err, "failed to list EphemeralApplications for source", "kind", kind, "name", obj.GetName())
+			return nil
+		}
+		var requests []reconcile.Request
+		for _, ephApp := range list.Items {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: client.ObjectKeyFromObject(&ephApp),
+			})
+		}
+
+		templates := &ephemeralv1alpha1.EphemeralApplicationTemplateList{}
+		if err := r.List(ctx, templates, key); err != nil {
+			logger.Error(err, "failed to list templates for source", "kind", kind, "name", obj.GetName())
+			return requests
+		}
+		for i := range templates.Items {
+			requests = append(requests, r.findApplicationsForTemplate(ctx, &templates.Items[i])...)
+		}
+		return requests
+	}
+}
+
+// syncInjectedResources copies again the secrets and configmaps that failed to be injected or
+// are missing from the status, and the continuously synced ones whose source changed since they
+// were last copied. Failures are recorded in the status of each object and retried later
+func (r *EphemeralApplicationReconciler) syncInjectedResources(ctx context.Context, ephApp *ephemeralv1alpha1.EphemeralApplication) error {
+	logger := log.FromContext(ctx)
+	namespace := ephApp.Status.Namespace
+	before := ephApp.Status.DeepCopy()
+
+	var target client.Client
+	// The destination is only resolved once an object has to be copied
+	targetClient := func() (client.Client, error) {
+		if target == nil {
+			cluster, err := r.targetCluster(ctx, ephApp)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve destination cluster: %w", err)
+			}
+			target = cluster.Client
+		}
+		return target, nil
+	}
+
+	for _, secretRef := range ephApp.Spec.Secrets {
+		name := secretTargetName(secretRef)
+		continuous := secretRef.SyncMode == ephemeralv1alpha1.SyncModeContinuous && copiedFromSource(secretRef)
+		stale, err := r.needsInjection(ctx, ephApp, &corev1.Secret{}, secretKind, name, secretRef.SourceNamespace, secretRef.Name, continuous)
+		if err == nil && !stale {
+			continue
+		}
+		if err == nil {
+			logger.Info("copying secret again", "name", name)
+			var c client.Client
+			if c, err = targetClient(); err == nil {
+				err = r.copySecret(ctx, c, secretRef, namespace, ephApp)
+			}
+		}
+		if err != nil {
+			logger.Error(err, "failed to sync secret", "name", name)
+			recordInjectionError(ephApp, secretStatus(secretRef), err)
+		}
+	}
+
+	for _, cmRef := range ephApp.Spec.ConfigMaps {
+		name := configMapTargetName(cmRef)
+		continuous := cmRef.SyncMode == ephemeralv1alpha1.SyncModeContinuous && cmRef.SourceNamespace != ""
+		stale, err := r.needsInjection(ctx, ephApp, &corev1.ConfigMap{}, configMapKind, name, cmRef.SourceNamespace, cmRef.Name, continuous)
+		if err == nil && !stale {
+			continue
+		}
+		if err == nil {
+			logger.Info("copying configmap again", "name", name)
+			var c client.Client
+			if c, err = targetClient(); err == nil {
+				err = r.copyConfigMap(ctx, c, cmRef, namespace, ephApp)
+			}
+		}
+		if err != nil {
+			logger.Error(err, "failed to sync configmap", "name", name)
+			recordInjectionError(ephApp, configMapStatus(cmRef), err)
+		}
+	}
+
+	// Updating the status triggers another reconcile, only do it when something changed
+	r.setInjectionCondition(ephApp)
+	if equality.Semantic.DeepEqual(before, &ephApp.Status) {
+		return nil
+	}
+	return r.updateStatus(ctx, ephApp)
+}
+
+// needsInjection reports whether an object has to be copied again: it is missing from the
+// status, failed the last time or, when synced continuously, its source has changed
+func (r *EphemeralApplicationReconciler) needsInjection(
+	ctx context.Context,
+	ephApp *ephemeralv1alpha1.EphemeralApplication,
+	source client.Object,
+	kind, name, sourceNamespace, sourceName string,
+	continuous bool,
+) (bool, error) {
+	injected := findInjected(ephApp, kind, name)
+	if injected == nil || injected.Error != "" {
+		return true, nil
+	}
+	if !continuous {
+		return false, nil
+	}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: sourceNamespace, Name: sourceName}, source); err != nil {
+		return false, fmt.Errorf("failed to get source %s %s/%s: %w", kind, sourceNamespace, sourceName, err)
+	}
+	return source.GetResourceVersion() != injected.LastSyncedResourceVersion, nil
+}
+
+// copiedFromSource reports whether a secret is copied from its source namespace
+// instead of being created from inline, templated or generated values
+func copiedFromSource(secretRef ephemeralv1alpha1.SecretReference) bool {
+	return len(secretRef.Values) == 0 && secretRef.Generate == nil && secretRef.SourceNamespace != ""
+}
+
+// secretTargetName returns the name of the copy of a secret in the ephemeral namespace
+func secretTargetName(secretRef ephemeralv1alpha1.SecretReference) string {
+	if secretRef.TargetName != "" {
+		return secretRef.TargetName
+	}
+	return secretRef.Name
+}
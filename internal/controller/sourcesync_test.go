@@ -0,0 +1,291 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	ephemeralv1alpha1 "github.com/jbarea/argo-ephemeral-operator/api/v1alpha1"
+)
+
+func TestSyncInjectedResources_SourceRotated(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = ephemeralv1alpha1.AddToScheme(scheme)
+
+	sourceSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "db-credentials", Namespace: "shared-secrets"},
+		Data:       map[string][]byte{"password": []byte("old")},
+	}
+	sourceCM := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-config", Namespace: "shared-config"},
+		Data:       map[string]string{"LOG_LEVEL": "info"},
+	}
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-app", Namespace: "default"},
+		Spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+			Secrets: []ephemeralv1alpha1.SecretReference{
+				{Name: "db-credentials", SourceNamespace: "shared-secrets", SyncMode: ephemeralv1alpha1.SyncModeContinuous},
+			},
+			ConfigMaps: []ephemeralv1alpha1.ConfigMapReference{
+				{Name: "app-config", SourceNamespace: "shared-config", SyncMode: ephemeralv1alpha1.SyncModeContinuous},
+			},
+		},
+		Status: ephemeralv1alpha1.EphemeralApplicationStatus{
+			Phase:     ephemeralv1alpha1.PhaseActive,
+			Namespace: "ephemeral-test",
+		},
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(sourceSecret, sourceCM, ephApp).
+		WithStatusSubresource(ephApp).
+		Build()
+	reconciler := &EphemeralApplicationReconciler{Client: fakeClient, Scheme: scheme}
+	ctx := context.Background()
+
+	// The first sync copies both sources
+	if err := reconciler.syncInjectedResources(ctx, ephApp); err != nil {
+		t.Fatalf("syncInjectedResources failed: %v", err)
+	}
+	if len(ephApp.Status.InjectedResources) != 2 {
+		t.Fatalf("expected 2 injected resources, got %v", ephApp.Status.InjectedResources)
+	}
+	firstHash := findInjected(ephApp, secretKind, "db-credentials").Hash
+
+	// Rotate the source secret
+	if err := fakeClient.Get(ctx, client.ObjectKeyFromObject(sourceSecret), sourceSecret); err != nil {
+		t.Fatal(err)
+	}
+	sourceSecret.Data["password"] = []byte("new")
+	if err := fakeClient.Update(ctx, sourceSecret); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := reconciler.syncInjectedResources(ctx, ephApp); err != nil {
+		t.Fatalf("syncInjectedResources failed: %v", err)
+	}
+
+	copied := &corev1.Secret{}
+	if err := fakeClient.Get(ctx, client.ObjectKey{Namespace: "ephemeral-test", Name: "db-credentials"}, copied); err != nil {
+		t.Fatalf("failed to get copied secret: %v", err)
+	}
+	if string(copied.Data["password"]) != "new" {
+		t.Errorf("expected the rotated password, got %q", copied.Data["password"])
+	}
+	injected := findInjected(ephApp, secretKind, "db-credentials")
+	if injected.LastSyncedResourceVersion != sourceSecret.ResourceVersion {
+		t.Errorf("expected last synced version %s, got %s", sourceSecret.ResourceVersion, injected.LastSyncedResourceVersion)
+	}
+	if injected.Hash == firstHash {
+		t.Error("expected the hash to change with the rotated password")
+	}
+
+	// The stored status records the new version too
+	stored := &ephemeralv1alpha1.EphemeralApplication{}
+	if err := fakeClient.Get(ctx, client.ObjectKeyFromObject(ephApp), stored); err != nil {
+		t.Fatal(err)
+	}
+	if got := findInjected(stored, secretKind, "db-credentials"); got == nil || got.LastSyncedResourceVersion != sourceSecret.ResourceVersion {
+		t.Errorf("expected stored last synced version %s, got %v", sourceSecret.ResourceVersion, got)
+	}
+}
+
+func TestSyncInjectedResources_OnceIsNotSynced(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = ephemeralv1alpha1.AddToScheme(scheme)
+
+	sourceSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "db-credentials", Namespace: "shared-secrets"},
+		Data:       map[string][]byte{"password": []byte("old")},
+	}
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-app", Namespace: "default"},
+		Spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+			Secrets: []ephemeralv1alpha1.SecretReference{
+				{Name: "db-credentials", SourceNamespace: "shared-secrets"},
+			},
+		},
+		Status: ephemeralv1alpha1.EphemeralApplicationStatus{
+			Phase:     ephemeralv1alpha1.PhaseActive,
+			Namespace: "ephemeral-test",
+			InjectedResources: []ephemeralv1alpha1.InjectedResourceStatus{
+				{Kind: secretKind, Name: "db-credentials", SourceNamespace: "shared-secrets", SourceName: "db-credentials", Hash: "abc"},
+			},
+		},
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(sourceSecret, ephApp).
+		WithStatusSubresource(ephApp).
+		Build()
+	reconciler := &EphemeralApplicationReconciler{Client: fakeClient, Scheme: scheme}
+
+	if err := reconciler.syncInjectedResources(context.Background(), ephApp); err != nil {
+		t.Fatalf("syncInjectedResources failed: %v", err)
+	}
+
+	copied := &corev1.Secret{}
+	err := fakeClient.Get(context.Background(), client.ObjectKey{Namespace: "ephemeral-test", Name: "db-credentials"}, copied)
+	if err == nil {
+		t.Error("expected a secret copied once not to be synced")
+	}
+}
+
+func TestSyncInjectedResources_RetriesFailed(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = ephemeralv1alpha1.AddToScheme(scheme)
+
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-app", Namespace: "default"},
+		Spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+			Secrets: []ephemeralv1alpha1.SecretReference{
+				{Name: "db-credentials", SourceNamespace: "shared-secrets"},
+				{Name: "api-keys", Values: map[string]string{"token": "abc"}},
+			},
+		},
+		Status: ephemeralv1alpha1.EphemeralApplicationStatus{
+			Phase:     ephemeralv1alpha1.PhaseActive,
+			Namespace: "ephemeral-test",
+		},
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(ephApp).
+		WithStatusSubresource(ephApp).
+		Build()
+	reconciler := &EphemeralApplicationReconciler{Client: fakeClient, Scheme: scheme}
+	ctx := context.Background()
+
+	// The source secret does not exist yet, the inline one is still injected
+	if err := reconciler.syncInjectedResources(ctx, ephApp); err != nil {
+		t.Fatalf("syncInjectedResources failed: %v", err)
+	}
+	if failed := findInjected(ephApp, secretKind, "db-credentials"); failed == nil || failed.Error == "" {
+		t.Fatalf("expected the missing source to be reported, got %v", failed)
+	}
+	if inline := findInjected(ephApp, secretKind, "api-keys"); inline == nil || inline.Error != "" || inline.Hash == "" {
+		t.Errorf("expected the inline secret to be injected, got %v", inline)
+	}
+	condition := meta.FindStatusCondition(ephApp.Status.Conditions, "ResourcesInjected")
+	if condition == nil || condition.Status != metav1.ConditionFalse || condition.Reason != "InjectionFailed" {
+		t.Fatalf("expected a failed ResourcesInjected condition, got %v", condition)
+	}
+	if !strings.Contains(condition.Message, "Secret db-credentials") {
+		t.Errorf("expected the failed secret in the message, got %q", condition.Message)
+	}
+
+	// Once the source shows up the next sync injects it
+	sourceSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "db-credentials", Namespace: "shared-secrets"},
+		Data:       map[string][]byte{"password": []byte("secret")},
+	}
+	if err := fakeClient.Create(ctx, sourceSecret); err != nil {
+		t.Fatal(err)
+	}
+	if err := reconciler.syncInjectedResources(ctx, ephApp); err != nil {
+		t.Fatalf("syncInjectedResources failed: %v", err)
+	}
+	if injected := findInjected(ephApp, secretKind, "db-credentials"); injected.Error != "" || injected.SourceNamespace != "shared-secrets" {
+		t.Errorf("expected the secret to be injected, got %v", injected)
+	}
+	condition = meta.FindStatusCondition(ephApp.Status.Conditions, "ResourcesInjected")
+	if condition == nil || condition.Status != metav1.ConditionTrue {
+		t.Errorf("expected the ResourcesInjected condition to be true, got %v", condition)
+	}
+}
+
+func TestContinuousSources(t *testing.T) {
+	keys := continuousSources(
+		[]ephemeralv1alpha1.SecretReference{
+			{Name: "once", SourceNamespace: "shared"},
+			{Name: "db", SourceNamespace: "shared", SyncMode: ephemeralv1alpha1.SyncModeContinuous},
+			{Name: "inline", SyncMode: ephemeralv1alpha1.SyncModeContinuous, Values: map[string]string{"a": "b"}},
+		},
+		[]ephemeralv1alpha1.ConfigMapReference{
+			{Name: "config", SourceNamespace: "shared", SyncMode: ephemeralv1alpha1.SyncModeContinuous},
+		},
+	)
+
+	expected := []string{"Secret/shared/db", "ConfigMap/shared/config"}
+	if len(keys) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, keys)
+	}
+	for i := range expected {
+		if keys[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected, keys)
+		}
+	}
+}
+
+func TestSyncInjectedResources_KeepsMergedTemplate(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = ephemeralv1alpha1.AddToScheme(scheme)
+
+	template := &ephemeralv1alpha1.EphemeralApplicationTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "backend", Namespace: "default"},
+		Spec: ephemeralv1alpha1.EphemeralApplicationTemplateSpec{
+			RepoURL: "https://github.com/example/backend.git",
+			Path:    "deploy",
+		},
+	}
+	sourceSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "db-credentials", Namespace: "shared-secrets"},
+		Data:       map[string][]byte{"password": []byte("secret")},
+	}
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-app", Namespace: "default"},
+		Spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+			TemplateRef: &ephemeralv1alpha1.TemplateReference{Name: "backend"},
+			Secrets:     []ephemeralv1alpha1.SecretReference{{Name: "db-credentials", SourceNamespace: "shared-secrets"}},
+		},
+		Status: ephemeralv1alpha1.EphemeralApplicationStatus{
+			Phase:     ephemeralv1alpha1.PhaseActive,
+			Namespace: "ephemeral-test",
+		},
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(template, sourceSecret, ephApp).
+		WithStatusSubresource(ephApp).
+		Build()
+	reconciler := &EphemeralApplicationReconciler{Client: fakeClient, Scheme: scheme}
+	ctx := context.Background()
+
+	if _, err := reconciler.resolveTemplate(ctx, ephApp); err != nil {
+		t.Fatalf("resolveTemplate failed: %v", err)
+	}
+	if err := reconciler.syncInjectedResources(ctx, ephApp); err != nil {
+		t.Fatalf("syncInjectedResources failed: %v", err)
+	}
+
+	// The status was written, the rest of the reconcile still sees the merged spec
+	if ephApp.Spec.RepoURL != template.Spec.RepoURL || ephApp.Spec.Path != template.Spec.Path {
+		t.Errorf("expected the merged source to be kept, got %q %q", ephApp.Spec.RepoURL, ephApp.Spec.Path)
+	}
+
+	stored := &ephemeralv1alpha1.EphemeralApplication{}
+	if err := fakeClient.Get(ctx, client.ObjectKeyFromObject(ephApp), stored); err != nil {
+		t.Fatal(err)
+	}
+	if len(stored.Status.InjectedResources) != 1 {
+		t.Errorf("expected the injected secret in the stored status, got %v", stored.Status.InjectedResources)
+	}
+	if stored.Spec.RepoURL != "" {
+		t.Errorf("expected the merged spec not to be written back, got %q", stored.Spec.RepoURL)
+	}
+}
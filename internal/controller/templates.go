@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	ephemeralv1alpha1 "github.com/jbarea/argo-ephemeral-operator/api/v1alpha1"
+)
+
+// resolveTemplate merges the referenced EphemeralApplicationTemplate into the spec
+// The merged spec is only kept in memory and must never be written back, the status is
+// written with updateStatus so it survives the update. It returns the generation of the
+// template or zero when no template is referenced
+func (r *EphemeralApplicationReconciler) resolveTemplate(ctx context.Context, ephApp *ephemeralv1alpha1.EphemeralApplication) (int64, error) {
+	if ephApp.Spec.TemplateRef == nil {
+		return 0, nil
+	}
+
+	template := &ephemeralv1alpha1.EphemeralApplicationTemplate{}
+	key := client.ObjectKey{
+		Namespace: ephApp.Namespace,
+		Name:      ephApp.Spec.TemplateRef.Name,
+	}
+	if err := r.Get(ctx, key, template); err != nil {
+		return 0, fmt.Errorf("failed to get template %s: %w", key.Name, err)
+	}
+
+	ephApp.Spec = ephApp.Spec.MergeTemplate(&template.Spec)
+	return template.Generation, nil
+}
+
+// updateStatus writes the status of an EphemeralApplication. The update decodes the stored
+// object into ephApp, so the spec merged by resolveTemplate is restored afterwards for the
+// rest of the reconcile
+func (r *EphemeralApplicationReconciler) updateStatus(ctx context.Context, ephApp *ephemeralv1alpha1.EphemeralApplication) error {
+	spec := ephApp.Spec.DeepCopy()
+	if err := r.Status().Update(ctx, ephApp); err != nil {
+		return err
+	}
+	ephApp.Spec = *spec
+	return nil
+}
+
+// findApplicationsForTemplate maps a template to the EphemeralApplications referencing it
+func (r *EphemeralApplicationReconciler) findApplicationsForTemplate(ctx context.Context, obj client.Object) []reconcile.Request {
+	logger := log.FromContext(ctx)
+
+	list := &ephemeralv1alpha1.EphemeralApplicationList{}
+	if err := r.List(ctx, list, client.InNamespace(obj.GetNamespace())); err != nil {
+		logger.Error(err, "failed to list EphemeralApplications for template", "template", obj.GetName())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, ephApp := range list.Items {
+		if ephApp.Spec.TemplateRef != nil && ephApp.Spec.TemplateRef.Name == obj.GetName() {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: client.ObjectKeyFromObject(&ephApp),
+			})
+		}
+	}
+	return requests
+}
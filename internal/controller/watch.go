@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/argoproj/argo-cd/v2/pkg/apiclient/application"
+	ephemeralv1alpha1 "github.com/jbarea/argo-ephemeral-operator/api/v1alpha1"
+	"github.com/jbarea/argo-ephemeral-operator/internal/argocd"
+)
+
+const (
+	// ownerLabel and ownerNamespaceLabel link an ArgoCD Application back to its EphemeralApplication
+	ownerLabel          = "ephemeral.argo.io/owner"
+	ownerNamespaceLabel = "ephemeral.argo.io/owner-namespace"
+
+	// pollInterval is how often the ArgoCD applications are polled while waiting for a sync
+	// when they are not watched
+	pollInterval = 30 * time.Second
+)
+
+// argoApplicationGVK is the kind of the ArgoCD Application custom resource
+var argoApplicationGVK = schema.GroupVersionKind{
+	Group:   "argoproj.io",
+	Version: "v1alpha1",
+	Kind:    "Application",
+}
+
+// NewArgoApplicationObject returns an empty ArgoCD Application, it is read as unstructured
+// so the operator does not depend on the ArgoCD API types being registered in the scheme
+func NewArgoApplicationObject() *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(argoApplicationGVK)
+	return obj
+}
+
+// applicationLabels returns the labels of the ArgoCD Applications of an EphemeralApplication
+func applicationLabels(ephApp *ephemeralv1alpha1.EphemeralApplication) map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/managed-by": "argo-ephemeral-operator",
+		ownerLabel:                     ephApp.Name,
+		ownerNamespaceLabel:            ephApp.Namespace,
+	}
+}
+
+// ownedBy reports whether the owner labels of an object point to the EphemeralApplication, the
+// owner namespace is only compared when it was recorded
+func ownedBy(labels map[string]string, ephApp *ephemeralv1alpha1.EphemeralApplication) bool {
+	if labels[ownerLabel] != ephApp.Name {
+		return false
+	}
+	namespace, ok := labels[ownerNamespaceLabel]
+	return !ok || namespace == ephApp.Namespace
+}
+
+// findApplicationForOwner maps an ArgoCD Application or a hook Job to the EphemeralApplication
+// that owns it using its owner labels
+func (r *EphemeralApplicationReconciler) findApplicationForOwner(ctx context.Context, obj client.Object) []reconcile.Request {
+	labels := obj.GetLabels()
+	name, namespace := labels[ownerLabel], labels[ownerNamespaceLabel]
+	if name == "" || namespace == "" {
+		return nil
+	}
+	return []reconcile.Request{{
+		NamespacedName: types.NamespacedName{Namespace: namespace, Name: name},
+	}}
+}
+
+// inArgoNamespace filters the watched ArgoCD Applications to the configured namespace
+func (r *EphemeralApplicationReconciler) inArgoNamespace(obj client.Object) bool {
+	return obj.GetNamespace() == r.Config.ArgoNamespace
+}
+
+// syncRequeueInterval returns the delay before checking again an environment waiting for
+// ArgoCD, changes of the watched applications trigger a reconcile on their own
+func (r *EphemeralApplicationReconciler) syncRequeueInterval() time.Duration {
+	if r.Config.WatchArgoApplications {
+		return r.Config.ReconcileInterval
+	}
+	return pollInterval
+}
+
+// getApplicationStatus returns the sync and health status of an ArgoCD Application, read
+// from the watched object when applications are watched and from the ArgoCD API otherwise
+// The cache may not have seen an application that was just created yet, so a cache miss is
+// confirmed with the ArgoCD API before the application is reported as missing
+func (r *EphemeralApplicationReconciler) getApplicationStatus(ctx context.Context, name string) (string, string, error) {
+	if r.Config.WatchArgoApplications {
+		obj := NewArgoApplicationObject()
+		key := client.ObjectKey{Namespace: r.Config.ArgoNamespace, Name: name}
+		err := r.Get(ctx, key, obj)
+		if err == nil {
+			syncStatus, _, _ := unstructured.NestedString(obj.Object, "status", "sync", "status")
+			healthStatus, _, _ := unstructured.NestedString(obj.Object, "status", "health", "status")
+			return syncStatus, healthStatus, nil
+		}
+		if !errors.IsNotFound(err) {
+			return "", "", argocd.FromKubernetesError(err)
+		}
+	}
+
+	appQuery := application.ApplicationQuery{
+		Name:         &name,
+		AppNamespace: &r.Config.ArgoNamespace,
+	}
+	argoApp, err := r.ArgoClient.GetApplication(ctx, appQuery)
+	if err != nil {
+		return "", "", err
+	}
+	return string(argoApp.Status.Sync.Status), string(argoApp.Status.Health.Status), nil
+}
@@ -0,0 +1,195 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	ephemeralv1alpha1 "github.com/jbarea/argo-ephemeral-operator/api/v1alpha1"
+	"github.com/jbarea/argo-ephemeral-operator/internal/config"
+)
+
+func TestFindApplicationForArgoApplication(t *testing.T) {
+	reconciler := &EphemeralApplicationReconciler{
+		Config: &config.Config{ArgoNamespace: "argocd"},
+	}
+
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "preview", Namespace: "team-a"},
+	}
+	owned := NewArgoApplicationObject()
+	owned.SetName("preview-api")
+	owned.SetNamespace("argocd")
+	owned.SetLabels(applicationLabels(ephApp))
+
+	requests := reconciler.findApplicationForOwner(context.Background(), owned)
+	if len(requests) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(requests))
+	}
+	if got := requests[0].NamespacedName; got.Name != "preview" || got.Namespace != "team-a" {
+		t.Errorf("expected request for team-a/preview, got %s", got)
+	}
+
+	unowned := NewArgoApplicationObject()
+	unowned.SetName("guestbook")
+	unowned.SetNamespace("argocd")
+	if requests := reconciler.findApplicationForOwner(context.Background(), unowned); len(requests) != 0 {
+		t.Errorf("expected no request for an unlabelled application, got %v", requests)
+	}
+
+	unowned.SetNamespace("other")
+	if reconciler.inArgoNamespace(unowned) {
+		t.Error("expected applications outside the ArgoCD namespace to be filtered")
+	}
+}
+
+func TestReconcile_ReadsWatchedApplicationStatus(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = networkingv1.AddToScheme(scheme)
+	_ = ephemeralv1alpha1.AddToScheme(scheme)
+
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "preview",
+			Namespace:  "default",
+			Generation: 1,
+			Finalizers: []string{finalizerName},
+		},
+		Spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+			RepoURL:        "https://github.com/example/app.git",
+			Path:           "manifests",
+			ExpirationDate: &metav1.Time{Time: time.Now().Add(time.Hour)},
+		},
+		Status: ephemeralv1alpha1.EphemeralApplicationStatus{
+			Phase:               ephemeralv1alpha1.PhaseCreating,
+			Namespace:           "ephemeral-test",
+			ArgoApplicationName: "preview",
+			ObservedGeneration:  1,
+		},
+	}
+
+	argoApp := NewArgoApplicationObject()
+	argoApp.SetName("preview")
+	argoApp.SetNamespace("argocd")
+	argoApp.SetLabels(applicationLabels(ephApp))
+	_ = unstructured.SetNestedField(argoApp.Object, "Synced", "status", "sync", "status")
+	_ = unstructured.SetNestedField(argoApp.Object, "Healthy", "status", "health", "status")
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(ephApp, argoApp).
+		WithStatusSubresource(ephApp).
+		Build()
+
+	// The ArgoCD API is never queried, the mock has no applications
+	reconciler := &EphemeralApplicationReconciler{
+		Client:     fakeClient,
+		Scheme:     scheme,
+		ArgoClient: newMockArgoClient(),
+		Config: &config.Config{
+			ArgoNamespace:         "argocd",
+			ReconcileInterval:     time.Minute,
+			WatchArgoApplications: true,
+		},
+	}
+
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ephApp)}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	updated := &ephemeralv1alpha1.EphemeralApplication{}
+	if err := fakeClient.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("failed to get EphemeralApplication: %v", err)
+	}
+	if updated.Status.Phase != ephemeralv1alpha1.PhaseActive {
+		t.Errorf("expected phase Active, got %s", updated.Status.Phase)
+	}
+}
+
+func TestReconcile_WatchedApplicationNotCachedYet(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = networkingv1.AddToScheme(scheme)
+	_ = ephemeralv1alpha1.AddToScheme(scheme)
+
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "preview",
+			Namespace:  "default",
+			Generation: 1,
+			Finalizers: []string{finalizerName},
+		},
+		Spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+			RepoURL:        "https://github.com/example/app.git",
+			Path:           "manifests",
+			ExpirationDate: &metav1.Time{Time: time.Now().Add(time.Hour)},
+		},
+		Status: ephemeralv1alpha1.EphemeralApplicationStatus{
+			Phase:               ephemeralv1alpha1.PhaseCreating,
+			Namespace:           "ephemeral-test",
+			ArgoApplicationName: "preview",
+			ObservedGeneration:  1,
+		},
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(ephApp).
+		WithStatusSubresource(ephApp).
+		Build()
+
+	// The application was just created, ArgoCD knows it but the cache has not seen it yet
+	argoClient := newMockArgoClient()
+	argoClient.apps["preview"] = &v1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "preview", Namespace: "argocd"},
+	}
+	reconciler := &EphemeralApplicationReconciler{
+		Client:     fakeClient,
+		Scheme:     scheme,
+		ArgoClient: argoClient,
+		Config: &config.Config{
+			ArgoNamespace:         "argocd",
+			ReconcileInterval:     time.Minute,
+			WatchArgoApplications: true,
+		},
+	}
+
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ephApp)}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	updated := &ephemeralv1alpha1.EphemeralApplication{}
+	if err := fakeClient.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("failed to get EphemeralApplication: %v", err)
+	}
+	if updated.Status.Phase != ephemeralv1alpha1.PhaseCreating {
+		t.Errorf("expected phase Creating, got %s: %s", updated.Status.Phase, updated.Status.Message)
+	}
+
+	// It is only reported as missing once ArgoCD does not know it either
+	delete(argoClient.apps, "preview")
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if err := fakeClient.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("failed to get EphemeralApplication: %v", err)
+	}
+	if updated.Status.Phase != ephemeralv1alpha1.PhaseFailed {
+		t.Errorf("expected phase Failed, got %s", updated.Status.Phase)
+	}
+}
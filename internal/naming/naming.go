@@ -1,20 +1,30 @@
-package controller
+// Package naming generates the names of the ephemeral namespaces, it is shared by the
+// reconciler and the admission webhooks
+package naming
 
 import (
 	"fmt"
 	"math/rand"
 	"strings"
+	"sync"
 	"time"
 )
 
-// DefaultNameGenerator is the default implementation of NameGenerator
-type DefaultNameGenerator struct {
+// Generator generates unique namespace names
+type Generator interface {
+	GenerateNamespace(prefix, suffix string) string
+}
+
+// DefaultGenerator is the default implementation of Generator, it is shared by the
+// reconciler and the webhook so the random source is guarded by a mutex
+type DefaultGenerator struct {
+	mu  sync.Mutex
 	rnd *rand.Rand
 }
 
-// NewDefaultNameGenerator creates a new DefaultNameGenerator with random seed
-func NewDefaultNameGenerator() *DefaultNameGenerator {
-	return &DefaultNameGenerator{
+// NewDefaultGenerator creates a new DefaultGenerator with random seed
+func NewDefaultGenerator() *DefaultGenerator {
+	return &DefaultGenerator{
 		rnd: rand.New(rand.NewSource(time.Now().UnixNano())),
 	}
 }
@@ -22,7 +32,7 @@ func NewDefaultNameGenerator() *DefaultNameGenerator {
 // GenerateNamespace generates a namespace name
 // If namespaceName is provided, uses it directly
 // Otherwise generates "ephemeral-{random}"
-func (g *DefaultNameGenerator) GenerateNamespace(namespaceName, _ string) string {
+func (g *DefaultGenerator) GenerateNamespace(namespaceName, _ string) string {
 	if namespaceName != "" {
 		// Use provided name directly, sanitize it
 		sanitized := strings.ToLower(namespaceName)
@@ -39,9 +49,11 @@ func (g *DefaultNameGenerator) GenerateNamespace(namespaceName, _ string) string
 	// Generate random suffix (7 characters)
 	const charset = "abcdefghijklmnopqrstuvwxyz0123456789"
 	suffix := make([]byte, 7)
+	g.mu.Lock()
 	for i := range suffix {
 		suffix[i] = charset[g.rnd.Intn(len(charset))]
 	}
+	g.mu.Unlock()
 
 	return fmt.Sprintf("ephemeral-%s", string(suffix))
 }
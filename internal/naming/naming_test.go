@@ -0,0 +1,100 @@
+package naming
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestDefaultGenerator_GenerateNamespace(t *testing.T) {
+	tests := []struct {
+		name          string
+		namespaceName string
+		wantLen       int
+		validate      func(t *testing.T, result string)
+	}{
+		{
+			name:          "custom namespace name",
+			namespaceName: "my-custom-namespace",
+			wantLen:       63,
+			validate: func(t *testing.T, result string) {
+				if result != "my-custom-namespace" {
+					t.Errorf("expected 'my-custom-namespace', got '%s'", result)
+				}
+			},
+		},
+		{
+			name:          "auto-generated namespace",
+			namespaceName: "",
+			wantLen:       63,
+			validate: func(t *testing.T, result string) {
+				if !strings.HasPrefix(result, "ephemeral-") {
+					t.Errorf("expected prefix 'ephemeral-', got '%s'", result)
+				}
+				if len(result) != 17 { // "ephemeral-" (10) + 7 random chars
+					t.Errorf("expected length 17, got %d", len(result))
+				}
+			},
+		},
+		{
+			name:          "long custom name",
+			namespaceName: "this-is-a-very-long-namespace-name-that-exceeds-kubernetes-limits",
+			wantLen:       63,
+			validate: func(t *testing.T, result string) {
+				if len(result) > 63 {
+					t.Errorf("result length %d exceeds 63 characters", len(result))
+				}
+			},
+		},
+		{
+			name:          "name with underscores",
+			namespaceName: "my_custom_namespace",
+			wantLen:       63,
+			validate: func(t *testing.T, result string) {
+				if result != "my-custom-namespace" {
+					t.Errorf("expected 'my-custom-namespace', got '%s'", result)
+				}
+			},
+		},
+	}
+
+	gen := NewDefaultGenerator()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := gen.GenerateNamespace(tt.namespaceName, "")
+
+			if len(got) > tt.wantLen {
+				t.Errorf("GenerateNamespace() length = %v, want <= %v", len(got), tt.wantLen)
+			}
+
+			if tt.validate != nil {
+				tt.validate(t, got)
+			}
+		})
+	}
+}
+
+func TestDefaultGenerator_Concurrent(t *testing.T) {
+	gen := NewDefaultGenerator()
+
+	// The generator is shared by the reconciler and the webhook, run with -race
+	var wg sync.WaitGroup
+	names := make([]string, 8)
+	for i := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				names[i] = gen.GenerateNamespace("", "")
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, name := range names {
+		if !strings.HasPrefix(name, "ephemeral-") || len(name) != 17 {
+			t.Errorf("unexpected namespace %q", name)
+		}
+	}
+}
@@ -0,0 +1,336 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	ephemeralv1alpha1 "github.com/jbarea/argo-ephemeral-operator/api/v1alpha1"
+)
+
+// Policies is the set of EphemeralPolicies in the cluster, every policy must be satisfied
+type Policies []ephemeralv1alpha1.EphemeralPolicy
+
+// List returns the EphemeralPolicies defined in the cluster
+func List(ctx context.Context, c client.Reader) (Policies, error) {
+	list := &ephemeralv1alpha1.EphemeralPolicyList{}
+	if err := c.List(ctx, list); err != nil {
+		return nil, fmt.Errorf("failed to list ephemeral policies: %w", err)
+	}
+	return Policies(list.Items), nil
+}
+
+// CheckSpec verifies the repositories, the source namespaces and the destination of an environment
+// The spec must already have its template merged
+func (p Policies) CheckSpec(ephApp *ephemeralv1alpha1.EphemeralApplication) error {
+	for _, policy := range p {
+		if err := checkDestination(policy, ephApp.Spec.Destination); err != nil {
+			return err
+		}
+
+		for _, component := range ephApp.Spec.ResolvedComponents() {
+			if !matchesAny(policy.Spec.AllowedRepoURLs, component.RepoURL) {
+				return fmt.Errorf("policy %s: repository %q is not allowed", policy.Name, component.RepoURL)
+			}
+		}
+
+		for _, secret := range ephApp.Spec.Secrets {
+			// Inline and generated secrets are not copied from any namespace
+			if secret.SourceNamespace == "" {
+				continue
+			}
+			if !allowsNamespace(policy.Spec.AllowedSourceNamespaces, secret.SourceNamespace) {
+				return fmt.Errorf("policy %s: secrets can not be copied from namespace %q", policy.Name, secret.SourceNamespace)
+			}
+		}
+		for _, cm := range ephApp.Spec.ConfigMaps {
+			// Inline configmaps are not copied from any namespace
+			if cm.SourceNamespace == "" {
+				continue
+			}
+			if !allowsNamespace(policy.Spec.AllowedSourceNamespaces, cm.SourceNamespace) {
+				return fmt.Errorf("policy %s: configmaps can not be copied from namespace %q", policy.Name, cm.SourceNamespace)
+			}
+		}
+	}
+	return nil
+}
+
+// checkDestination verifies that the destination cluster is allowed by a policy
+func checkDestination(policy ephemeralv1alpha1.EphemeralPolicy, destination *ephemeralv1alpha1.Destination) error {
+	if destination == nil || len(policy.Spec.AllowedDestinations) == 0 {
+		return nil
+	}
+	if destination.Name == "" {
+		return fmt.Errorf("policy %s: only the allowed ArgoCD clusters can be used as destination", policy.Name)
+	}
+	if !matchesAny(policy.Spec.AllowedDestinations, destination.Name) {
+		return fmt.Errorf("policy %s: destination %q is not allowed", policy.Name, destination.Name)
+	}
+	return nil
+}
+
+// CheckLifetime verifies that the requested expiry of an environment is within the maximum lifetime
+func (p Policies) CheckLifetime(ephApp *ephemeralv1alpha1.EphemeralApplication) error {
+	var lifetime time.Duration
+	switch {
+	case ephApp.Spec.TTL != nil:
+		lifetime = ephApp.Spec.TTL.Duration
+	case ephApp.Spec.ExpirationDate != nil:
+		lifetime = ephApp.Spec.ExpirationDate.Sub(creationTime(ephApp))
+	default:
+		return nil
+	}
+
+	for _, policy := range p {
+		if policy.Spec.MaxTTL != nil && lifetime > policy.Spec.MaxTTL.Duration {
+			return fmt.Errorf("policy %s: lifetime %s exceeds the maximum of %s",
+				policy.Name, lifetime.Round(time.Second), policy.Spec.MaxTTL.Duration)
+		}
+	}
+	return nil
+}
+
+// CheckExtensions verifies that the expiry of an environment can be postponed for the given number of times
+func (p Policies) CheckExtensions(extensions int32) error {
+	for _, policy := range p {
+		if policy.Spec.MaxExtensions != nil && extensions > *policy.Spec.MaxExtensions {
+			return fmt.Errorf("policy %s: the expiry can be extended at most %d times",
+				policy.Name, *policy.Spec.MaxExtensions)
+		}
+	}
+	return nil
+}
+
+// MaxExpiry returns the latest expiry allowed for an environment, or nil when there is no limit
+func (p Policies) MaxExpiry(ephApp *ephemeralv1alpha1.EphemeralApplication) *metav1.Time {
+	var maxExpiry *metav1.Time
+	for _, policy := range p {
+		if policy.Spec.MaxTTL == nil {
+			continue
+		}
+		expiry := metav1.NewTime(creationTime(ephApp).Add(policy.Spec.MaxTTL.Duration)).Rfc3339Copy()
+		if maxExpiry == nil || expiry.Before(maxExpiry) {
+			maxExpiry = &expiry
+		}
+	}
+	return maxExpiry
+}
+
+// CheckQuota verifies that one more environment fits in the namespace and user limits
+// The environment itself is not counted and user is empty when the creator is unknown.
+// Environments still waiting in the Pending phase are only counted when includePending is set
+func (p Policies) CheckQuota(
+	ctx context.Context,
+	c client.Reader,
+	ephApp *ephemeralv1alpha1.EphemeralApplication,
+	user string,
+	includePending bool,
+) error {
+	var namespaceLimit, userLimit *int32
+	var namespacePolicy, userPolicy string
+	for _, policy := range p {
+		if limit := policy.Spec.MaxEnvironmentsPerNamespace; limit != nil && (namespaceLimit == nil || *limit < *namespaceLimit) {
+			namespaceLimit, namespacePolicy = limit, policy.Name
+		}
+		if limit := policy.Spec.MaxEnvironmentsPerUser; limit != nil && (userLimit == nil || *limit < *userLimit) {
+			userLimit, userPolicy = limit, policy.Name
+		}
+	}
+	if namespaceLimit == nil && (userLimit == nil || user == "") {
+		return nil
+	}
+
+	list := &ephemeralv1alpha1.EphemeralApplicationList{}
+	if err := c.List(ctx, list); err != nil {
+		return fmt.Errorf("failed to list ephemeral applications: %w", err)
+	}
+
+	var inNamespace, byUser int32
+	for _, other := range list.Items {
+		if other.Namespace == ephApp.Namespace && other.Name == ephApp.Name {
+			continue
+		}
+		if !other.DeletionTimestamp.IsZero() {
+			continue
+		}
+		// Pending environments running their preSync hooks already hold a namespace
+		pending := (other.Status.Phase == "" || other.Status.Phase == ephemeralv1alpha1.PhasePending) &&
+			other.Status.Namespace == ""
+		if pending && !includePending {
+			continue
+		}
+		if other.Namespace == ephApp.Namespace {
+			inNamespace++
+		}
+		if user != "" && other.Annotations[ephemeralv1alpha1.CreatedByAnnotation] == user {
+			byUser++
+		}
+	}
+
+	if namespaceLimit != nil && inNamespace >= *namespaceLimit {
+		return fmt.Errorf("policy %s: namespace %s already has %d environments, the maximum is %d",
+			namespacePolicy, ephApp.Namespace, inNamespace, *namespaceLimit)
+	}
+	if userLimit != nil && user != "" && byUser >= *userLimit {
+		return fmt.Errorf("policy %s: user %s already has %d environments, the maximum is %d",
+			userPolicy, user, byUser, *userLimit)
+	}
+	return nil
+}
+
+// AppProject returns the AppProject settings of the environments combining every policy
+// The source repositories and the blacklists of all policies are added up, while only the
+// cluster resources whitelisted by every policy that defines a whitelist are allowed
+func (p Policies) AppProject() ephemeralv1alpha1.AppProjectPolicy {
+	var merged ephemeralv1alpha1.AppProjectPolicy
+	whitelisted := false
+	for _, policy := range p {
+		project := policy.Spec.AppProject
+		if project == nil {
+			continue
+		}
+		for _, repo := range project.SourceRepos {
+			if !slices.Contains(merged.SourceRepos, repo) {
+				merged.SourceRepos = append(merged.SourceRepos, repo)
+			}
+		}
+		for _, gk := range project.ClusterResourceBlacklist {
+			if !slices.Contains(merged.ClusterResourceBlacklist, gk) {
+				merged.ClusterResourceBlacklist = append(merged.ClusterResourceBlacklist, gk)
+			}
+		}
+		if len(project.ClusterResourceWhitelist) == 0 {
+			continue
+		}
+		if !whitelisted {
+			merged.ClusterResourceWhitelist = slices.Clone(project.ClusterResourceWhitelist)
+			whitelisted = true
+			continue
+		}
+		merged.ClusterResourceWhitelist = slices.DeleteFunc(merged.ClusterResourceWhitelist, func(gk metav1.GroupKind) bool {
+			return !slices.Contains(project.ClusterResourceWhitelist, gk)
+		})
+	}
+	return merged
+}
+
+// NamespaceOptions returns the options of the ephemeral namespace of an environment on top of
+// the defaults of the policies. Labels, annotations and container defaults of the environment
+// override those of the policies, while quotas and container maximums keep the lowest value
+// and network isolation can not be turned off
+func (p Policies) NamespaceOptions(options *ephemeralv1alpha1.NamespaceOptions) ephemeralv1alpha1.NamespaceOptions {
+	var merged ephemeralv1alpha1.NamespaceOptions
+	for _, policy := range p {
+		if policy.Spec.Namespace != nil {
+			mergeNamespaceOptions(&merged, policy.Spec.Namespace)
+		}
+	}
+	if options != nil {
+		mergeNamespaceOptions(&merged, options)
+	}
+	return merged
+}
+
+// mergeNamespaceOptions merges options into merged, see NamespaceOptions
+func mergeNamespaceOptions(merged, options *ephemeralv1alpha1.NamespaceOptions) {
+	merged.Labels = mergeStrings(merged.Labels, options.Labels)
+	merged.Annotations = mergeStrings(merged.Annotations, options.Annotations)
+	merged.ResourceQuota = minResources(merged.ResourceQuota, options.ResourceQuota)
+
+	if options.LimitRange != nil {
+		if merged.LimitRange == nil {
+			merged.LimitRange = &ephemeralv1alpha1.ContainerLimits{}
+		}
+		merged.LimitRange.Default = overrideResources(merged.LimitRange.Default, options.LimitRange.Default)
+		merged.LimitRange.DefaultRequest = overrideResources(merged.LimitRange.DefaultRequest, options.LimitRange.DefaultRequest)
+		merged.LimitRange.Max = minResources(merged.LimitRange.Max, options.LimitRange.Max)
+	}
+
+	if options.NetworkPolicy != nil {
+		if merged.NetworkPolicy == nil {
+			merged.NetworkPolicy = &ephemeralv1alpha1.NetworkIsolation{}
+		}
+		for _, namespace := range options.NetworkPolicy.AllowedNamespaces {
+			if !slices.Contains(merged.NetworkPolicy.AllowedNamespaces, namespace) {
+				merged.NetworkPolicy.AllowedNamespaces = append(merged.NetworkPolicy.AllowedNamespaces, namespace)
+			}
+		}
+	}
+}
+
+// mergeStrings returns base with the values of overrides
+func mergeStrings(base, overrides map[string]string) map[string]string {
+	if len(overrides) == 0 {
+		return base
+	}
+	if base == nil {
+		base = make(map[string]string, len(overrides))
+	}
+	for k, v := range overrides {
+		base[k] = v
+	}
+	return base
+}
+
+// overrideResources returns base with the quantities of overrides
+func overrideResources(base, overrides corev1.ResourceList) corev1.ResourceList {
+	if len(overrides) == 0 {
+		return base
+	}
+	if base == nil {
+		base = make(corev1.ResourceList, len(overrides))
+	}
+	for name, quantity := range overrides {
+		base[name] = quantity.DeepCopy()
+	}
+	return base
+}
+
+// minResources returns base with the quantities of limits that are lower or missing
+func minResources(base, limits corev1.ResourceList) corev1.ResourceList {
+	if len(limits) == 0 {
+		return base
+	}
+	if base == nil {
+		base = make(corev1.ResourceList, len(limits))
+	}
+	for name, quantity := range limits {
+		if current, ok := base[name]; !ok || quantity.Cmp(current) < 0 {
+			base[name] = quantity.DeepCopy()
+		}
+	}
+	return base
+}
+
+// creationTime returns when the environment was created, or now if it is being created
+func creationTime(ephApp *ephemeralv1alpha1.EphemeralApplication) time.Time {
+	if ephApp.CreationTimestamp.IsZero() {
+		return time.Now()
+	}
+	return ephApp.CreationTimestamp.Time
+}
+
+// matchesAny reports whether the value matches one of the glob patterns, an empty list matches everything
+func matchesAny(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if matched, err := path.Match(pattern, value); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsNamespace reports whether the namespace is in the allowed list, an empty list allows everything
+func allowsNamespace(allowed []string, namespace string) bool {
+	return len(allowed) == 0 || slices.Contains(allowed, namespace)
+}
@@ -0,0 +1,316 @@
+package policy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	ephemeralv1alpha1 "github.com/jbarea/argo-ephemeral-operator/api/v1alpha1"
+)
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func TestCheckSpec(t *testing.T) {
+	policies := Policies{{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec: ephemeralv1alpha1.EphemeralPolicySpec{
+			AllowedRepoURLs:         []string{"https://github.com/my-org/*"},
+			AllowedSourceNamespaces: []string{"shared"},
+			AllowedDestinations:     []string{"preview-*"},
+		},
+	}}
+
+	tests := []struct {
+		name    string
+		spec    ephemeralv1alpha1.EphemeralApplicationSpec
+		wantErr bool
+	}{
+		{
+			name: "allowed repository",
+			spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+				RepoURL: "https://github.com/my-org/app.git",
+			},
+		},
+		{
+			name: "repository outside the organization",
+			spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+				RepoURL: "https://github.com/other/app.git",
+			},
+			wantErr: true,
+		},
+		{
+			name: "component repository is checked",
+			spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+				Components: []ephemeralv1alpha1.Component{
+					{Name: "api", RepoURL: "https://github.com/my-org/api.git"},
+					{Name: "db", RepoURL: "https://gitlab.com/my-org/db.git"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "secret from a forbidden namespace",
+			spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+				RepoURL: "https://github.com/my-org/app.git",
+				Secrets: []ephemeralv1alpha1.SecretReference{
+					{Name: "db", SourceNamespace: "production"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "inline configmap is allowed",
+			spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+				RepoURL: "https://github.com/my-org/app.git",
+				ConfigMaps: []ephemeralv1alpha1.ConfigMapReference{
+					{Name: "settings", Data: map[string]string{"key": "value"}},
+				},
+			},
+		},
+		{
+			name: "allowed destination",
+			spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+				RepoURL:     "https://github.com/my-org/app.git",
+				Destination: &ephemeralv1alpha1.Destination{Name: "preview-eu"},
+			},
+		},
+		{
+			name: "destination outside the allowed clusters",
+			spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+				RepoURL:     "https://github.com/my-org/app.git",
+				Destination: &ephemeralv1alpha1.Destination{Name: "production"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "kubeconfig destination is rejected",
+			spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+				RepoURL: "https://github.com/my-org/app.git",
+				Destination: &ephemeralv1alpha1.Destination{
+					KubeconfigSecretRef: &ephemeralv1alpha1.KubeconfigSecretReference{Name: "preview-eu"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "generated secret is allowed",
+			spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+				RepoURL: "https://github.com/my-org/app.git",
+				Secrets: []ephemeralv1alpha1.SecretReference{{
+					Name: "db",
+					Generate: &ephemeralv1alpha1.SecretGenerator{
+						Passwords: []ephemeralv1alpha1.PasswordGenerator{{Key: "password"}},
+					},
+				}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ephApp := &ephemeralv1alpha1.EphemeralApplication{Spec: tt.spec}
+			err := policies.CheckSpec(ephApp)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckSpec() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckLifetime(t *testing.T) {
+	policies := Policies{{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec: ephemeralv1alpha1.EphemeralPolicySpec{
+			MaxTTL: &metav1.Duration{Duration: 24 * time.Hour},
+		},
+	}}
+
+	short := &ephemeralv1alpha1.EphemeralApplication{
+		Spec: ephemeralv1alpha1.EphemeralApplicationSpec{TTL: &metav1.Duration{Duration: 4 * time.Hour}},
+	}
+	if err := policies.CheckLifetime(short); err != nil {
+		t.Errorf("expected a 4h lifetime to be allowed, got %v", err)
+	}
+
+	long := &ephemeralv1alpha1.EphemeralApplication{
+		Spec: ephemeralv1alpha1.EphemeralApplicationSpec{TTL: &metav1.Duration{Duration: 48 * time.Hour}},
+	}
+	if err := policies.CheckLifetime(long); err == nil {
+		t.Error("expected a 48h lifetime to be rejected")
+	}
+
+	created := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+	long.CreationTimestamp = created
+	want := created.Add(24 * time.Hour)
+	if got := policies.MaxExpiry(long); got == nil || !got.Time.Equal(want) {
+		t.Errorf("expected max expiry %v, got %v", want, got)
+	}
+}
+
+func TestCheckExtensions(t *testing.T) {
+	policies := Policies{{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec:       ephemeralv1alpha1.EphemeralPolicySpec{MaxExtensions: int32Ptr(2)},
+	}}
+
+	if err := policies.CheckExtensions(2); err != nil {
+		t.Errorf("expected the second extension to be allowed, got %v", err)
+	}
+	if err := policies.CheckExtensions(3); err == nil {
+		t.Error("expected the third extension to be rejected")
+	}
+}
+
+func TestAppProject(t *testing.T) {
+	crd := metav1.GroupKind{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}
+	clusterRole := metav1.GroupKind{Group: "rbac.authorization.k8s.io", Kind: "ClusterRole"}
+	namespace := metav1.GroupKind{Kind: "Namespace"}
+
+	policies := Policies{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "platform"},
+			Spec: ephemeralv1alpha1.EphemeralPolicySpec{AppProject: &ephemeralv1alpha1.AppProjectPolicy{
+				SourceRepos:              []string{"https://github.com/my-org/bases"},
+				ClusterResourceWhitelist: []metav1.GroupKind{crd, clusterRole},
+				ClusterResourceBlacklist: []metav1.GroupKind{namespace},
+			}},
+		},
+		{ObjectMeta: metav1.ObjectMeta{Name: "quota"}},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "security"},
+			Spec: ephemeralv1alpha1.EphemeralPolicySpec{AppProject: &ephemeralv1alpha1.AppProjectPolicy{
+				SourceRepos:              []string{"https://github.com/my-org/bases", "https://charts.my-org.io"},
+				ClusterResourceWhitelist: []metav1.GroupKind{clusterRole},
+			}},
+		},
+	}
+
+	project := policies.AppProject()
+	if len(project.SourceRepos) != 2 {
+		t.Errorf("expected the source repositories of both policies, got %v", project.SourceRepos)
+	}
+	if len(project.ClusterResourceWhitelist) != 1 || project.ClusterResourceWhitelist[0] != clusterRole {
+		t.Errorf("expected only the resources whitelisted by every policy, got %v", project.ClusterResourceWhitelist)
+	}
+	if len(project.ClusterResourceBlacklist) != 1 || project.ClusterResourceBlacklist[0] != namespace {
+		t.Errorf("expected the blacklist of the platform policy, got %v", project.ClusterResourceBlacklist)
+	}
+}
+
+func TestNamespaceOptions(t *testing.T) {
+	policies := Policies{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "default"},
+			Spec: ephemeralv1alpha1.EphemeralPolicySpec{Namespace: &ephemeralv1alpha1.NamespaceOptions{
+				Labels:        map[string]string{"pod-security.kubernetes.io/enforce": "restricted"},
+				ResourceQuota: corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("4")},
+				LimitRange: &ephemeralv1alpha1.ContainerLimits{
+					Default: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")},
+				},
+				NetworkPolicy: &ephemeralv1alpha1.NetworkIsolation{},
+			}},
+		},
+	}
+
+	merged := policies.NamespaceOptions(&ephemeralv1alpha1.NamespaceOptions{
+		Labels: map[string]string{"team": "payments"},
+		ResourceQuota: corev1.ResourceList{
+			corev1.ResourceRequestsCPU: resource.MustParse("8"),
+			corev1.ResourcePods:        resource.MustParse("20"),
+		},
+		LimitRange: &ephemeralv1alpha1.ContainerLimits{
+			Default: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("512Mi")},
+		},
+	})
+
+	if len(merged.Labels) != 2 {
+		t.Errorf("expected the labels of the policy and the environment, got %v", merged.Labels)
+	}
+	if cpu := merged.ResourceQuota[corev1.ResourceRequestsCPU]; cpu.String() != "4" {
+		t.Errorf("expected the quota of the policy to cap the environment, got %s", cpu.String())
+	}
+	if pods := merged.ResourceQuota[corev1.ResourcePods]; pods.String() != "20" {
+		t.Errorf("expected the pods quota of the environment, got %s", pods.String())
+	}
+	if memory := merged.LimitRange.Default[corev1.ResourceMemory]; memory.String() != "512Mi" {
+		t.Errorf("expected the environment to override the default limit, got %s", memory.String())
+	}
+	if merged.NetworkPolicy == nil {
+		t.Error("expected the network isolation of the policy to be kept")
+	}
+	if policies[0].Spec.Namespace.ResourceQuota[corev1.ResourcePods] != (resource.Quantity{}) {
+		t.Error("expected the policy not to be modified")
+	}
+}
+
+func TestCheckQuota(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = ephemeralv1alpha1.AddToScheme(scheme)
+
+	newApp := func(name string, phase ephemeralv1alpha1.EphemeralApplicationPhase, user string) *ephemeralv1alpha1.EphemeralApplication {
+		return &ephemeralv1alpha1.EphemeralApplication{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   "team-a",
+				Annotations: map[string]string{ephemeralv1alpha1.CreatedByAnnotation: user},
+			},
+			Status: ephemeralv1alpha1.EphemeralApplicationStatus{Phase: phase},
+		}
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(
+			newApp("active", ephemeralv1alpha1.PhaseActive, "alice"),
+			newApp("waiting", ephemeralv1alpha1.PhasePending, "bob"),
+		).
+		Build()
+
+	ctx := context.Background()
+	candidate := newApp("candidate", "", "carol")
+
+	perNamespace := Policies{{
+		ObjectMeta: metav1.ObjectMeta{Name: "namespace-limit"},
+		Spec:       ephemeralv1alpha1.EphemeralPolicySpec{MaxEnvironmentsPerNamespace: int32Ptr(2)},
+	}}
+	if err := perNamespace.CheckQuota(ctx, fakeClient, candidate, "carol", false); err != nil {
+		t.Errorf("expected pending environments to be ignored, got %v", err)
+	}
+	if err := perNamespace.CheckQuota(ctx, fakeClient, candidate, "carol", true); err == nil {
+		t.Error("expected the namespace quota to be exceeded when pending environments are counted")
+	}
+
+	perUser := Policies{{
+		ObjectMeta: metav1.ObjectMeta{Name: "user-limit"},
+		Spec:       ephemeralv1alpha1.EphemeralPolicySpec{MaxEnvironmentsPerUser: int32Ptr(1)},
+	}}
+	if err := perUser.CheckQuota(ctx, fakeClient, candidate, "carol", true); err != nil {
+		t.Errorf("expected carol to be within quota, got %v", err)
+	}
+	if err := perUser.CheckQuota(ctx, fakeClient, candidate, "alice", true); err == nil {
+		t.Error("expected alice to exceed the user quota")
+	}
+
+	// The environment itself is never counted
+	if err := perUser.CheckQuota(ctx, fakeClient, newApp("active", ephemeralv1alpha1.PhaseActive, "alice"), "alice", true); err != nil {
+		t.Errorf("expected an environment not to count against itself, got %v", err)
+	}
+
+	// A pending environment running its preSync hooks already holds a namespace
+	running := newApp("migrating", ephemeralv1alpha1.PhasePending, "dave")
+	running.Status.Namespace = "ephemeral-migrating"
+	if err := fakeClient.Create(ctx, running); err != nil {
+		t.Fatal(err)
+	}
+	if err := perNamespace.CheckQuota(ctx, fakeClient, candidate, "carol", false); err == nil {
+		t.Error("expected environments holding a namespace to be counted")
+	}
+}
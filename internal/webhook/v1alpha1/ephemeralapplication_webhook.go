@@ -0,0 +1,233 @@
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	ephemeralv1alpha1 "github.com/jbarea/argo-ephemeral-operator/api/v1alpha1"
+	"github.com/jbarea/argo-ephemeral-operator/internal/argocd"
+	"github.com/jbarea/argo-ephemeral-operator/internal/naming"
+	"github.com/jbarea/argo-ephemeral-operator/internal/policy"
+)
+
+// +kubebuilder:webhook:path=/mutate-ephemeral-argo-io-v1alpha1-ephemeralapplication,mutating=true,failurePolicy=fail,sideEffects=None,groups=ephemeral.argo.io,resources=ephemeralapplications,verbs=create,versions=v1alpha1,name=mephemeralapplication.ephemeral.argo.io,admissionReviewVersions=v1
+
+// EphemeralApplicationDefaulter fills in the defaults of new EphemeralApplications
+type EphemeralApplicationDefaulter struct {
+	NameGenerator naming.Generator
+
+	// TrustedCreator is the only user allowed to record another user as the creator, the API
+	// server creates the environments on behalf of its users with its service account
+	TrustedCreator string
+}
+
+// Default sets the target revision, the namespace name and the creator of the environment
+// The namespace name is generated up front so it is known before the environment is reconciled
+func (d *EphemeralApplicationDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	ephApp, err := toEphemeralApplication(obj)
+	if err != nil {
+		return err
+	}
+
+	// The revision of templated environments comes from the template
+	if ephApp.Spec.TemplateRef == nil {
+		if len(ephApp.Spec.Components) == 0 && ephApp.Spec.TargetRevision == "" {
+			ephApp.Spec.TargetRevision = argocd.DefaultTargetRevision
+		}
+		for i := range ephApp.Spec.Components {
+			if ephApp.Spec.Components[i].TargetRevision == "" {
+				ephApp.Spec.Components[i].TargetRevision = argocd.DefaultTargetRevision
+			}
+		}
+	}
+
+	if ephApp.Spec.NamespaceName == "" {
+		ephApp.Spec.NamespaceName = d.NameGenerator.GenerateNamespace("", "")
+	}
+
+	// The quotas are counted per creator, so the annotation always records the requesting user
+	if req, err := admission.RequestFromContext(ctx); err == nil && req.UserInfo.Username != "" {
+		trusted := d.TrustedCreator != "" && req.UserInfo.Username == d.TrustedCreator
+		if !trusted || ephApp.Annotations[ephemeralv1alpha1.CreatedByAnnotation] == "" {
+			if ephApp.Annotations == nil {
+				ephApp.Annotations = make(map[string]string)
+			}
+			ephApp.Annotations[ephemeralv1alpha1.CreatedByAnnotation] = req.UserInfo.Username
+		}
+	}
+
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-ephemeral-argo-io-v1alpha1-ephemeralapplication,mutating=false,failurePolicy=fail,sideEffects=None,groups=ephemeral.argo.io,resources=ephemeralapplications,verbs=create;update,versions=v1alpha1,name=vephemeralapplication.ephemeral.argo.io,admissionReviewVersions=v1
+
+// EphemeralApplicationValidator rejects invalid EphemeralApplications and those violating an EphemeralPolicy
+type EphemeralApplicationValidator struct {
+	Client client.Reader
+}
+
+// SetupWebhookWithManager registers the defaulting and validating webhooks with the manager
+func SetupWebhookWithManager(mgr ctrl.Manager, nameGenerator naming.Generator, trustedCreator string) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&ephemeralv1alpha1.EphemeralApplication{}).
+		WithDefaulter(&EphemeralApplicationDefaulter{NameGenerator: nameGenerator, TrustedCreator: trustedCreator}).
+		WithValidator(&EphemeralApplicationValidator{Client: mgr.GetAPIReader()}).
+		Complete()
+}
+
+// ValidateCreate checks a new environment and its policies, including the quotas
+func (v *EphemeralApplicationValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	ephApp, err := toEphemeralApplication(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	if date := ephApp.Spec.ExpirationDate; date != nil && date.Before(&metav1.Time{Time: time.Now()}) {
+		return nil, fmt.Errorf("spec.expirationDate %s is in the past", date.Format(time.RFC3339))
+	}
+	if err := v.validateSpec(ctx, ephApp); err != nil {
+		return nil, err
+	}
+
+	policies, err := policy.List(ctx, v.Client)
+	if err != nil {
+		return nil, err
+	}
+	if err := policies.CheckSpec(ephApp); err != nil {
+		return nil, err
+	}
+	if err := policies.CheckLifetime(ephApp); err != nil {
+		return nil, err
+	}
+
+	// Fall back to the requesting user when the creator is not recorded
+	user := ephApp.Annotations[ephemeralv1alpha1.CreatedByAnnotation]
+	if user == "" {
+		if req, err := admission.RequestFromContext(ctx); err == nil {
+			user = req.UserInfo.Username
+		}
+	}
+	return nil, policies.CheckQuota(ctx, v.Client, ephApp, user, true)
+}
+
+// ValidateUpdate checks spec changes and their policies, quotas are only enforced on creation
+func (v *EphemeralApplicationValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldApp, err := toEphemeralApplication(oldObj)
+	if err != nil {
+		return nil, err
+	}
+	ephApp, err := toEphemeralApplication(newObj)
+	if err != nil {
+		return nil, err
+	}
+
+	// Never block finalizer removal
+	if !ephApp.DeletionTimestamp.IsZero() {
+		return nil, nil
+	}
+
+	// The creator can not be changed to move the environment to another user's quota
+	if ephApp.Annotations[ephemeralv1alpha1.CreatedByAnnotation] != oldApp.Annotations[ephemeralv1alpha1.CreatedByAnnotation] {
+		return nil, fmt.Errorf("metadata.annotations[%s] is immutable", ephemeralv1alpha1.CreatedByAnnotation)
+	}
+
+	// Status-only changes are not checked again
+	if equality.Semantic.DeepEqual(oldApp.Spec, ephApp.Spec) {
+		return nil, nil
+	}
+
+	// The namespace can not be renamed once it is chosen
+	namespaceChosen := oldApp.Spec.NamespaceName != "" || oldApp.Status.Namespace != ""
+	if namespaceChosen && ephApp.Spec.NamespaceName != oldApp.Spec.NamespaceName {
+		return nil, fmt.Errorf("spec.namespaceName is immutable")
+	}
+
+	// The environment can not be moved to another cluster, nor a destination added or removed
+	if !equality.Semantic.DeepEqual(oldApp.Spec.Destination, ephApp.Spec.Destination) {
+		return nil, fmt.Errorf("spec.destination is immutable")
+	}
+
+	date := ephApp.Spec.ExpirationDate
+	if date != nil && !date.Equal(oldApp.Spec.ExpirationDate) && date.Before(&metav1.Time{Time: time.Now()}) {
+		return nil, fmt.Errorf("spec.expirationDate %s is in the past", date.Format(time.RFC3339))
+	}
+	if err := v.validateSpec(ctx, ephApp); err != nil {
+		return nil, err
+	}
+
+	policies, err := policy.List(ctx, v.Client)
+	if err != nil {
+		return nil, err
+	}
+	if err := policies.CheckSpec(ephApp); err != nil {
+		return nil, err
+	}
+	if err := policies.CheckLifetime(ephApp); err != nil {
+		return nil, err
+	}
+
+	if expiresAt, previous := ephApp.ExpiresAt(), oldApp.Status.ExpiresAt; expiresAt != nil && previous != nil && expiresAt.After(previous.Time) {
+		if err := policies.CheckExtensions(oldApp.Status.Extensions + 1); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// ValidateDelete allows every deletion
+func (v *EphemeralApplicationValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateSpec merges the referenced template and runs the checks the reconciler would otherwise
+// only report through the Failed phase. The merged spec is kept so the policies see the effective sources
+func (v *EphemeralApplicationValidator) validateSpec(ctx context.Context, ephApp *ephemeralv1alpha1.EphemeralApplication) error {
+	if ephApp.Spec.TemplateRef != nil {
+		template := &ephemeralv1alpha1.EphemeralApplicationTemplate{}
+		key := client.ObjectKey{
+			Namespace: ephApp.Namespace,
+			Name:      ephApp.Spec.TemplateRef.Name,
+		}
+		if err := v.Client.Get(ctx, key, template); err != nil {
+			return fmt.Errorf("failed to get template %s: %w", key.Name, err)
+		}
+		ephApp.Spec = ephApp.Spec.MergeTemplate(&template.Spec)
+	}
+
+	if err := ephApp.Spec.ValidateSource(ephApp.Name); err != nil {
+		return err
+	}
+	if err := ephApp.Spec.ValidateExpiration(); err != nil {
+		return err
+	}
+	if err := ephApp.Spec.ValidateResources(); err != nil {
+		return err
+	}
+	if err := ephApp.Spec.ValidateHibernation(); err != nil {
+		return err
+	}
+	if err := ephApp.Spec.ValidateDestination(); err != nil {
+		return err
+	}
+	if err := ephApp.Spec.ValidateHooks(); err != nil {
+		return err
+	}
+	return ephApp.Spec.ValidateNamespaceName()
+}
+
+// toEphemeralApplication casts the admitted object
+func toEphemeralApplication(obj runtime.Object) (*ephemeralv1alpha1.EphemeralApplication, error) {
+	ephApp, ok := obj.(*ephemeralv1alpha1.EphemeralApplication)
+	if !ok {
+		return nil, fmt.Errorf("expected an EphemeralApplication but got %T", obj)
+	}
+	return ephApp, nil
+}
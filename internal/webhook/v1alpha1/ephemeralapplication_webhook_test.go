@@ -0,0 +1,340 @@
+package v1alpha1
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	ephemeralv1alpha1 "github.com/jbarea/argo-ephemeral-operator/api/v1alpha1"
+	"github.com/jbarea/argo-ephemeral-operator/internal/naming"
+)
+
+func newValidator(objs ...runtime.Object) *EphemeralApplicationValidator {
+	scheme := runtime.NewScheme()
+	_ = ephemeralv1alpha1.AddToScheme(scheme)
+
+	return &EphemeralApplicationValidator{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objs...).Build(),
+	}
+}
+
+func TestValidateCreate_TemplateRepositoryIsChecked(t *testing.T) {
+	validator := newValidator(
+		&ephemeralv1alpha1.EphemeralPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "default"},
+			Spec: ephemeralv1alpha1.EphemeralPolicySpec{
+				AllowedRepoURLs: []string{"https://github.com/my-org/*"},
+			},
+		},
+		&ephemeralv1alpha1.EphemeralApplicationTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "external", Namespace: "default"},
+			Spec: ephemeralv1alpha1.EphemeralApplicationTemplateSpec{
+				RepoURL: "https://github.com/other/app.git",
+				Path:    "manifests",
+			},
+		},
+	)
+
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "preview", Namespace: "default"},
+		Spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+			TemplateRef: &ephemeralv1alpha1.TemplateReference{Name: "external"},
+			TTL:         &metav1.Duration{Duration: time.Hour},
+		},
+	}
+
+	if _, err := validator.ValidateCreate(context.Background(), ephApp); err == nil {
+		t.Error("expected the repository from the template to be rejected")
+	}
+}
+
+func TestValidateUpdate_LimitsExtensions(t *testing.T) {
+	maxExtensions := int32(1)
+	validator := newValidator(&ephemeralv1alpha1.EphemeralPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec: ephemeralv1alpha1.EphemeralPolicySpec{
+			MaxTTL:        &metav1.Duration{Duration: 24 * time.Hour},
+			MaxExtensions: &maxExtensions,
+		},
+	})
+
+	created := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+	expiresAt := metav1.NewTime(created.Add(2 * time.Hour))
+	oldApp := &ephemeralv1alpha1.EphemeralApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "preview", Namespace: "default", CreationTimestamp: created},
+		Spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+			RepoURL: "https://github.com/my-org/app.git",
+			Path:    "manifests",
+			TTL:     &metav1.Duration{Duration: 2 * time.Hour},
+		},
+		Status: ephemeralv1alpha1.EphemeralApplicationStatus{ExpiresAt: &expiresAt},
+	}
+
+	tests := []struct {
+		name       string
+		ttl        time.Duration
+		extensions int32
+		wantErr    bool
+	}{
+		{name: "first extension", ttl: 4 * time.Hour},
+		{name: "extension beyond the limit", ttl: 4 * time.Hour, extensions: 1, wantErr: true},
+		{name: "shortening is always allowed", ttl: 90 * time.Minute, extensions: 1},
+		{name: "lifetime beyond the maximum", ttl: 48 * time.Hour, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := oldApp.DeepCopy()
+			previous.Status.Extensions = tt.extensions
+			updated := previous.DeepCopy()
+			updated.Spec.TTL = &metav1.Duration{Duration: tt.ttl}
+
+			_, err := validator.ValidateUpdate(context.Background(), previous, updated)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateUpdate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateCreate_RejectsInvalidSpecs(t *testing.T) {
+	validator := newValidator()
+
+	valid := ephemeralv1alpha1.EphemeralApplicationSpec{
+		RepoURL: "https://github.com/example/app.git",
+		Path:    "manifests",
+		TTL:     &metav1.Duration{Duration: time.Hour},
+	}
+
+	tests := []struct {
+		name    string
+		mutate  func(spec *ephemeralv1alpha1.EphemeralApplicationSpec)
+		wantErr bool
+	}{
+		{name: "valid", mutate: func(*ephemeralv1alpha1.EphemeralApplicationSpec) {}},
+		{
+			name: "expiration date in the past",
+			mutate: func(spec *ephemeralv1alpha1.EphemeralApplicationSpec) {
+				spec.TTL = nil
+				spec.ExpirationDate = &metav1.Time{Time: time.Now().Add(-time.Hour)}
+			},
+			wantErr: true,
+		},
+		{
+			name: "configmap with invalid template",
+			mutate: func(spec *ephemeralv1alpha1.EphemeralApplicationSpec) {
+				spec.ConfigMaps = []ephemeralv1alpha1.ConfigMapReference{{
+					Name:            "settings",
+					SourceNamespace: "shared",
+					Templates:       map[string]string{"url": "{{ .PreviewURL"},
+				}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "hook without containers",
+			mutate: func(spec *ephemeralv1alpha1.EphemeralApplicationSpec) {
+				spec.Hooks = &ephemeralv1alpha1.Hooks{PreSync: []ephemeralv1alpha1.Hook{{Name: "migrate"}}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "inline secret without name",
+			mutate: func(spec *ephemeralv1alpha1.EphemeralApplicationSpec) {
+				spec.Secrets = []ephemeralv1alpha1.SecretReference{{Values: map[string]string{"key": "value"}}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid namespace name",
+			mutate: func(spec *ephemeralv1alpha1.EphemeralApplicationSpec) {
+				spec.NamespaceName = "Feature_Login"
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ephApp := &ephemeralv1alpha1.EphemeralApplication{
+				ObjectMeta: metav1.ObjectMeta{Name: "preview", Namespace: "default"},
+				Spec:       *valid.DeepCopy(),
+			}
+			tt.mutate(&ephApp.Spec)
+
+			_, err := validator.ValidateCreate(context.Background(), ephApp)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateUpdate_NamespaceNameIsImmutable(t *testing.T) {
+	validator := newValidator()
+
+	oldApp := &ephemeralv1alpha1.EphemeralApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "preview", Namespace: "default"},
+		Spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+			RepoURL:       "https://github.com/example/app.git",
+			Path:          "manifests",
+			TTL:           &metav1.Duration{Duration: time.Hour},
+			NamespaceName: "ephemeral-abc1234",
+		},
+	}
+	updated := oldApp.DeepCopy()
+	updated.Spec.NamespaceName = "renamed"
+
+	if _, err := validator.ValidateUpdate(context.Background(), oldApp, updated); err == nil {
+		t.Error("expected renaming the namespace to be rejected")
+	}
+}
+
+func TestDefault(t *testing.T) {
+	defaulter := &EphemeralApplicationDefaulter{NameGenerator: naming.NewDefaultGenerator()}
+
+	ephApp := &ephemeralv1alpha1.EphemeralApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "preview", Namespace: "default"},
+		Spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+			Components: []ephemeralv1alpha1.Component{
+				{Name: "api", RepoURL: "https://github.com/example/api.git", Path: "manifests"},
+				{Name: "web", RepoURL: "https://github.com/example/web.git", Path: "manifests", TargetRevision: "main"},
+			},
+			TTL: &metav1.Duration{Duration: time.Hour},
+		},
+	}
+	if err := defaulter.Default(context.Background(), ephApp); err != nil {
+		t.Fatalf("Default() failed: %v", err)
+	}
+
+	if got := ephApp.Spec.Components[0].TargetRevision; got != "HEAD" {
+		t.Errorf("expected targetRevision HEAD, got %q", got)
+	}
+	if got := ephApp.Spec.Components[1].TargetRevision; got != "main" {
+		t.Errorf("expected targetRevision main to be kept, got %q", got)
+	}
+	if !strings.HasPrefix(ephApp.Spec.NamespaceName, "ephemeral-") {
+		t.Errorf("expected a generated namespace name, got %q", ephApp.Spec.NamespaceName)
+	}
+
+	templated := &ephemeralv1alpha1.EphemeralApplication{
+		Spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+			TemplateRef: &ephemeralv1alpha1.TemplateReference{Name: "backend"},
+		},
+	}
+	if err := defaulter.Default(context.Background(), templated); err != nil {
+		t.Fatalf("Default() failed: %v", err)
+	}
+	if templated.Spec.TargetRevision != "" {
+		t.Errorf("expected the template to provide the revision, got %q", templated.Spec.TargetRevision)
+	}
+}
+
+func TestDefault_RecordsRequestingUser(t *testing.T) {
+	defaulter := &EphemeralApplicationDefaulter{
+		NameGenerator:  naming.NewDefaultGenerator(),
+		TrustedCreator: "system:serviceaccount:argo-ephemeral-operator-system:argo-ephemeral-api",
+	}
+	requestBy := func(username string) context.Context {
+		return admission.NewContextWithRequest(context.Background(), admission.Request{
+			AdmissionRequest: admissionv1.AdmissionRequest{UserInfo: authenticationv1.UserInfo{Username: username}},
+		})
+	}
+
+	tests := []struct {
+		name      string
+		username  string
+		createdBy string
+		want      string
+	}{
+		{name: "not recorded", username: "alice", want: "alice"},
+		{name: "another user is overwritten", username: "alice", createdBy: "bob", want: "alice"},
+		{name: "trusted creator records the user", username: defaulter.TrustedCreator, createdBy: "bob", want: "bob"},
+		{name: "trusted creator without user", username: defaulter.TrustedCreator, want: defaulter.TrustedCreator},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ephApp := &ephemeralv1alpha1.EphemeralApplication{
+				ObjectMeta: metav1.ObjectMeta{Name: "preview", Namespace: "default"},
+			}
+			if tt.createdBy != "" {
+				ephApp.Annotations = map[string]string{ephemeralv1alpha1.CreatedByAnnotation: tt.createdBy}
+			}
+			if err := defaulter.Default(requestBy(tt.username), ephApp); err != nil {
+				t.Fatalf("Default() failed: %v", err)
+			}
+			if got := ephApp.Annotations[ephemeralv1alpha1.CreatedByAnnotation]; got != tt.want {
+				t.Errorf("expected creator %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestValidateUpdate_CreatorIsImmutable(t *testing.T) {
+	validator := newValidator()
+
+	oldApp := &ephemeralv1alpha1.EphemeralApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "preview",
+			Namespace:   "default",
+			Annotations: map[string]string{ephemeralv1alpha1.CreatedByAnnotation: "alice"},
+		},
+		Spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+			RepoURL:       "https://github.com/example/app.git",
+			Path:          "manifests",
+			TTL:           &metav1.Duration{Duration: time.Hour},
+			NamespaceName: "ephemeral-abc1234",
+		},
+	}
+	updated := oldApp.DeepCopy()
+	updated.Annotations[ephemeralv1alpha1.CreatedByAnnotation] = "bob"
+
+	if _, err := validator.ValidateUpdate(context.Background(), oldApp, updated); err == nil {
+		t.Error("expected changing the creator to be rejected")
+	}
+}
+
+func TestValidateUpdate_DestinationIsImmutable(t *testing.T) {
+	validator := newValidator()
+
+	oldApp := &ephemeralv1alpha1.EphemeralApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "preview", Namespace: "default"},
+		Spec: ephemeralv1alpha1.EphemeralApplicationSpec{
+			RepoURL:       "https://github.com/example/app.git",
+			Path:          "manifests",
+			TTL:           &metav1.Duration{Duration: time.Hour},
+			NamespaceName: "ephemeral-abc1234",
+		},
+	}
+	withDestination := oldApp.DeepCopy()
+	withDestination.Spec.Destination = &ephemeralv1alpha1.Destination{Name: "staging"}
+	moved := withDestination.DeepCopy()
+	moved.Spec.Destination.Name = "production"
+
+	tests := []struct {
+		name       string
+		oldApp     *ephemeralv1alpha1.EphemeralApplication
+		updatedApp *ephemeralv1alpha1.EphemeralApplication
+	}{
+		{name: "added", oldApp: oldApp, updatedApp: withDestination},
+		{name: "changed", oldApp: withDestination, updatedApp: moved},
+		{name: "removed", oldApp: withDestination, updatedApp: oldApp},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := validator.ValidateUpdate(context.Background(), tt.oldApp, tt.updatedApp)
+			if err == nil || !strings.Contains(err.Error(), "spec.destination is immutable") {
+				t.Errorf("expected the destination change to be rejected, got %v", err)
+			}
+		})
+	}
+}
@@ -43,6 +43,14 @@ export const ephemeralAppsApi = {
     return data;
   },
 
+  // Wake up a hibernating ephemeral application until its next scheduled sleep
+  wake: async (name: string, namespace = 'default'): Promise<EphemeralApplication> => {
+    const { data } = await apiClient.post<EphemeralApplication>(
+      `/ephemeral-apps/${name}/wake?namespace=${namespace}`
+    );
+    return data;
+  },
+
   // Delete an ephemeral application
   delete: async (name: string, namespace = 'default'): Promise<void> => {
     await apiClient.delete(`/ephemeral-apps/${name}?namespace=${namespace}`);
@@ -0,0 +1,43 @@
+import apiClient from './client';
+import type { EphemeralApplicationTemplate, EphemeralApplicationTemplateList } from './types';
+
+export const templatesApi = {
+  // List application templates
+  list: async (namespace?: string): Promise<EphemeralApplicationTemplate[]> => {
+    const query = namespace ? `?namespace=${namespace}` : '';
+    const { data } = await apiClient.get<EphemeralApplicationTemplateList>(`/templates${query}`);
+    return data.items || [];
+  },
+
+  // Get a single template
+  get: async (name: string, namespace = 'default'): Promise<EphemeralApplicationTemplate> => {
+    const { data } = await apiClient.get<EphemeralApplicationTemplate>(
+      `/templates/${name}?namespace=${namespace}`
+    );
+    return data;
+  },
+
+  // Create a new template
+  create: async (template: EphemeralApplicationTemplate): Promise<EphemeralApplicationTemplate> => {
+    const { data } = await apiClient.post<EphemeralApplicationTemplate>('/templates/create', template);
+    return data;
+  },
+
+  // Replace the spec of a template
+  update: async (
+    name: string,
+    template: EphemeralApplicationTemplate,
+    namespace = 'default'
+  ): Promise<EphemeralApplicationTemplate> => {
+    const { data } = await apiClient.put<EphemeralApplicationTemplate>(
+      `/templates/${name}?namespace=${namespace}`,
+      template
+    );
+    return data;
+  },
+
+  // Delete a template
+  delete: async (name: string, namespace = 'default'): Promise<void> => {
+    await apiClient.delete(`/templates/${name}?namespace=${namespace}`);
+  },
+};
@@ -17,26 +17,111 @@ export interface Metadata {
 }
 
 export interface EphemeralApplicationSpec {
-  repoURL: string;
-  path: string;
-  targetRevision: string;
-  expirationDate: string;
+  templateRef?: TemplateReference;
+  repoURL?: string;
+  path?: string;
+  targetRevision?: string;
+  helm?: HelmSource;
+  kustomize?: KustomizeSource;
+  components?: Component[];
+  expirationDate?: string;
+  ttl?: string;
   namespaceName?: string;
   secrets?: SecretReference[];
   configMaps?: ConfigMapReference[];
   syncPolicy?: SyncPolicy;
+  hibernation?: HibernationSpec;
+  hooks?: Hooks;
+}
+
+export interface TemplateReference {
+  name: string;
+}
+
+export interface Component {
+  name: string;
+  repoURL?: string;
+  path?: string;
+  targetRevision?: string;
+  helm?: HelmSource;
+  kustomize?: KustomizeSource;
+}
+
+export interface HelmSource {
+  chart?: string;
+  releaseName?: string;
+  valueFiles?: string[];
+  values?: Record<string, unknown>;
+  parameters?: HelmParameter[];
+}
+
+export interface HelmParameter {
+  name: string;
+  value?: string;
+  forceString?: boolean;
+}
+
+export interface KustomizeSource {
+  images?: string[];
+  namePrefix?: string;
+  nameSuffix?: string;
+  commonLabels?: Record<string, string>;
+  commonAnnotations?: Record<string, string>;
+  patches?: KustomizePatch[];
+}
+
+export interface KustomizePatch {
+  patch: string;
+  target?: {
+    group?: string;
+    version?: string;
+    kind?: string;
+    name?: string;
+    namespace?: string;
+    labelSelector?: string;
+    annotationSelector?: string;
+  };
 }
 
 export interface SyncPolicy {
   automated?: {
     prune?: boolean;
     selfHeal?: boolean;
+    allowEmpty?: boolean;
   };
+  prune?: boolean;
+  selfHeal?: boolean;
+  syncOptions?: string[];
+  retry?: {
+    limit?: number;
+    backoff?: {
+      duration?: string;
+      factor?: number;
+      maxDuration?: string;
+    };
+  };
+}
+
+export interface HibernationSpec {
+  sleepSchedule: string;
+  wakeSchedule: string;
+  timezone?: string;
+}
+
+export interface Hooks {
+  preSync?: Hook[];
+  postReady?: Hook[];
+  preDelete?: Hook[];
+}
+
+export interface Hook {
+  name: string;
+  spec: Record<string, unknown>;
 }
 
 export interface SecretReference {
   name: string;
-  sourceNamespace: string;
+  sourceNamespace?: string;
   targetName?: string;
   values?: Record<string, string>;
 }
@@ -49,16 +134,84 @@ export interface ConfigMapReference {
 
 export interface EphemeralApplicationStatus {
   phase?: Phase;
+  observedGeneration?: number;
+  templateGeneration?: number;
+  expiresAt?: string;
+  extensions?: number;
+  hibernatedAt?: string;
+  nextHibernationTransition?: string;
   namespace?: string;
   argoApplicationName?: string;
+  components?: ComponentStatus[];
   message?: string;
   lastSyncTime?: string;
   conditions?: Condition[];
-  copiedSecrets?: string[];
-  copiedConfigMaps?: string[];
+  injectedResources?: InjectedResourceStatus[];
+  hooks?: HookStatus[];
 }
 
-export type Phase = 'Pending' | 'Creating' | 'Active' | 'Expiring' | 'Failed';
+export interface HookStatus {
+  stage: 'PreSync' | 'PostReady' | 'PreDelete';
+  name: string;
+  jobName: string;
+  phase: 'Running' | 'Succeeded' | 'Failed';
+  startedAt?: string;
+  completedAt?: string;
+  message?: string;
+}
+
+export interface InjectedResourceStatus {
+  kind: 'Secret' | 'ConfigMap';
+  name: string;
+  sourceNamespace?: string;
+  sourceName?: string;
+  hash?: string;
+  lastSyncedTime?: string;
+  lastSyncedResourceVersion?: string;
+  error?: string;
+}
+
+export interface ComponentStatus {
+  name: string;
+  argoApplicationName: string;
+  syncStatus?: string;
+  healthStatus?: string;
+}
+
+export type Phase =
+  | 'Pending'
+  | 'Creating'
+  | 'Updating'
+  | 'Active'
+  | 'Hibernating'
+  | 'Expiring'
+  | 'Failed';
+
+export interface EphemeralApplicationTemplate {
+  apiVersion: string;
+  kind: string;
+  metadata: Metadata;
+  spec: EphemeralApplicationTemplateSpec;
+}
+
+export interface EphemeralApplicationTemplateSpec {
+  description?: string;
+  repoURL?: string;
+  path?: string;
+  targetRevision?: string;
+  helm?: HelmSource;
+  kustomize?: KustomizeSource;
+  components?: Component[];
+  secrets?: SecretReference[];
+  configMaps?: ConfigMapReference[];
+  syncPolicy?: SyncPolicy;
+}
+
+export interface EphemeralApplicationTemplateList {
+  apiVersion: string;
+  kind: string;
+  items: EphemeralApplicationTemplate[];
+}
 
 export interface Condition {
   type: string;
@@ -87,7 +240,7 @@ export interface EnvironmentSummary {
   name: string;
   namespace: string;
   phase: string;
-  expirationDate: string;
+  expirationDate?: string;
   createdAt: string;
 }
 
@@ -35,8 +35,13 @@ export const EphemeralAppTable: React.FC<EphemeralAppTableProps> = ({ environmen
     setSelectedApp(null);
   };
 
+  // Effective expiry, computed by the operator from expirationDate or ttl
+  const getExpiresAt = (env: EphemeralApplication) =>
+    env.status?.expiresAt ?? env.spec?.expirationDate;
+
   const getPhase = (env: EphemeralApplication) => {
-    if (env.spec?.expirationDate && new Date(env.spec.expirationDate) < new Date()) {
+    const expiresAt = getExpiresAt(env);
+    if (expiresAt && new Date(expiresAt) < new Date()) {
       return 'Expiring';
     }
     return env.status?.phase;
@@ -74,8 +79,8 @@ export const EphemeralAppTable: React.FC<EphemeralAppTableProps> = ({ environmen
               <Td dataLabel="Repository">{env.spec.repoURL}</Td>
               <Td dataLabel="Target Revision">{env.spec.targetRevision}</Td>
               <Td dataLabel="Expires">
-                {env.spec.expirationDate
-                  ? formatDistanceToNow(new Date(env.spec.expirationDate), {
+                {getExpiresAt(env)
+                  ? formatDistanceToNow(new Date(getExpiresAt(env)!), {
                       addSuffix: true,
                     })
                   : 'N/A'}
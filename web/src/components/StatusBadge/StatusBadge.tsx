@@ -7,12 +7,15 @@ interface StatusBadgeProps {
 }
 
 export const StatusBadge: React.FC<StatusBadgeProps> = ({ phase = 'Pending' }) => {
-  const getColor = (): 'green' | 'blue' | 'orange' | 'red' | 'grey' => {
+  const getColor = (): 'green' | 'blue' | 'purple' | 'orange' | 'red' | 'grey' => {
     switch (phase) {
       case 'Active':
         return 'green';
       case 'Creating':
+      case 'Updating':
         return 'blue';
+      case 'Hibernating':
+        return 'purple';
       case 'Expiring':
         return 'orange';
       case 'Failed':
@@ -69,6 +69,18 @@ export const useExtendExpiration = () => {
   });
 };
 
+export const useWakeEnvironment = () => {
+  const queryClient = useQueryClient();
+
+  return useMutation({
+    mutationFn: ({ name, namespace = 'default' }: { name: string; namespace?: string }) =>
+      ephemeralAppsApi.wake(name, namespace),
+    onSuccess: () => {
+      queryClient.invalidateQueries({ queryKey: [QUERY_KEY] });
+    },
+  });
+};
+
 export const useMetrics = () => {
   return useQuery({
     queryKey: ['metrics'],
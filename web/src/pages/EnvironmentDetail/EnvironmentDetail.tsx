@@ -18,7 +18,7 @@ import {
   FlexItem,
 } from '@patternfly/react-core';
 import { ArrowLeftIcon } from '@patternfly/react-icons';
-import { useEphemeralApp } from '../../hooks/useEphemeralApps';
+import { useEphemeralApp, useWakeEnvironment } from '../../hooks/useEphemeralApps';
 import { StatusBadge } from '../../components/StatusBadge/StatusBadge';
 import { formatDistanceToNow } from 'date-fns';
 
@@ -26,6 +26,7 @@ export const EnvironmentDetail: React.FC = () => {
   const { name } = useParams<{ name: string }>();
   const navigate = useNavigate();
   const { data: environment, isLoading, error } = useEphemeralApp(name || '');
+  const wakeEnvironment = useWakeEnvironment();
 
   if (isLoading) {
     return (
@@ -45,6 +46,9 @@ export const EnvironmentDetail: React.FC = () => {
     );
   }
 
+  // Effective expiry, computed by the operator from expirationDate or ttl
+  const expiresAt = environment.status?.expiresAt ?? environment.spec.expirationDate;
+
   return (
     <>
       <PageSection variant="light">
@@ -58,6 +62,23 @@ export const EnvironmentDetail: React.FC = () => {
               Back
             </Button>
           </FlexItem>
+          {environment.status?.phase === 'Hibernating' && (
+            <FlexItem align={{ default: 'alignRight' }}>
+              <Button
+                variant="secondary"
+                isLoading={wakeEnvironment.isPending}
+                isDisabled={wakeEnvironment.isPending}
+                onClick={() =>
+                  wakeEnvironment.mutate({
+                    name: environment.metadata.name,
+                    namespace: environment.metadata.namespace,
+                  })
+                }
+              >
+                Wake up
+              </Button>
+            </FlexItem>
+          )}
         </Flex>
         <Breadcrumb>
           <BreadcrumbItem to="/environments">Environments</BreadcrumbItem>
@@ -110,14 +131,25 @@ export const EnvironmentDetail: React.FC = () => {
               <DescriptionListGroup>
                 <DescriptionListTerm>Expires</DescriptionListTerm>
                 <DescriptionListDescription>
-                  {environment.spec.expirationDate
-                    ? formatDistanceToNow(new Date(environment.spec.expirationDate), {
+                  {expiresAt
+                    ? formatDistanceToNow(new Date(expiresAt), {
                         addSuffix: true,
                       })
                     : 'N/A'}
                 </DescriptionListDescription>
               </DescriptionListGroup>
 
+              {environment.spec.hibernation && (
+                <DescriptionListGroup>
+                  <DescriptionListTerm>Hibernation</DescriptionListTerm>
+                  <DescriptionListDescription>
+                    Sleeps at <code>{environment.spec.hibernation.sleepSchedule}</code>, wakes at{' '}
+                    <code>{environment.spec.hibernation.wakeSchedule}</code> (
+                    {environment.spec.hibernation.timezone || 'UTC'})
+                  </DescriptionListDescription>
+                </DescriptionListGroup>
+              )}
+
               {environment.status?.message && (
                 <DescriptionListGroup>
                   <DescriptionListTerm>Message</DescriptionListTerm>